@@ -0,0 +1,253 @@
+package core
+
+import (
+	"reflect"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// ConditionBuilder 流式条件构造器，生成等价的 goqu.Ex
+// 调用方无需直接依赖 goqu 即可构造查询条件：
+//
+//	core.Cond().Eq("status", 1).Gt("age", 18).In("role", roles).Build()
+type ConditionBuilder struct {
+	ex goqu.Ex
+}
+
+// comparisonOperators 拼接字面量 SQL 时允许的比较操作符，防止注入
+var comparisonOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, ">": true, ">=": true, "<": true, "<=": true,
+}
+
+// Cond 创建一个条件构造器
+func Cond() *ConditionBuilder {
+	return &ConditionBuilder{ex: goqu.Ex{}}
+}
+
+// op 为字段追加一个操作符，同一字段的多个操作符以 AND 连接
+func (c *ConditionBuilder) op(column, operator string, value interface{}) *ConditionBuilder {
+	value = normalizeValue(value)
+	if ops, ok := c.ex[column].(goqu.Op); ok {
+		ops[operator] = value
+		return c
+	}
+	c.ex[column] = goqu.Op{operator: value}
+	return c
+}
+
+// Eq 等于
+func (c *ConditionBuilder) Eq(column string, value interface{}) *ConditionBuilder {
+	return c.op(column, "eq", value)
+}
+
+// Ne 不等于
+func (c *ConditionBuilder) Ne(column string, value interface{}) *ConditionBuilder {
+	return c.op(column, "neq", value)
+}
+
+// Gt 大于
+func (c *ConditionBuilder) Gt(column string, value interface{}) *ConditionBuilder {
+	return c.op(column, "gt", value)
+}
+
+// Gte 大于等于
+func (c *ConditionBuilder) Gte(column string, value interface{}) *ConditionBuilder {
+	return c.op(column, "gte", value)
+}
+
+// Lt 小于
+func (c *ConditionBuilder) Lt(column string, value interface{}) *ConditionBuilder {
+	return c.op(column, "lt", value)
+}
+
+// Lte 小于等于
+func (c *ConditionBuilder) Lte(column string, value interface{}) *ConditionBuilder {
+	return c.op(column, "lte", value)
+}
+
+// In 包含于，values 可以是任意切片
+func (c *ConditionBuilder) In(column string, values interface{}) *ConditionBuilder {
+	return c.op(column, "in", values)
+}
+
+// Like 模糊匹配，pattern 需自行包含通配符，如 "%abc%"
+func (c *ConditionBuilder) Like(column string, pattern string) *ConditionBuilder {
+	return c.op(column, "like", pattern)
+}
+
+// IsNull 为空
+func (c *ConditionBuilder) IsNull(column string) *ConditionBuilder {
+	return c.op(column, "is", nil)
+}
+
+// Build 返回构造好的 goqu.Ex
+func (c *ConditionBuilder) Build() goqu.Ex {
+	result := make(goqu.Ex, len(c.ex))
+	for column, value := range c.ex {
+		if ops, ok := value.(goqu.Op); ok {
+			copied := make(goqu.Op, len(ops))
+			for k, v := range ops {
+				copied[k] = v
+			}
+			value = copied
+		}
+		result[column] = value
+	}
+	return result
+}
+
+// basicTypes 各基础 Kind 对应的内置类型
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// basicType 自定义的基础类型（如 type Status int）返回对应的内置类型，其他类型返回 nil
+// 实现了 driver.Valuer 的类型由其自身负责转换，不做处理
+func basicType(t reflect.Type) reflect.Type {
+	base, ok := basicTypes[t.Kind()]
+	if !ok || t == base || t.Implements(valuerType) {
+		return nil
+	}
+	return base
+}
+
+// normalizeValue 将枚举常量等自定义基础类型的值转换为内置类型再绑定，避免驱动报 unsupported type，
+// 元素为自定义基础类型的切片（如 []Status）转换为内置类型的切片，[]interface{} 逐个元素转换，其他值原样返回
+func normalizeValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if base := basicType(v.Type()); base != nil {
+		return v.Convert(base).Interface()
+	}
+	if v.Kind() != reflect.Slice {
+		return value
+	}
+	if items, ok := value.([]interface{}); ok {
+		return normalizeArgs(items)
+	}
+	base := basicType(v.Type().Elem())
+	if base == nil {
+		return value
+	}
+	converted := reflect.MakeSlice(reflect.SliceOf(base), v.Len(), v.Len())
+	for i := 0; i < v.Len(); i++ {
+		converted.Index(i).Set(v.Index(i).Convert(base))
+	}
+	return converted.Interface()
+}
+
+// normalizeEx 返回 condition 的副本，其中的值（包括 goqu.Op 中的值）经过 normalizeValue 转换
+func normalizeEx(condition goqu.Ex) goqu.Ex {
+	if len(condition) == 0 {
+		return condition
+	}
+	result := make(goqu.Ex, len(condition))
+	for column, value := range condition {
+		if ops, ok := value.(goqu.Op); ok {
+			normalized := make(goqu.Op, len(ops))
+			for k, v := range ops {
+				normalized[k] = normalizeValue(v)
+			}
+			result[column] = normalized
+			continue
+		}
+		result[column] = normalizeValue(value)
+	}
+	return result
+}
+
+// normalizeArgs 对原生 SQL 片段的参数逐个做 normalizeValue 转换
+func normalizeArgs(args []interface{}) []interface{} {
+	normalized := make([]interface{}, len(args))
+	for i, arg := range args {
+		normalized[i] = normalizeValue(arg)
+	}
+	return normalized
+}
+
+// ConditionGroup 嵌套的 AND/OR 条件组，配合 WhereGroup 构造任意层级的布尔表达式：
+//
+//	q.WhereGroup(func(g core.ConditionGroup) {
+//	    g.And(goqu.C("a").Eq(1), goqu.C("b").Eq(2)).
+//	        OrGroup(func(g core.ConditionGroup) {
+//	            g.And(goqu.C("c").Eq(3)).Or(goqu.C("d").Eq(4))
+//	        })
+//	})
+//
+// 生成 (((a = 1) AND (b = 2)) OR ((c = 3) OR (d = 4)))
+type ConditionGroup interface {
+	// And 将条件以 AND 追加到当前组
+	And(conditions ...goqu.Expression) ConditionGroup
+	// Or 将条件（多个时先以 AND 连接）以 OR 追加到当前组
+	Or(conditions ...goqu.Expression) ConditionGroup
+	// AndGroup 将 fn 构造的子组以 AND 追加到当前组
+	AndGroup(fn func(g ConditionGroup)) ConditionGroup
+	// OrGroup 将 fn 构造的子组以 OR 追加到当前组
+	OrGroup(fn func(g ConditionGroup)) ConditionGroup
+}
+
+type conditionGroup struct {
+	expr exp.ExpressionList // 为空表示还没有条件
+}
+
+// newConditionGroup 执行 fn 并返回构造出的条件组
+func newConditionGroup(fn func(g ConditionGroup)) *conditionGroup {
+	g := &conditionGroup{}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+func (g *conditionGroup) And(conditions ...goqu.Expression) ConditionGroup {
+	if len(conditions) == 0 {
+		return g
+	}
+	if g.expr == nil {
+		g.expr = goqu.And(conditions...)
+		return g
+	}
+	g.expr = goqu.And(append([]goqu.Expression{g.expr}, conditions...)...)
+	return g
+}
+
+func (g *conditionGroup) Or(conditions ...goqu.Expression) ConditionGroup {
+	if len(conditions) == 0 {
+		return g
+	}
+	if g.expr == nil {
+		return g.And(conditions...)
+	}
+	g.expr = goqu.Or(g.expr, goqu.And(conditions...))
+	return g
+}
+
+func (g *conditionGroup) AndGroup(fn func(g ConditionGroup)) ConditionGroup {
+	if sub := newConditionGroup(fn); sub.expr != nil {
+		g.And(sub.expr)
+	}
+	return g
+}
+
+func (g *conditionGroup) OrGroup(fn func(g ConditionGroup)) ConditionGroup {
+	if sub := newConditionGroup(fn); sub.expr != nil {
+		g.Or(sub.expr)
+	}
+	return g
+}
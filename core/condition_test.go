@@ -0,0 +1,116 @@
+package core
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+func TestConditionBuilder(t *testing.T) {
+	roles := []string{"admin", "editor"}
+	tests := []struct {
+		name     string
+		built    goqu.Ex
+		expected goqu.Ex
+	}{
+		{"Eq", Cond().Eq("status", 1).Build(), goqu.Ex{"status": 1}},
+		{"Ne", Cond().Ne("status", 1).Build(), goqu.Ex{"status": goqu.Op{"neq": 1}}},
+		{"Gt", Cond().Gt("age", 18).Build(), goqu.Ex{"age": goqu.Op{"gt": 18}}},
+		{"Gte", Cond().Gte("age", 18).Build(), goqu.Ex{"age": goqu.Op{"gte": 18}}},
+		{"Lt", Cond().Lt("age", 60).Build(), goqu.Ex{"age": goqu.Op{"lt": 60}}},
+		{"Lte", Cond().Lte("age", 60).Build(), goqu.Ex{"age": goqu.Op{"lte": 60}}},
+		{"In", Cond().In("role", roles).Build(), goqu.Ex{"role": roles}},
+		{"Like", Cond().Like("name", "%bob%").Build(), goqu.Ex{"name": goqu.Op{"like": "%bob%"}}},
+		{"IsNull", Cond().IsNull("deleted_at").Build(), goqu.Ex{"deleted_at": nil}},
+		{
+			"Combined",
+			Cond().Eq("status", 1).Gt("age", 18).Lt("age", 60).In("role", roles).Build(),
+			goqu.Ex{"status": 1, "age": goqu.Op{"gt": 18, "lt": 60}, "role": roles},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builtSQL, _, err := goqu.Dialect("mysql").From("users").Where(tt.built).ToSQL()
+			if err != nil {
+				t.Fatalf("ToSQL failed: %v", err)
+			}
+			expectedSQL, _, err := goqu.Dialect("mysql").From("users").Where(tt.expected).ToSQL()
+			if err != nil {
+				t.Fatalf("ToSQL failed: %v", err)
+			}
+			if builtSQL != expectedSQL {
+				t.Errorf("Expected SQL %q, got %q", expectedSQL, builtSQL)
+			}
+		})
+	}
+}
+
+type testStatus int
+
+const testStatusActive testStatus = 1
+
+type testRole string
+
+const (
+	testRoleAdmin  testRole = "admin"
+	testRoleEditor testRole = "editor"
+)
+
+// testUpperRole 自定义的 Valuer，写入时转为大写
+type testUpperRole string
+
+func (r testUpperRole) Value() (driver.Value, error) {
+	return strings.ToUpper(string(r)), nil
+}
+
+func TestTypedConstantsNormalized(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "users", MySQL)
+
+	built := Cond().Eq("status", testStatusActive).In("role", []testRole{testRoleAdmin, testRoleEditor}).Build()
+	expected := goqu.Ex{"status": goqu.Op{"eq": 1}, "role": goqu.Op{"in": []string{"admin", "editor"}}}
+	if !reflect.DeepEqual(built, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, built)
+	}
+
+	ex := normalizeEx(goqu.Ex{"status": goqu.Op{"neq": testStatusActive}, "role": []interface{}{testRoleAdmin, "x"}})
+	expected = goqu.Ex{"status": goqu.Op{"neq": 1}, "role": []interface{}{"admin", "x"}}
+	if !reflect.DeepEqual(ex, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, ex)
+	}
+
+	tests := []struct {
+		name string
+		q    IQueryable[TestEntity]
+		args []interface{}
+	}{
+		{"Where", repo.Query().Where(goqu.Ex{"status": testStatusActive}), []interface{}{int64(1)}},
+		{"WhereOp", repo.Query().Where(goqu.Ex{"status": goqu.Op{"neq": testStatusActive}}), []interface{}{int64(1)}},
+		{"WhereEq", repo.Query().WhereEq("status", testStatusActive), []interface{}{int64(1)}},
+		{"WhereIn", repo.Query().Where(goqu.Ex{"role": []testRole{testRoleAdmin}}), []interface{}{"admin"}},
+		{"WhereRaw", repo.Query().WhereRaw("status = ? AND role = ?", testStatusActive, testRoleAdmin), []interface{}{int64(1), "admin"}},
+		{"WhereStruct", repo.Query().WhereStruct(struct {
+			Status testStatus `db:"status"`
+		}{testStatusActive}), []interface{}{int64(1)}},
+	}
+	for _, tt := range tests {
+		_, args, err := tt.q.Dataset().Prepared(true).ToSQL()
+		if err != nil {
+			t.Fatalf("%s: ToSQL failed: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %#v, got %#v", tt.name, tt.args, args)
+		}
+	}
+
+	// 实现了 driver.Valuer 的类型和内置类型保持不变
+	if v := normalizeValue(testUpperRole("admin")); v != testUpperRole("admin") {
+		t.Errorf("Expected a Valuer to be kept, got %#v", v)
+	}
+	if v := normalizeValue([]byte("x")); !reflect.DeepEqual(v, []byte("x")) {
+		t.Errorf("Expected []byte to be kept, got %#v", v)
+	}
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// ErrInvalidCursor 游标无法解码
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	cursorInt    = "i:"
+	cursorString = "s:"
+)
+
+// PageResultCursor 游标分页结果，NextCursor 为空表示没有下一页
+type PageResultCursor[T any] struct {
+	Items      []*T
+	NextCursor string
+}
+
+// HasNext 是否存在下一页
+func (p *PageResultCursor[T]) HasNext() bool {
+	return p.NextCursor != ""
+}
+
+// EncodeCursor 将游标列的值编码为不透明的 base64 令牌，整数按 int64 编码，其余类型按字符串编码
+func EncodeCursor(value interface{}) string {
+	var raw string
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		raw = cursorInt + strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		raw = cursorInt + strconv.FormatUint(v.Uint(), 10)
+	default:
+		raw = cursorString + fmt.Sprint(value)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor 解码 EncodeCursor 生成的令牌，返回 int64 或 string，空令牌返回 nil
+func DecodeCursor(token string) (interface{}, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	raw := string(data)
+	switch {
+	case strings.HasPrefix(raw, cursorInt):
+		n, err := strconv.ParseInt(strings.TrimPrefix(raw, cursorInt), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		return n, nil
+	case strings.HasPrefix(raw, cursorString):
+		return strings.TrimPrefix(raw, cursorString), nil
+	}
+	return nil, ErrInvalidCursor
+}
+
+// AfterCursor 按 column 升序做游标分页，只返回 column 大于令牌所指值的记录
+// 令牌为空或无法解码时从头开始，配合 ToCursorPage 使用
+func (q *Queryable[T]) AfterCursor(column string, token string) IQueryable[T] {
+	q = q.clone()
+	q.cursorColumn = column
+	if value, err := DecodeCursor(token); err == nil && value != nil {
+		q.query = q.query.Where(goqu.I(column).Gt(value))
+		q.hasWhere = true
+	}
+	q.query = q.query.Order(goqu.I(column).Asc())
+	q.hasOrder = true
+	return q
+}
+
+// AfterComposite 按多列组合键做键集分页，如 (created_at, id)，排序列不唯一时用后续列打破平局
+// 按 cols 升序排序并取 pageSize 行，lastValues 为上一页最后一行对应列的值，为空时取第一页
+// 条件等价于 (a, b) > (?, ?)；MySQL/StarRocks 展开为 a > ? OR (a = ? AND b > ?)，见 keysetCondition
+func (q *Queryable[T]) AfterComposite(cols []string, lastValues []interface{}, pageSize int) IQueryable[T] {
+	q = q.clone()
+	if len(cols) == 0 {
+		q.query = q.query.SetError(errors.New("AfterComposite requires at least one column"))
+		return q
+	}
+	if len(lastValues) > 0 {
+		if len(lastValues) != len(cols) {
+			q.query = q.query.SetError(fmt.Errorf("AfterComposite: %d columns but %d values", len(cols), len(lastValues)))
+			return q
+		}
+		// 所有支持的方言都使用展开形式：旧版本 MySQL 不支持行值比较，且行值比较在 MySQL 中不一定能使用索引范围扫描
+		q.query = q.query.Where(keysetCondition(cols, lastValues, false))
+		q.hasWhere = true
+	}
+
+	orders := make([]exp.OrderedExpression, len(cols))
+	for i, col := range cols {
+		orders[i] = goqu.I(col).Asc()
+	}
+	q.query = q.query.Order(orders...)
+	q.hasOrder = true
+	return q.Take(pageSize)
+}
+
+// keysetCondition 构造 (cols) > (values) 条件，rowValues 为 false 时展开为
+// c1 > v1 OR (c1 = v1 AND c2 > v2) OR (c1 = v1 AND c2 = v2 AND c3 > v3)
+func keysetCondition(cols []string, values []interface{}, rowValues bool) exp.Expression {
+	if rowValues {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+		idents := make([]interface{}, len(cols))
+		for i, col := range cols {
+			idents[i] = goqu.I(col)
+		}
+		return goqu.L("("+placeholders+") > ("+placeholders+")", append(idents, values...)...)
+	}
+
+	branches := make([]exp.Expression, len(cols))
+	for i := range cols {
+		terms := make([]exp.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, goqu.I(cols[j]).Eq(values[j]))
+		}
+		terms = append(terms, goqu.I(cols[i]).Gt(values[i]))
+		branches[i] = goqu.And(terms...)
+	}
+	return goqu.Or(branches...)
+}
+
+// ToCursorPage 取一页数据，多取一条判断是否存在下一页，并生成下一页的游标
+func (q *Queryable[T]) ToCursorPage(size int) (_ *PageResultCursor[T], err error) {
+	defer wrapQueryError("ToCursorPage", q.table, &err)
+	return q.ToCursorPageTx(q.baseContext(), size)
+}
+
+func (q *Queryable[T]) ToCursorPageTx(ctx context.Context, size int) (_ *PageResultCursor[T], err error) {
+	defer wrapQueryError("ToCursorPageTx", q.table, &err)
+	if q.cursorColumn == "" {
+		return nil, errors.New("ToCursorPage requires AfterCursor")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("page size must be positive, got %d", size)
+	}
+
+	items, err := q.Take(size + 1).ToListTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page := &PageResultCursor[T]{Items: items}
+	if len(items) <= size {
+		return page, nil
+	}
+
+	page.Items = items[:size]
+	value, err := q.cursorValue(page.Items[size-1])
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = EncodeCursor(value)
+	return page, nil
+}
+
+// cursorValue 读取实体中游标列对应的字段值
+func (q *Queryable[T]) cursorValue(entity *T) (interface{}, error) {
+	v := reflect.ValueOf(entity).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cursor column %s requires a struct entity", q.cursorColumn)
+	}
+	column := q.cursorColumn
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	for _, c := range entityColumns(v.Type(), q.nameMapper) {
+		if c.name != column {
+			continue
+		}
+		if field := fieldByIndex(v, c.index); field.IsValid() {
+			return field.Interface(), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cursor column %s not found in %s", q.cursorColumn, v.Type().Name())
+}
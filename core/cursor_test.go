@@ -0,0 +1,136 @@
+package core
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{int64(42), int64(42)},
+		{int64(-7), int64(-7)},
+		{int(9), int64(9)},
+		{"abc", "abc"},
+		{"i:not-a-number", "i:not-a-number"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		token := EncodeCursor(c.value)
+		decoded, err := DecodeCursor(token)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q) failed: %v", token, err)
+		}
+		if decoded != c.expected {
+			t.Errorf("Expected %#v, got %#v", c.expected, decoded)
+		}
+	}
+
+	if v, err := DecodeCursor(""); v != nil || err != nil {
+		t.Errorf("Expected empty token to decode to nil, got %v, %v", v, err)
+	}
+	for _, token := range []string{"!!!", EncodeCursor("x")[:1], "eDox"} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestQueryableAfterCursor(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, _, err := repo.Query().AfterCursor("id", EncodeCursor(int64(10))).Take(20).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `test_table` WHERE (`id` > 10) ORDER BY `id` ASC LIMIT 20"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	for _, token := range []string{"", "not a cursor"} {
+		sql, _, err = repo.Query().AfterCursor("id", token).ToSQL()
+		if err != nil {
+			t.Fatalf("ToSQL failed: %v", err)
+		}
+		if expected := "SELECT * FROM `test_table` ORDER BY `id` ASC"; sql != expected {
+			t.Errorf("Expected SQL %q, got %q", expected, sql)
+		}
+	}
+}
+
+func TestQueryableToCursorPage(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	columns := []string{"id", "name", "status"}
+	fake.queueResult(columns,
+		[]driver.Value{int64(11), "a", int64(1)},
+		[]driver.Value{int64(12), "b", int64(1)},
+		[]driver.Value{int64(13), "c", int64(1)},
+	)
+
+	page, err := repo.Query().AfterCursor("id", EncodeCursor(int64(10))).ToCursorPage(2)
+	if err != nil {
+		t.Fatalf("ToCursorPage failed: %v", err)
+	}
+	if len(page.Items) != 2 || !page.HasNext() {
+		t.Fatalf("Expected 2 items and a next page, got %d items, next %q", len(page.Items), page.NextCursor)
+	}
+	if next, _ := DecodeCursor(page.NextCursor); next != int64(12) {
+		t.Errorf("Expected next cursor 12, got %v", next)
+	}
+	expected := "SELECT `id`, `name`, `status` FROM `test_table` WHERE (`id` > 10) ORDER BY `id` ASC LIMIT 3"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	fake.queueResult(columns, []driver.Value{int64(13), "c", int64(1)})
+	page, err = repo.Query().AfterCursor("id", page.NextCursor).ToCursorPage(2)
+	if err != nil {
+		t.Fatalf("ToCursorPage failed: %v", err)
+	}
+	if len(page.Items) != 1 || page.HasNext() {
+		t.Errorf("Expected last page with 1 item, got %d items, next %q", len(page.Items), page.NextCursor)
+	}
+
+	if _, err := repo.Query().ToCursorPage(2); err == nil {
+		t.Error("Expected error without AfterCursor")
+	}
+}
+
+func TestQueryableAfterComposite(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	cols := []string{"created_at", "id"}
+
+	sql, _, err := repo.Query().AfterComposite(cols, []interface{}{"2024-05-01", int64(10)}, 20).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `test_table` WHERE ((`created_at` > '2024-05-01') OR ((`created_at` = '2024-05-01') AND (`id` > 10))) ORDER BY `created_at` ASC, `id` ASC LIMIT 20"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().AfterComposite(cols, nil, 20).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if expected := "SELECT * FROM `test_table` ORDER BY `created_at` ASC, `id` ASC LIMIT 20"; sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	if _, _, err := repo.Query().AfterComposite(cols, []interface{}{int64(1)}, 20).ToSQL(); err == nil {
+		t.Error("Expected error for mismatched columns and values")
+	}
+
+	sql, _, err = repo.Query().Dataset().Where(keysetCondition(cols, []interface{}{"2024-05-01", int64(10)}, true)).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if expected := "SELECT * FROM `test_table` WHERE (`created_at`, `id`) > ('2024-05-01', 10)"; sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+}
@@ -3,17 +3,69 @@ package core
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	"go.uber.org/zap"
 )
 
 // DBLogger wraps sqlx.DB with logging capabilities
+//
+// When read replicas are configured, read methods (Get, Select, Queryx, QueryRowx
+// and their Context variants) are routed to the replicas in round-robin order,
+// while writes and transactions always use the primary.
 type DBLogger struct {
 	*sqlx.DB
-	logger *zap.Logger
-	prefix string
+	logger  *zap.Logger
+	prefix  string
+	dialect DialectType
+
+	replicas    []*sqlx.DB
+	replicaNext *uint64
+
+	// tx, when set, serves every read; see InTx
+	tx *Tx
+
+	// OnSQL, when set, is called with the final SQL and args right before every
+	// statement runs. It is lighter than zap logging and handy for asserting the
+	// generated SQL in tests. Set it before the DBLogger is shared.
+	OnSQL func(sql string, args []interface{})
+
+	// DryRun, when true, logs every write (Exec, ExecContext, NamedExec,
+	// transactional Exec and StreamLoad) and returns an empty result without
+	// running it.
+	// Reads still execute.
+	DryRun bool
+
+	// LogArgs controls whether bound argument values are written to the logs.
+	// NewDBLogger enables it; turn it off when arguments may carry PII.
+	LogArgs bool
+
+	// RedactArgs, when set, is applied to the arguments before they are logged,
+	// e.g. to mask emails or tokens. It never changes what is executed.
+	RedactArgs func(args []interface{}) []interface{}
+}
+
+// dryRunResult is the sql.Result returned for statements skipped in dry-run mode
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, nil }
+func (dryRunResult) RowsAffected() (int64, error) { return 0, nil }
+
+// skipWrite logs the statement and reports whether it must be skipped in dry-run mode
+func (db *DBLogger) skipWrite(query string, args []interface{}) bool {
+	if !db.DryRun {
+		return false
+	}
+	fields := []zap.Field{zap.String("query", query)}
+	fields = append(fields, db.argsFields(args)...)
+	fields = append(fields, zap.String("prefix", db.prefix))
+	db.logger.Info("Dry run, statement not executed", fields...)
+	return true
 }
 
 // Tx wraps sqlx.Tx for transaction operations
@@ -25,12 +77,30 @@ func NewDBLogger(db *sqlx.DB, logger *zap.Logger, prefix string) *DBLogger {
 		logger, _ = zap.NewProduction()
 	}
 	return &DBLogger{
-		DB:     db,
-		logger: logger,
-		prefix: prefix,
+		DB:      db,
+		logger:  logger,
+		prefix:  prefix,
+		LogArgs: true,
 	}
 }
 
+// NewDBLoggerWithDialect wraps an already configured sqlx.DB (for example one backed
+// by go-sqlmock) and records its dialect, which repositories use when they are
+// created without an explicit DialectType
+func NewDBLoggerWithDialect(db *sqlx.DB, dialect DialectType, logger *zap.Logger, prefix string) *DBLogger {
+	d := NewDBLogger(db, logger, prefix)
+	d.dialect = dialect
+	return d
+}
+
+// Dialect returns the dialect of the connection, MySQL when none was given
+func (db *DBLogger) Dialect() DialectType {
+	if db == nil || db.dialect == "" {
+		return MySQL
+	}
+	return db.dialect
+}
+
 // ConnectMySQL connects to MySQL database
 func ConnectMySQL(dsn string, logger *zap.Logger, prefix string) (*DBLogger, error) {
 	db, err := sqlx.Connect("mysql", dsn)
@@ -46,6 +116,219 @@ func ConnectMySQL(dsn string, logger *zap.Logger, prefix string) (*DBLogger, err
 	return NewDBLogger(db, logger, prefix), nil
 }
 
+// NewDBLoggerWithReplicas creates a DBLogger that routes reads to the given replicas
+func NewDBLoggerWithReplicas(primary *sqlx.DB, replicas []*sqlx.DB, logger *zap.Logger, prefix string) *DBLogger {
+	db := NewDBLogger(primary, logger, prefix)
+	db.replicas = replicas
+	db.replicaNext = new(uint64)
+	return db
+}
+
+// ConnectMySQLWithReplicas connects to a MySQL primary and its read replicas
+func ConnectMySQLWithReplicas(primaryDSN string, replicaDSNs []string, logger *zap.Logger, prefix string) (*DBLogger, error) {
+	primary, err := ConnectMySQL(primaryDSN, logger, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]*sqlx.DB, 0, len(replicaDSNs))
+	for i, dsn := range replicaDSNs {
+		replica, err := ConnectMySQL(dsn, logger, prefix)
+		if err != nil {
+			primary.Close()
+			for _, r := range replicas {
+				r.Close()
+			}
+			return nil, fmt.Errorf("connect replica %d: %w", i, err)
+		}
+		replicas = append(replicas, replica.DB)
+	}
+
+	return NewDBLoggerWithReplicas(primary.DB, replicas, primary.logger, prefix), nil
+}
+
+// Primary returns a view of the DBLogger that sends every query to the primary.
+// Use it for read-after-write consistency.
+func (db *DBLogger) Primary() *DBLogger {
+	primary := *db
+	primary.replicas = nil
+	return &primary
+}
+
+type primaryContextKey struct{}
+
+// WithPrimary marks the context so that reads using it are served by the primary
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// ErrNoDatabase is returned when a query runs without a database connection,
+// e.g. a repository created with a nil DBLogger
+var ErrNoDatabase = errors.New("no database connection")
+
+// ready reports ErrNoDatabase instead of letting sqlx dereference a nil connection
+func (db *DBLogger) ready() error {
+	if db == nil || db.DB == nil {
+		return ErrNoDatabase
+	}
+	return nil
+}
+
+// readConn is the read API shared by sqlx.DB and sqlx.Tx
+type readConn interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	Queryx(query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowx(query string, args ...interface{}) *sqlx.Row
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+	QueryRow(query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// InTx returns a view of the DBLogger whose reads run inside tx, so they see
+// the transaction's uncommitted writes. Writes still go through the DBLogger.
+func (db *DBLogger) InTx(tx *Tx) *DBLogger {
+	view := db.Primary()
+	view.tx = tx
+	return view
+}
+
+// withMapper returns a view of the DBLogger whose reads map struct fields to
+// columns with mapper instead of the connection's own sqlx mapper. The view
+// shares the connection pools and the transaction of the DBLogger.
+func (db *DBLogger) withMapper(mapper *reflectx.Mapper) *DBLogger {
+	if db == nil || mapper == nil {
+		return db
+	}
+	view := *db
+	if db.DB != nil {
+		view.DB = mappedDB(db.DB, mapper)
+	}
+	if len(db.replicas) > 0 {
+		view.replicas = make([]*sqlx.DB, len(db.replicas))
+		for i, replica := range db.replicas {
+			view.replicas[i] = mappedDB(replica, mapper)
+		}
+	}
+	if db.tx != nil {
+		tx := *db.tx
+		tx.Mapper = mapper
+		view.tx = &tx
+	}
+	return &view
+}
+
+// mappedDB returns a copy of conn that uses mapper; it shares conn's pool
+func mappedDB(conn *sqlx.DB, mapper *reflectx.Mapper) *sqlx.DB {
+	mapped := *conn
+	mapped.Mapper = mapper
+	return &mapped
+}
+
+// reader picks the connection used for a read
+func (db *DBLogger) reader(ctx context.Context) readConn {
+	if db.tx != nil {
+		return db.tx
+	}
+	if len(db.replicas) == 0 {
+		return db.DB
+	}
+	if forced, _ := ctx.Value(primaryContextKey{}).(bool); forced {
+		return db.DB
+	}
+	n := atomic.AddUint64(db.replicaNext, 1)
+	return db.replicas[(n-1)%uint64(len(db.replicas))]
+}
+
+// Get reads a single row, routed to a replica when configured
+func (db *DBLogger) Get(dest interface{}, query string, args ...interface{}) error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	db.traceSQL(query, args)
+	return db.reader(context.Background()).Get(dest, query, args...)
+}
+
+// GetContext reads a single row with context, routed to a replica when configured
+func (db *DBLogger) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	db.traceSQL(query, args)
+	return db.reader(ctx).GetContext(ctx, dest, query, args...)
+}
+
+// Select reads rows, routed to a replica when configured
+func (db *DBLogger) Select(dest interface{}, query string, args ...interface{}) error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	db.traceSQL(query, args)
+	return db.reader(context.Background()).Select(dest, query, args...)
+}
+
+// SelectContext reads rows with context, routed to a replica when configured
+func (db *DBLogger) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	db.traceSQL(query, args)
+	return db.reader(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+// Queryx queries rows, routed to a replica when configured
+func (db *DBLogger) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
+	db.traceSQL(query, args)
+	return db.reader(context.Background()).Queryx(query, args...)
+}
+
+// QueryxContext queries rows with context, routed to a replica when configured
+func (db *DBLogger) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
+	db.traceSQL(query, args)
+	return db.reader(ctx).QueryxContext(ctx, query, args...)
+}
+
+// QueryRowx queries a single row, routed to a replica when configured
+func (db *DBLogger) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	db.traceSQL(query, args)
+	return db.reader(context.Background()).QueryRowx(query, args...)
+}
+
+// QueryRowxContext queries a single row with context, routed to a replica when configured
+func (db *DBLogger) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	db.traceSQL(query, args)
+	return db.reader(ctx).QueryRowxContext(ctx, query, args...)
+}
+
+// QueryRow queries a single row, routed to a replica when configured
+func (db *DBLogger) QueryRow(query string, args ...interface{}) *sql.Row {
+	db.traceSQL(query, args)
+	return db.reader(context.Background()).QueryRow(query, args...)
+}
+
+// Close closes the primary and all replicas
+func (db *DBLogger) Close() error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	err := db.DB.Close()
+	for _, replica := range db.replicas {
+		if closeErr := replica.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
 // GetPrefix returns the database prefix
 func (db *DBLogger) GetPrefix() string {
 	return db.prefix
@@ -53,11 +336,21 @@ func (db *DBLogger) GetPrefix() string {
 
 // Begin starts a transaction
 func (db *DBLogger) Begin() (*Tx, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
 	return db.DB.Beginx()
 }
 
 // ExecContext executes a query with context
 func (db *DBLogger) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
+	db.traceSQL(query, args)
+	if db.skipWrite(query, args) {
+		return dryRunResult{}, nil
+	}
 	start := time.Now()
 	result, err := db.DB.ExecContext(ctx, query, args...)
 	duration := time.Since(start)
@@ -66,25 +359,131 @@ func (db *DBLogger) ExecContext(ctx context.Context, query string, args ...inter
 	return result, err
 }
 
-// QueryContext queries with context
+// Exec executes a query on the primary
+func (db *DBLogger) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
+	db.traceSQL(query, args)
+	if db.skipWrite(query, args) {
+		return dryRunResult{}, nil
+	}
+	return db.DB.Exec(query, args...)
+}
+
+// NamedExec executes a named query on the primary
+func (db *DBLogger) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
+	db.traceSQL(query, []interface{}{arg})
+	if db.skipWrite(query, []interface{}{arg}) {
+		return dryRunResult{}, nil
+	}
+	return db.DB.NamedExec(query, arg)
+}
+
+// NamedExecContext runs a named statement with context and logs it like ExecContext
+func (db *DBLogger) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
+	args := []interface{}{arg}
+	db.traceSQL(query, args)
+	if db.skipWrite(query, args) {
+		return dryRunResult{}, nil
+	}
+	start := time.Now()
+	result, err := db.DB.NamedExecContext(ctx, query, arg)
+	duration := time.Since(start)
+
+	db.logQuery(ctx, "NamedExec", query, args, err, duration)
+	return result, err
+}
+
+// QueryContext queries with context, routed to a replica when configured
 func (db *DBLogger) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := db.ready(); err != nil {
+		return nil, err
+	}
 	start := time.Now()
-	rows, err := db.DB.QueryContext(ctx, query, args...)
+	db.traceSQL(query, args)
+	rows, err := db.reader(ctx).QueryContext(ctx, query, args...)
 	duration := time.Since(start)
 
 	db.logQuery(ctx, "Query", query, args, err, duration)
 	return rows, err
 }
 
+// HealthCheckError reports which connection failed a health check
+type HealthCheckError struct {
+	Handle string // "primary" or "replica[i]"
+	Err    error
+}
+
+func (e *HealthCheckError) Error() string {
+	return fmt.Sprintf("health check failed on %s: %v", e.Handle, e.Err)
+}
+
+func (e *HealthCheckError) Unwrap() error {
+	return e.Err
+}
+
+// HealthCheck pings the primary within ctx's deadline, suitable for a readiness probe
+func (db *DBLogger) HealthCheck(ctx context.Context) error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	return db.ping(ctx, "primary", db.DB)
+}
+
+// HealthCheckAll pings the primary and every replica. Each failure is returned as a
+// *HealthCheckError, joined together when more than one connection is down.
+func (db *DBLogger) HealthCheckAll(ctx context.Context) error {
+	if err := db.ready(); err != nil {
+		return err
+	}
+	errs := []error{db.ping(ctx, "primary", db.DB)}
+	for i, replica := range db.replicas {
+		errs = append(errs, db.ping(ctx, fmt.Sprintf("replica[%d]", i), replica))
+	}
+	return errors.Join(errs...)
+}
+
+// ping pings a single connection and logs the outcome
+func (db *DBLogger) ping(ctx context.Context, handle string, conn *sqlx.DB) error {
+	start := time.Now()
+	err := conn.PingContext(ctx)
+	fields := []zap.Field{
+		zap.String("handle", handle),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		db.logger.Warn("Health check failed", append(fields, zap.Error(err))...)
+		return &HealthCheckError{Handle: handle, Err: err}
+	}
+	db.logger.Debug("Health check passed", fields...)
+	return nil
+}
+
+// traceSQL passes the statement to OnSQL when it is set
+func (db *DBLogger) traceSQL(query string, args []interface{}) {
+	if db.OnSQL != nil {
+		db.OnSQL(query, args)
+	}
+}
+
 // logQuery logs database operations
 func (db *DBLogger) logQuery(ctx context.Context, operation, query string, args []interface{}, err error, duration time.Duration) {
 	fields := []zap.Field{
 		zap.String("operation", operation),
 		zap.String("query", query),
-		zap.Any("args", args),
+	}
+	fields = append(fields, db.argsFields(args)...)
+	fields = append(fields,
 		zap.Duration("duration", duration),
 		zap.String("prefix", db.prefix),
-	}
+	)
 
 	if err != nil {
 		fields = append(fields, zap.Error(err))
@@ -95,3 +494,14 @@ func (db *DBLogger) logQuery(ctx context.Context, operation, query string, args
 		db.logger.Debug("Database operation", fields...)
 	}
 }
+
+// argsFields returns the log field for the bound arguments, honouring LogArgs and RedactArgs
+func (db *DBLogger) argsFields(args []interface{}) []zap.Field {
+	if !db.LogArgs {
+		return nil
+	}
+	if db.RedactArgs != nil {
+		args = db.RedactArgs(args)
+	}
+	return []zap.Field{zap.Any("args", args)}
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDBLoggerReplicaRouting(t *testing.T) {
+	primary, primaryFake := newFakeSQLX(t, t.Name()+"/primary")
+	replica1, replica1Fake := newFakeSQLX(t, t.Name()+"/replica1")
+	replica2, replica2Fake := newFakeSQLX(t, t.Name()+"/replica2")
+	db := NewDBLoggerWithReplicas(primary, []*sqlx.DB{replica1, replica2}, zap.NewNop(), "")
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	for i := 0; i < 4; i++ {
+		if _, err := repo.Query().ToList(); err != nil {
+			t.Fatalf("ToList failed: %v", err)
+		}
+	}
+	if len(replica1Fake.Queries()) != 2 || len(replica2Fake.Queries()) != 2 {
+		t.Errorf("Expected reads to be spread round-robin, got %d and %d",
+			len(replica1Fake.Queries()), len(replica2Fake.Queries()))
+	}
+
+	if err := repo.UpdateFieldsByCondition(goqu.Ex{"id": 1}, map[string]interface{}{"status": 1}); err != nil {
+		t.Fatalf("UpdateFieldsByCondition failed: %v", err)
+	}
+	if len(primaryFake.Execs()) != 1 {
+		t.Errorf("Expected writes to go to the primary, got %d", len(primaryFake.Execs()))
+	}
+
+	if _, err := NewRepository[TestEntity](db.Primary(), "test_table", MySQL).Query().ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if _, err := repo.Query().ToListTx(WithPrimary(context.Background())); err != nil {
+		t.Fatalf("ToListTx failed: %v", err)
+	}
+	if len(primaryFake.Queries()) != 2 {
+		t.Errorf("Expected forced reads to go to the primary, got %d", len(primaryFake.Queries()))
+	}
+
+	uow := NewUnitOfWork(db)
+	if err := uow.Begin(); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer uow.Rollback()
+	if _, err := repo.WithUnitOfWork(uow).Query().ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if len(primaryFake.Queries()) != 3 {
+		t.Errorf("Expected reads inside a unit of work to go to the primary, got %d", len(primaryFake.Queries()))
+	}
+	if len(replica1Fake.Queries())+len(replica2Fake.Queries()) != 4 {
+		t.Errorf("Expected replicas to receive only the routed reads")
+	}
+}
+
+func TestDBLoggerOnSQL(t *testing.T) {
+	db, _ := newFakeDBLogger(t)
+	var captured []string
+	db.OnSQL = func(sql string, args []interface{}) {
+		captured = append(captured, sql)
+	}
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	if _, err := repo.Query().Where(goqu.Ex{"status": 1}).ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if err := repo.BatchDelete(goqu.Ex{"status": 0}); err != nil {
+		t.Fatalf("BatchDelete failed: %v", err)
+	}
+
+	expected := []string{
+		"SELECT `id`, `name`, `status` FROM `test_table` WHERE (`status` = 1)",
+		"DELETE `test_table` FROM `test_table` WHERE (`status` = 0)",
+	}
+	if len(captured) != len(expected) {
+		t.Fatalf("Expected %d captured statements, got %v", len(expected), captured)
+	}
+	for i := range expected {
+		if captured[i] != expected[i] {
+			t.Errorf("Expected SQL %q, got %q", expected[i], captured[i])
+		}
+	}
+
+	db.OnSQL = nil
+	if _, err := repo.Query().ToList(); err != nil {
+		t.Fatalf("ToList without OnSQL failed: %v", err)
+	}
+}
+
+func TestDBLoggerHealthCheck(t *testing.T) {
+	primary, primaryFake := newFakeSQLX(t, t.Name()+"/primary")
+	replica1, _ := newFakeSQLX(t, t.Name()+"/replica1")
+	replica2, replica2Fake := newFakeSQLX(t, t.Name()+"/replica2")
+	db := NewDBLoggerWithReplicas(primary, []*sqlx.DB{replica1, replica2}, zap.NewNop(), "")
+
+	if err := db.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("Expected healthy primary, got %v", err)
+	}
+	if err := db.HealthCheckAll(context.Background()); err != nil {
+		t.Fatalf("Expected all handles healthy, got %v", err)
+	}
+
+	pingErr := errors.New("connection refused")
+	replica2Fake.setPingErr(pingErr)
+	err := db.HealthCheckAll(context.Background())
+	var healthErr *HealthCheckError
+	if !errors.As(err, &healthErr) || healthErr.Handle != "replica[1]" {
+		t.Fatalf("Expected failure on replica[1], got %v", err)
+	}
+	if !errors.Is(err, pingErr) {
+		t.Errorf("Expected the ping error to be wrapped, got %v", err)
+	}
+	if err := db.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck should only ping the primary, got %v", err)
+	}
+
+	primaryFake.setPingErr(pingErr)
+	if err := db.HealthCheck(context.Background()); !errors.As(err, &healthErr) || healthErr.Handle != "primary" {
+		t.Errorf("Expected failure on primary, got %v", err)
+	}
+}
+
+func TestDBLoggerDryRun(t *testing.T) {
+	sqlxDB, fake := newFakeSQLX(t, t.Name())
+	observed, logs := observer.New(zap.InfoLevel)
+	db := NewDBLogger(sqlxDB, zap.New(observed), "")
+	db.DryRun = true
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	if err := repo.Create(&TestEntity{ID: 1, Name: "a"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	entities := []*TestEntity{{ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	if err := repo.BatchInsert(entities, &BatchInsertOption{BatchSize: 1}); err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if err := repo.UpdateFieldsByCondition(goqu.Ex{"id": 1}, map[string]interface{}{"status": 2}); err != nil {
+		t.Fatalf("UpdateFieldsByCondition failed: %v", err)
+	}
+	if _, err := repo.Query().ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+
+	if execs := fake.Execs(); len(execs) != 0 {
+		t.Errorf("Expected no statements to be executed, got %v", execs)
+	}
+	if len(fake.Queries()) != 1 {
+		t.Errorf("Expected reads to still execute, got %d queries", len(fake.Queries()))
+	}
+
+	entries := logs.FilterMessage("Dry run, statement not executed").All()
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 logged statements (one per batch), got %d", len(entries))
+	}
+	if got := entries[3].ContextMap()["query"]; got != "UPDATE `test_table` SET `status`=2 WHERE (`id` = 1)" {
+		t.Errorf("Unexpected logged SQL: %v", got)
+	}
+}
+
+func TestDBLoggerDryRunAtomicNamedExec(t *testing.T) {
+	sqlxDB, fake := newFakeSQLX(t, t.Name())
+	observed, logs := observer.New(zap.InfoLevel)
+	db := NewDBLogger(sqlxDB, zap.New(observed), "")
+	db.DryRun = true
+	var traced []string
+	db.OnSQL = func(sql string, args []interface{}) { traced = append(traced, sql) }
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	entities := []*TestEntity{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	err := repo.BatchInsert(entities, &BatchInsertOption{BatchSize: 2, UseNamedExec: true, Atomic: true})
+	if err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+
+	if execs := fake.Execs(); len(execs) != 0 {
+		t.Errorf("Expected no statements to be executed, got %v", execs)
+	}
+	if n := logs.FilterMessage("Dry run, statement not executed").Len(); n != 2 {
+		t.Errorf("Expected 2 logged batches, got %d", n)
+	}
+	expected := "INSERT INTO test_table (id,name,status) VALUES (:id,:name,:status)"
+	if len(traced) != 2 || traced[0] != expected {
+		t.Errorf("Expected OnSQL to see both batches as %q, got %v", expected, traced)
+	}
+}
+
+func TestDBLoggerArgLogging(t *testing.T) {
+	sqlxDB, _ := newFakeSQLX(t, t.Name())
+	observed, logs := observer.New(zap.DebugLevel)
+	db := NewDBLogger(sqlxDB, zap.New(observed), "")
+	ctx := context.Background()
+	query := "UPDATE `users` SET `email` = ? WHERE `id` = ?"
+
+	if !db.LogArgs {
+		t.Fatal("Expected NewDBLogger to log args by default")
+	}
+	db.RedactArgs = func(args []interface{}) []interface{} {
+		masked := make([]interface{}, len(args))
+		for i := range args {
+			masked[i] = "***"
+		}
+		return masked
+	}
+	if _, err := db.ExecContext(ctx, query, "alice@example.com", 1); err != nil {
+		t.Fatalf("ExecContext failed: %v", err)
+	}
+	entries := logs.TakeAll()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	if args := entries[0].ContextMap()["args"]; !reflect.DeepEqual(args, []interface{}{"***", "***"}) {
+		t.Errorf("Expected redacted args, got %v", args)
+	}
+
+	db.LogArgs = false
+	if _, err := db.ExecContext(ctx, query, "alice@example.com", 1); err != nil {
+		t.Fatalf("ExecContext failed: %v", err)
+	}
+	entries = logs.TakeAll()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	if _, ok := entries[0].ContextMap()["args"]; ok {
+		t.Error("Expected args to be omitted when LogArgs is false")
+	}
+}
@@ -20,6 +20,7 @@ type IEnumerable[T any] interface {
 	Average(selector func(T) float64) float64
 	GroupBy(keySelector func(T) interface{}) map[interface{}][]T
 	Distinct(comparer func(T, T) bool) IEnumerable[T]
+	Reverse() IEnumerable[T]
 }
 
 // Enumerable 实现
@@ -205,3 +206,39 @@ func (e *Enumerable[T]) Distinct(comparer func(T, T) bool) IEnumerable[T] {
 	}
 	return NewEnumerable(result)
 }
+
+// Reverse 倒序，返回新的集合，不修改原数据
+func (e *Enumerable[T]) Reverse() IEnumerable[T] {
+	result := make([]T, len(e.data))
+	for i, item := range e.data {
+		result[len(e.data)-1-i] = item
+	}
+	return NewEnumerable(result)
+}
+
+// Chunk 按固定大小分块，最后一块可能不足 size；size <= 0 时返回 nil
+func Chunk[T any](e IEnumerable[T], size int) [][]T {
+	if size <= 0 {
+		return nil
+	}
+	data := e.ToList()
+	chunks := make([][]T, 0, (len(data)+size-1)/size)
+	for i := 0; i < len(data); i += size {
+		end := i + size
+		if end > len(data) {
+			end = len(data)
+		}
+		chunks = append(chunks, data[i:end:end])
+	}
+	return chunks
+}
+
+// Aggregate 对集合做左折叠，与 LINQ 的 Aggregate(seed, func) 一致：
+// 以 seed 为初始值依次调用 acc，集合为空时返回 seed
+func Aggregate[T any, A any](e IEnumerable[T], seed A, acc func(A, T) A) A {
+	result := seed
+	for _, item := range e.ToList() {
+		result = acc(result, item)
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnumerableReverse(t *testing.T) {
+	data := []int{1, 2, 3}
+	reversed := NewEnumerable(data).Reverse().ToList()
+
+	if !reflect.DeepEqual(reversed, []int{3, 2, 1}) {
+		t.Errorf("Expected [3 2 1], got %v", reversed)
+	}
+	if !reflect.DeepEqual(data, []int{1, 2, 3}) {
+		t.Errorf("Reverse should not mutate the source, got %v", data)
+	}
+	if got := NewEnumerable[int](nil).Reverse().Count(); got != 0 {
+		t.Errorf("Expected empty result, got %d items", got)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	chunks := Chunk(NewEnumerable([]int{1, 2, 3, 4, 5}), 2)
+	expected := [][]int{{1, 2}, {3, 4}, {5}}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Expected %v, got %v", expected, chunks)
+	}
+
+	if chunks := Chunk(NewEnumerable([]int{1, 2, 3}), 3); len(chunks) != 1 || len(chunks[0]) != 3 {
+		t.Errorf("Expected a single full chunk, got %v", chunks)
+	}
+	if chunks := Chunk(NewEnumerable[int](nil), 2); len(chunks) != 0 {
+		t.Errorf("Expected no chunks for empty input, got %v", chunks)
+	}
+	if chunks := Chunk(NewEnumerable([]int{1, 2}), 0); chunks != nil {
+		t.Errorf("Expected nil for size 0, got %v", chunks)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	joined := Aggregate(NewEnumerable([]string{"a", "b", "c"}), "", func(acc string, s string) string {
+		if acc == "" {
+			return s
+		}
+		return acc + "," + s
+	})
+	if joined != "a,b,c" {
+		t.Errorf("Expected \"a,b,c\", got %q", joined)
+	}
+
+	type score struct {
+		Name  string
+		Value int
+	}
+	best := Aggregate(NewEnumerable([]score{{"a", 3}, {"b", 7}, {"c", 5}}), score{Value: -1}, func(max score, s score) score {
+		if s.Value > max.Value {
+			return s
+		}
+		return max
+	})
+	if best.Name != "b" || best.Value != 7 {
+		t.Errorf("Expected b with 7, got %+v", best)
+	}
+
+	if got := Aggregate(NewEnumerable[int](nil), 42, func(acc, n int) int { return acc + n }); got != 42 {
+		t.Errorf("Expected seed for empty input, got %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// QueryError 执行失败时的错误，带上操作名和表名，便于从日志定位问题
+// 原始错误可以通过 errors.Is/errors.As 取得，如 errors.Is(err, sql.ErrNoRows)
+type QueryError struct {
+	Op    string // 方法名，如 ToList、Create
+	Table string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("goqu-linq: %s on %s: %v", e.Op, e.Table, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// wrapQueryError 在 defer 中包装方法返回的错误，已经包装过的错误（如内部调用 Count 的分页方法）保持不变
+func wrapQueryError(op, table string, errp *error) {
+	if *errp == nil {
+		return
+	}
+	var queryErr *QueryError
+	if errors.As(*errp, &queryErr) {
+		return
+	}
+	*errp = &QueryError{Op: op, Table: table, Err: *errp}
+}
@@ -0,0 +1,305 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// fakeDriver 是一个仅用于测试的 database/sql 驱动，记录执行过的 SQL 并返回预设结果
+type fakeDriver struct{}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+// fakeRows 预设的查询结果
+type fakeRows struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+}
+
+// fakeDB 保存一个测试连接的状态
+type fakeDB struct {
+	mu           sync.Mutex
+	execs        []string
+	queries      []string
+	args         [][]interface{}
+	rowsAffected []int64
+	lastInsertID int64
+	results      []fakeRows
+	execErrs     []error
+	pingErr      error
+	queryDelay   time.Duration
+	txEnds       []string // 事务的结束方式，COMMIT 或 ROLLBACK
+	onExec       func(query string)
+}
+
+// newFakeDBLogger 创建一个基于 fakeDriver 的 DBLogger
+func newFakeDBLogger(t *testing.T) (*DBLogger, *fakeDB) {
+	t.Helper()
+	db, fake := newFakeSQLX(t, t.Name())
+	return NewDBLogger(db, zap.NewNop(), ""), fake
+}
+
+// newFakeSQLX 创建一个基于 fakeDriver 的 sqlx.DB，name 在同一测试内需唯一
+func newFakeSQLX(t *testing.T, name string) (*sqlx.DB, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{}
+	fakeDBsMu.Lock()
+	fakeDBs[name] = fake
+	fakeDBsMu.Unlock()
+
+	db, err := sql.Open("fakedb", name)
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBsMu.Lock()
+		delete(fakeDBs, name)
+		fakeDBsMu.Unlock()
+	})
+	return sqlx.NewDb(db, "mysql"), fake
+}
+
+// setOnExec 设置每次 Exec 记录语句后调用的钩子，hook 中不能再调用 fakeDB 的方法
+func (f *fakeDB) setOnExec(hook func(query string)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onExec = hook
+}
+
+// queueRowsAffected 依次设置后续 Exec 返回的影响行数
+func (f *fakeDB) queueRowsAffected(n ...int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rowsAffected = append(f.rowsAffected, n...)
+}
+
+// queueResult 设置下一次查询返回的结果集
+func (f *fakeDB) queueResult(columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, fakeRows{columns: columns, rows: rows})
+}
+
+// queueTypedResult 设置下一次查询返回的结果集，并指定各列的数据库类型
+func (f *fakeDB) queueTypedResult(columns, types []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, fakeRows{columns: columns, types: types, rows: rows})
+}
+
+// queueExecErr 设置下一次 Exec 返回的错误
+func (f *fakeDB) queueExecErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.execErrs = append(f.execErrs, err)
+}
+
+// setPingErr 设置 Ping 返回的错误
+func (f *fakeDB) setPingErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pingErr = err
+}
+
+// setQueryDelay 设置查询的延迟，用于模拟慢查询，ctx 先结束时返回 ctx 的错误
+func (f *fakeDB) setQueryDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queryDelay = d
+}
+
+// Execs 返回所有执行过的写语句
+func (f *fakeDB) Execs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.execs...)
+}
+
+// TxEnds 返回所有事务的结束方式
+func (f *fakeDB) TxEnds() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.txEnds...)
+}
+
+// Queries 返回所有执行过的查询语句
+func (f *fakeDB) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queries...)
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	fake, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("fake db %q not registered", name)
+	}
+	return &fakeConn{db: fake}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{db: c.db}, nil }
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.db.pingErr
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.execs = append(f.execs, query)
+	f.args = append(f.args, namedValues(args))
+	if f.onExec != nil {
+		f.onExec(query)
+	}
+	if len(f.execErrs) > 0 {
+		err := f.execErrs[0]
+		f.execErrs = f.execErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	var affected int64
+	if len(f.rowsAffected) > 0 {
+		affected = f.rowsAffected[0]
+		f.rowsAffected = f.rowsAffected[1:]
+	}
+	return fakeResult{lastInsertID: f.lastInsertID, rowsAffected: affected}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	f := c.db
+	f.mu.Lock()
+	delay := f.queryDelay
+	f.mu.Unlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, namedValues(args))
+	if len(f.results) == 0 {
+		return &fakeDriverRows{}, nil
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+	return &fakeDriverRows{columns: result.columns, types: result.types, rows: result.rows}, nil
+}
+
+func namedValues(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, toNamed(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, toNamed(args))
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx fakeTx) Commit() error   { return tx.end("COMMIT") }
+func (tx fakeTx) Rollback() error { return tx.end("ROLLBACK") }
+
+func (tx fakeTx) end(how string) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.txEnds = append(tx.db.txEnds, how)
+	return nil
+}
+
+type fakeResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeDriverRows struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *fakeDriverRows) Columns() []string { return r.columns }
+
+func (r *fakeDriverRows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(r.types) {
+		return r.types[index]
+	}
+	return ""
+}
+func (r *fakeDriverRows) Close() error { return nil }
+
+func (r *fakeDriverRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -3,7 +3,12 @@
 package core
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 )
 
 // AggregateInfo 定义聚合操作的信息
@@ -11,6 +16,57 @@ type AggregateInfo struct {
 	Field    string
 	Function string
 	Alias    string
+	Distinct bool // 是否对字段去重，如 COUNT(DISTINCT field)
+}
+
+// validate 检查去重聚合的字段，COUNT(DISTINCT *) 不是合法的 SQL
+func (a AggregateInfo) validate() error {
+	if a.Distinct && a.Field == "*" {
+		return fmt.Errorf("%s(DISTINCT *) is not valid, distinct aggregates need a column", strings.ToUpper(a.Function))
+	}
+	return nil
+}
+
+// expression 将聚合信息转换为 goqu 表达式，未知的聚合函数返回 nil
+func (a AggregateInfo) expression() interface{} {
+	var field interface{} = a.Field
+	if a.Distinct {
+		field = goqu.L("DISTINCT ?", goqu.I(a.Field))
+	}
+
+	var fn exp.SQLFunctionExpression
+	switch strings.ToUpper(a.Function) {
+	case "SUM":
+		fn = goqu.SUM(field)
+	case "AVG":
+		fn = goqu.AVG(field)
+	case "COUNT":
+		fn = goqu.COUNT(field)
+	case "MAX":
+		fn = goqu.MAX(field)
+	case "MIN":
+		fn = goqu.MIN(field)
+	default:
+		return nil
+	}
+	if a.Alias != "" {
+		return fn.As(a.Alias)
+	}
+	return fn
+}
+
+// resultKey 返回聚合结果的键名，未设置别名时使用 "函数_字段"，如 sum_amount、count_distinct_user_id
+func (a AggregateInfo) resultKey() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	if a.Field == "*" {
+		return strings.ToLower(a.Function)
+	}
+	if a.Distinct {
+		return strings.ToLower(a.Function) + "_distinct_" + a.Field
+	}
+	return strings.ToLower(a.Function) + "_" + a.Field
 }
 
 // GroupAggregateBuilder 用于构建分组聚合查询
@@ -57,6 +113,16 @@ func (b *GroupAggregateBuilder[T]) Count() *GroupAggregateBuilder[T] {
 	return b
 }
 
+// CountDistinct 添加去重计数聚合，生成 COUNT(DISTINCT field)
+func (b *GroupAggregateBuilder[T]) CountDistinct(field string) *GroupAggregateBuilder[T] {
+	b.aggregations = append(b.aggregations, AggregateInfo{
+		Field:    field,
+		Function: "COUNT",
+		Distinct: true,
+	})
+	return b
+}
+
 // Max 添加最大值聚合
 func (b *GroupAggregateBuilder[T]) Max(field string) *GroupAggregateBuilder[T] {
 	b.aggregations = append(b.aggregations, AggregateInfo{
@@ -88,15 +154,34 @@ func (b *GroupAggregateBuilder[T]) GetAggregations() []AggregateInfo {
 	return b.aggregations
 }
 
-func (g *GroupingQuery[T]) Select(selector func(key interface{}, elements []T) interface{}) IQueryable[T] {
-	selects := []interface{}{
-		g.keySelector, // 分组键
+// Select 查询实体后在内存中按 keySelector 分组，对每组调用 selector，结果按分组首次出现的顺序返回
+// keySelector 是 Go 函数，无法转换为 SQL，数据量大时应先用 Where 缩小范围，或用 Aggregate/CountByColumns 在数据库中聚合
+func (g *GroupingQuery[T]) Select(selector func(key interface{}, elements []T) interface{}) (_ []interface{}, err error) {
+	defer wrapQueryError("Select", g.parent.table, &err)
+	items, err := g.parent.ToList()
+	if err != nil {
+		return nil, err
 	}
-	g.parent.query = g.parent.query.Select(selects...).GroupBy(g.keySelector)
-	return g.parent
+
+	var keys []interface{}
+	groups := make(map[interface{}][]T)
+	for _, item := range items {
+		key := g.keySelector(*item)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], *item)
+	}
+
+	results := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, selector(key, groups[key]))
+	}
+	return results, nil
 }
 
-func (g *GroupingQuery[T]) Count() (map[interface{}]int64, error) {
+func (g *GroupingQuery[T]) Count() (_ map[interface{}]int64, err error) {
+	defer wrapQueryError("Count", g.parent.table, &err)
 	// 构造 SELECT 子句，包含分组键和计数
 	selects := []interface{}{
 		g.keySelector,               // 分组键
@@ -104,12 +189,17 @@ func (g *GroupingQuery[T]) Count() (map[interface{}]int64, error) {
 	}
 
 	g.parent.query = g.parent.query.Select(selects...).GroupBy(g.keySelector)
+	sql, args, err := g.parent.query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
 
 	// 执行查询
-	rows, err := g.parent.db.DB.Queryx(g.parent.query.ToSQL())
+	rows, cancel, err := g.parent.queryx(g.parent.baseContext(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 	defer rows.Close()
 
 	// 处理结果
@@ -126,7 +216,8 @@ func (g *GroupingQuery[T]) Count() (map[interface{}]int64, error) {
 	return results, nil
 }
 
-func (g *GroupingQuery[T]) Sum(field string) (map[interface{}]float64, error) {
+func (g *GroupingQuery[T]) Sum(field string) (_ map[interface{}]float64, err error) {
+	defer wrapQueryError("Sum", g.parent.table, &err)
 	selects := []interface{}{
 		g.keySelector,
 		goqu.SUM(field).As("sum"),
@@ -138,10 +229,11 @@ func (g *GroupingQuery[T]) Sum(field string) (map[interface{}]float64, error) {
 		return nil, err
 	}
 
-	rows, err := g.parent.db.DB.Queryx(sql, args...)
+	rows, cancel, err := g.parent.queryx(g.parent.baseContext(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 	defer rows.Close()
 
 	results := make(map[interface{}]float64)
@@ -157,7 +249,8 @@ func (g *GroupingQuery[T]) Sum(field string) (map[interface{}]float64, error) {
 	return results, nil
 }
 
-func (g *GroupingQuery[T]) Average(field string) (map[interface{}]float64, error) {
+func (g *GroupingQuery[T]) Average(field string) (_ map[interface{}]float64, err error) {
+	defer wrapQueryError("Average", g.parent.table, &err)
 	selects := []interface{}{
 		g.keySelector,
 		goqu.AVG(field).As("avg"),
@@ -169,10 +262,11 @@ func (g *GroupingQuery[T]) Average(field string) (map[interface{}]float64, error
 		return nil, err
 	}
 
-	rows, err := g.parent.db.DB.Queryx(sql, args...)
+	rows, cancel, err := g.parent.queryx(g.parent.baseContext(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 	defer rows.Close()
 
 	results := make(map[interface{}]float64)
@@ -188,54 +282,101 @@ func (g *GroupingQuery[T]) Average(field string) (map[interface{}]float64, error
 	return results, nil
 }
 
+// CountDistinct 按分组统计 field 的去重数量，生成 COUNT(DISTINCT field)，如每个类目下的商品数
+// keySelector 是 Go 函数，无法转换为 SQL，分组列取自 GroupByColumns，且只能有一列，例如
+// q.GroupByColumns("category").GroupBy(byCategory).CountDistinct("product_id")
+func (g *GroupingQuery[T]) CountDistinct(field string) (_ map[interface{}]int64, err error) {
+	defer wrapQueryError("CountDistinct", g.parent.table, &err)
+	groupBy := g.parent.query.GetClauses().GroupBy()
+	if groupBy == nil || len(groupBy.Columns()) != 1 {
+		return nil, errors.New("CountDistinct requires exactly one GroupByColumns column")
+	}
+	key := groupBy.Columns()[0]
+	info := AggregateInfo{Field: field, Function: "COUNT", Alias: "count", Distinct: true}
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+	count := info.expression()
+
+	g.parent.query = g.parent.query.Select(key, count)
+	sql, args, err := g.parent.query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, cancel, err := g.parent.queryx(g.parent.baseContext(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer rows.Close()
+
+	results := make(map[interface{}]int64)
+	for rows.Next() {
+		var key interface{}
+		var count int64
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, err
+		}
+		// 驱动以 []byte 返回字符串列，[]byte 不能作为 map 的键
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		results[key] = count
+	}
+	return results, rows.Err()
+}
+
 // 在 group.go 中添加 Having 方法的实现
 func (g *GroupingQuery[T]) Having(condition goqu.Ex) IGroupingQuery[T] {
 	g.parent.query = g.parent.query.Having(condition)
 	return g
 }
 
+// HavingAggregate 追加 HAVING fn(field) op value 条件，如 HavingAggregate("SUM", "amount", ">=", 100)
+// fn 支持 COUNT/SUM/AVG/MIN/MAX，field 为 "*" 时生成 COUNT(*)；fn 和 op 不在允许列表中时错误由执行方法返回
+func (g *GroupingQuery[T]) HavingAggregate(fn, field, op string, value interface{}) IGroupingQuery[T] {
+	expr := AggregateInfo{Field: field, Function: fn}.expression()
+	if expr == nil {
+		g.parent.query = g.parent.query.SetError(fmt.Errorf("HavingAggregate: unsupported aggregate function %q", fn))
+		return g
+	}
+	if !comparisonOperators[op] {
+		g.parent.query = g.parent.query.SetError(fmt.Errorf("HavingAggregate: unsupported operator %q", op))
+		return g
+	}
+	g.parent.query = g.parent.query.Having(goqu.L("? "+op+" ?", expr, value))
+	return g
+}
+
+// Aggregate 按 GroupByColumns 的列分组计算 builder 中的聚合，返回的查询选择分组列和聚合列（未设置别名时同 Aggregates 的键名），
+// 用 ToRawMapSlice 等方法读取每组的结果；keySelector 是 Go 函数，无法转换为 SQL，例如
+// q.GroupByColumns("status").GroupBy(byStatus).Aggregate(builder).ToRawMapSlice()
 func (g *GroupingQuery[T]) Aggregate(builder *GroupAggregateBuilder[T]) IQueryable[T] {
-	selects := make([]interface{}, 0)
+	groupBy := g.parent.query.GetClauses().GroupBy()
+	if groupBy == nil || groupBy.IsEmpty() {
+		g.parent.query = g.parent.query.SetError(errors.New("Aggregate requires GroupByColumns, the key selector cannot be converted to SQL"))
+		return g.parent
+	}
 
 	// 添加分组字段
-	selects = append(selects, g.keySelector)
+	selects := make([]interface{}, 0, len(groupBy.Columns())+len(builder.GetAggregations()))
+	for _, col := range groupBy.Columns() {
+		selects = append(selects, col)
+	}
 
 	// 添加聚合表达式
 	for _, agg := range builder.GetAggregations() {
-		switch agg.Function {
-		case "SUM":
-			if agg.Alias != "" {
-				selects = append(selects, goqu.SUM(agg.Field).As(agg.Alias))
-			} else {
-				selects = append(selects, goqu.SUM(agg.Field))
-			}
-		case "AVG":
-			if agg.Alias != "" {
-				selects = append(selects, goqu.AVG(agg.Field).As(agg.Alias))
-			} else {
-				selects = append(selects, goqu.AVG(agg.Field))
-			}
-		case "COUNT":
-			if agg.Alias != "" {
-				selects = append(selects, goqu.COUNT(agg.Field).As(agg.Alias))
-			} else {
-				selects = append(selects, goqu.COUNT(agg.Field))
-			}
-		case "MAX":
-			if agg.Alias != "" {
-				selects = append(selects, goqu.MAX(agg.Field).As(agg.Alias))
-			} else {
-				selects = append(selects, goqu.MAX(agg.Field))
-			}
-		case "MIN":
-			if agg.Alias != "" {
-				selects = append(selects, goqu.MIN(agg.Field).As(agg.Alias))
-			} else {
-				selects = append(selects, goqu.MIN(agg.Field))
-			}
+		if err := agg.validate(); err != nil {
+			g.parent.query = g.parent.query.SetError(err)
+			return g.parent
+		}
+		agg.Alias = agg.resultKey()
+		if expr := agg.expression(); expr != nil {
+			selects = append(selects, expr)
 		}
 	}
 
-	g.parent.query = g.parent.query.Select(selects...).GroupBy(g.keySelector)
+	g.parent.query = g.parent.query.Select(selects...)
 	return g.parent
 }
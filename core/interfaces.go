@@ -31,8 +31,11 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
 )
 
 // IReadRepository defines read-only operations for database queries.
@@ -75,8 +78,17 @@ type IWriteRepository[T any] interface {
 	UpdateFieldsByCondition(condition goqu.Ex, fields map[string]interface{}) error
 	BatchCreate(entities []*T) error
 	BatchDelete(condition goqu.Ex) error
+	// DeleteReturning 在事务中删除满足条件的记录并返回被删除的行
+	DeleteReturning(condition goqu.Ex) ([]*T, error)
 	BatchInsert(entities []*T, opt *BatchInsertOption) error
 	BatchUpdate(entities []*T, opt *BatchUpdateOption) error
+
+	// 带 context 的写操作，可随请求超时取消
+	CreateContext(ctx context.Context, entity *T) error
+	UpdateContext(ctx context.Context, entity *T) error
+	UpdateFieldsByConditionContext(ctx context.Context, condition goqu.Ex, fields map[string]interface{}) error
+	BatchDeleteContext(ctx context.Context, condition goqu.Ex) error
+	DeleteReturningContext(ctx context.Context, condition goqu.Ex) ([]*T, error)
 }
 
 type IRepository[T any] interface {
@@ -84,13 +96,52 @@ type IRepository[T any] interface {
 	IWriteRepository[T]
 }
 
+// Validatable 实体可选实现的校验接口，Create/BatchCreate/BatchInsert 在执行 SQL 前调用
+type Validatable interface {
+	Validate() error
+}
+
+// PrimaryKeyer 实体可选实现的主键列接口，未实现时主键列为 id
+type PrimaryKeyer interface {
+	PrimaryKey() string
+}
+
+// TableNamer 实体可选实现的表名接口，NewRepository 的 table 为空时使用
+type TableNamer interface {
+	TableName() string
+}
+
 // IQueryable 接口增加 Lambda 风格的分组方法
 type IQueryable[T any] interface {
 	// 现有的链式操作
 	Where(condition goqu.Ex) IQueryable[T]
 	WhereRaw(condition string, args ...interface{}) IQueryable[T]
+	// WhereStruct 根据过滤结构体的非零字段构造条件，支持 query tag 指定操作符
+	WhereStruct(filter interface{}) IQueryable[T]
+	// WhereGroup 嵌套的 AND/OR 条件组
+	WhereGroup(fn func(g ConditionGroup)) IQueryable[T]
+	// MySQL JSON 列条件与投影，path 可省略开头的 "$."
+	WhereJSONContains(column, path string, value interface{}) IQueryable[T]
+	WhereJSONExtractEq(column, path string, value interface{}) IQueryable[T]
+	SelectJSONField(column, path, alias string) IQueryable[T]
+	// SelectCoalesce 追加 COALESCE(column, fallback) AS alias 投影，NULL 时使用 fallback
+	SelectCoalesce(column string, fallback interface{}, alias string) IQueryable[T]
+	// WhereColumns 两列比较，右侧作为标识符而不是值
+	WhereColumns(left, op, right string) IQueryable[T]
+	// 单列比较条件，多次调用以 AND 连接
+	WhereEq(column string, value interface{}) IQueryable[T]
+	WhereNe(column string, value interface{}) IQueryable[T]
+	WhereGt(column string, value interface{}) IQueryable[T]
+	WhereGte(column string, value interface{}) IQueryable[T]
+	WhereLt(column string, value interface{}) IQueryable[T]
+	WhereLte(column string, value interface{}) IQueryable[T]
+	// WhereTupleIn 多列行值 IN 条件，如 (a, b) IN ((1, 2), (3, 4))
+	WhereTupleIn(columns []string, tuples [][]interface{}) IQueryable[T]
 	OrderBy(cols ...string) IQueryable[T]
 	OrderByRaw(order string) IQueryable[T]
+	OrderByRandom() IQueryable[T] // 按方言随机排序，如 MySQL 的 ORDER BY RAND()
+	// Scope 应用可复用的查询片段，如 q.Scope(ActiveScope).Scope(TenantScope(42))
+	Scope(fn func(IQueryable[T]) IQueryable[T]) IQueryable[T]
 	Skip(offset int) IQueryable[T]
 	Take(take int) IQueryable[T]
 	Limit(limit int) IQueryable[T]
@@ -105,11 +156,18 @@ type IQueryable[T any] interface {
 	//新增一个泛型方法 scan
 
 	Select(cols ...interface{}) IQueryable[T]
-	SelectRaw(cols ...string) IQueryable[T] // 原生 SQL 查询
+	SelectRaw(cols ...string) IQueryable[T]      // 原生 SQL 查询
+	SelectFields(fields ...string) IQueryable[T] // 按 db tag 选择字段，未知字段会在执行时报错
+	// SelectQualified 追加 table.col 投影，列名可写成 "id AS user_id"，用于 Join 后区分同名列
+	SelectQualified(table string, cols ...string) IQueryable[T]
 	// 分组操作 - 新增 Lambda 风格
 	GroupBy(keySelector func(T) interface{}) IGroupingQuery[T]
 	// 保留原有的字符串方式，用于简单场景
 	GroupByColumns(cols ...string) IQueryable[T]
+	// OrderByAggregate 按 SELECT 中的聚合别名排序
+	OrderByAggregate(alias string, desc bool) IQueryable[T]
+	// CountByColumns 按多列分组计数，结果按数量降序
+	CountByColumns(cols ...string) ([]GroupCount, error)
 
 	// 连接操作
 	IJoinable[T]
@@ -120,6 +178,7 @@ type IQueryable[T any] interface {
 	//FirstOrDefaultTx(ctx context.Context) (*T, error)
 
 	ToListTx(ctx context.Context) ([]*T, error)
+	ToListDistinctTx(ctx context.Context, keyFn func(*T) interface{}) ([]*T, error)
 
 	CountTx(ctx context.Context) (int64, error)
 
@@ -129,27 +188,82 @@ type IQueryable[T any] interface {
 	MaxTx(ctx context.Context, field string) (interface{}, error)
 	MinTx(ctx context.Context, field string) (interface{}, error)
 	ToPagedListTx(ctx context.Context, page, size int, condition goqu.Ex) (*PageResult[T], error)
+	PaginateTx(ctx context.Context, page, size int) (*Page[T], error)
+	ToCursorPageTx(ctx context.Context, size int) (*PageResultCursor[T], error)
+	EstimatedCountTx(ctx context.Context) (int64, error)
 	ToInt64SliceTx(ctx context.Context) ([]int64, error)
 	ToStringSliceTx(ctx context.Context) ([]string, error)
 	ToFloat64SliceTx(ctx context.Context) ([]float64, error)
 	ToMapSliceTx(ctx context.Context) ([]map[string]interface{}, error)
+	ToRawMapSliceTx(ctx context.Context) ([]map[string]interface{}, error)
 	ToMapTx(ctx context.Context) (map[string]interface{}, error)
 	ToStructTx(ctx context.Context) (*T, error)
 	ToResultTx(ctx context.Context, result interface{}) error
 	ToPagedResultTx(ctx context.Context, page, pageSize int, dest interface{}) (*PagedResult, error)
 	OverTx(ctx context.Context, windowFunc string, partitionBy ...interface{}) IQueryable[T]
+	WhereEqTx(ctx context.Context, column string, value interface{}) IQueryable[T]
+	WhereNeTx(ctx context.Context, column string, value interface{}) IQueryable[T]
+	WhereGtTx(ctx context.Context, column string, value interface{}) IQueryable[T]
+	WhereGteTx(ctx context.Context, column string, value interface{}) IQueryable[T]
+	WhereLtTx(ctx context.Context, column string, value interface{}) IQueryable[T]
+	WhereLteTx(ctx context.Context, column string, value interface{}) IQueryable[T]
 	ToLookupTx(ctx context.Context, keySelector func(T) interface{}) map[interface{}][]*T
 	ToList() ([]*T, error)
+	// ToListDistinct 按 keyFn 去重，保留每个键第一次出现的实体，用于一对多连表后的结果
+	ToListDistinct(keyFn func(*T) interface{}) ([]*T, error)
+	// ToChan 流式返回查询结果，适合管道式并发处理
+	ToChan(ctx context.Context, bufferSize int) (<-chan *T, <-chan error)
 	Count() (int64, error)
 	Any(condition goqu.Ex) (bool, error)
+	// All 范围内所有行都满足 predicate 时返回 true（空集合为 true）
+	All(within goqu.Ex, predicate goqu.Ex) (bool, error)
+	AllTx(ctx context.Context, within goqu.Ex, predicate goqu.Ex) (bool, error)
+	// None 没有满足条件的行时返回 true
+	None(condition goqu.Ex) (bool, error)
+	NoneTx(ctx context.Context, condition goqu.Ex) (bool, error)
 
 	// 聚合方法
 	Sum(field string) (float64, error)
+	// SumPtr/AvgPtr 没有匹配的行时返回 nil，而不是 0
+	SumPtr(field string) (*float64, error)
+	AvgPtr(field string) (*float64, error)
 	Max(field string) (interface{}, error)
 	Min(field string) (interface{}, error)
+	// MaxBy/MinBy 返回 field 最大/最小的整行记录，没有记录时返回 ErrNotFound
+	MaxBy(field string) (*T, error)
+	MinBy(field string) (*T, error)
+	// 类型化的最值，结果为 NULL（如空集合）时 found 为 false
+	MaxInt64(field string) (value int64, found bool, err error)
+	MaxFloat64(field string) (value float64, found bool, err error)
+	MaxString(field string) (value string, found bool, err error)
+	MaxTime(field string) (value time.Time, found bool, err error)
+	MinInt64(field string) (value int64, found bool, err error)
+	MinFloat64(field string) (value float64, found bool, err error)
+	MinString(field string) (value string, found bool, err error)
+	MinTime(field string) (value time.Time, found bool, err error)
+	// 对原始 SQL 表达式聚合，如 SUM(price * quantity)
+	SumExpr(expr string, args ...interface{}) (float64, error)
+	AvgExpr(expr string, args ...interface{}) (float64, error)
+	SumExprTx(ctx context.Context, expr string, args ...interface{}) (float64, error)
+	AvgExprTx(ctx context.Context, expr string, args ...interface{}) (float64, error)
+	// Aggregates 一次查询计算多个聚合值，结果以别名为键
+	Aggregates(specs []AggregateInfo) (map[string]float64, error)
 
 	// 分页相关
 	ToPagedList(page, size int, condition goqu.Ex) (*PageResult[T], error)
+	// Paginate 一次计数加一次数据查询，返回当前页数据、总数、总页数和前后页信息
+	Paginate(page, size int) (*Page[T], error)
+	// WithTimeout 设置单条语句的超时，与调用方 ctx 的截止时间取较早者，不作用于 Rows/RowsTx
+	WithTimeout(d time.Duration) IQueryable[T]
+	// WithContext 保存 ctx，供 ToList、Count 等不带 ctx 的执行方法使用
+	WithContext(ctx context.Context) IQueryable[T]
+	// AfterCursor 游标分页，令牌由 EncodeCursor 生成，为空或无效时从头开始
+	AfterCursor(column string, token string) IQueryable[T]
+	ToCursorPage(size int) (*PageResultCursor[T], error)
+	// AfterComposite 按多列组合键做键集分页，lastValues 为上一页最后一行的值，为空时取第一页
+	AfterComposite(cols []string, lastValues []interface{}, pageSize int) IQueryable[T]
+	// EstimatedCount 无条件时读取 information_schema 的估算行数，否则退回 Count
+	EstimatedCount() (int64, error)
 	ToPagedListWithTotal(page, size int, condition goqu.Ex) ([]*T, int64, error)
 	ToPagedResult(page, pageSize int, dest interface{}) (*PagedResult, error)
 
@@ -158,22 +272,54 @@ type IQueryable[T any] interface {
 	ToStringSlice() ([]string, error)
 	ToFloat64Slice() ([]float64, error)
 	ToMapSlice() ([]map[string]interface{}, error)
+	// ToRawMapSlice 按实际结果列返回 map 切片，适用于 SelectRaw 等任意投影
+	ToRawMapSlice() ([]map[string]interface{}, error)
+	// Rows 返回原始结果集，调用方负责关闭
+	Rows() (*sqlx.Rows, error)
+	RowsTx(ctx context.Context) (*sqlx.Rows, error)
 	ToMap() (map[string]interface{}, error)
 	ToStruct() (*T, error)
 	ToResult(result interface{}) error
 
 	// 特殊查询
 	Over(windowFunc string, partitionBy ...interface{}) IQueryable[T]
+	// 排名窗口函数，追加到查询列，orderBy 可带方向，如 "created_at DESC"
+	RowNumber(partitionBy []string, orderBy string, alias string) IQueryable[T]
+	Rank(partitionBy []string, orderBy string, alias string) IQueryable[T]
+	DenseRank(partitionBy []string, orderBy string, alias string) IQueryable[T]
+	// WithTotalCount 追加 COUNT(*) OVER () 总行数列
+	WithTotalCount(alias string) IQueryable[T]
+	// DistinctBy 每个分组只保留排序后的第一行，如每个用户最新的订单
+	DistinctBy(partitionCols []string, orderBy string) IQueryable[T]
 	ToLookup(keySelector func(T) interface{}) map[interface{}][]*T
 	ToSQL() (sql string, params []interface{}, err error)
+	// ToInterpolatedSQL 返回参数已内联的 SQL，仅用于调试和分析
+	ToInterpolatedSQL() (string, error)
+
+	// Dataset 返回底层的 goqu 查询构造器，FromDataset 将自定义构造器包装回类型化查询
+	Dataset() *goqu.SelectDataset
+	FromDataset(ds *goqu.SelectDataset) IQueryable[T]
+	// HasWhere/HasOrder/HasLimit 返回构造方法是否已设置条件、排序和行数限制，用于按需追加默认值
+	HasWhere() bool
+	HasOrder() bool
+	HasLimit() bool
+	// Unordered 允许分页方法在没有 ORDER BY 时不追加默认的主键排序
+	Unordered() IQueryable[T]
 }
 
 // IJoinable 接口定义简化的连表操作
+// map 形式的 on 表示等值连接，键为源列，值为目标列，如 {"orders.user_id": "users.id"}
 type IJoinable[T any] interface {
 	Join(table string, on map[string]string) IQueryable[T]
 	LeftJoin(table string, on map[string]string) IQueryable[T]
 	RightJoin(table string, on map[string]string) IQueryable[T]
 	InnerJoin(table string, on map[string]string) IQueryable[T]
+	FullJoin(table string, on map[string]string) IQueryable[T]
+	// JoinOn 使用任意连接条件进行内连接
+	JoinOn(table string, on goqu.Expression) IQueryable[T]
+	// 连接表为表达式，用于带别名的表，如自连接时的 goqu.T("employees").As("m")
+	JoinTable(table exp.Expression, on goqu.Expression) IQueryable[T]
+	LeftJoinTable(table exp.Expression, on goqu.Expression) IQueryable[T]
 }
 
 type PagedResult struct {
@@ -207,10 +353,15 @@ type IGroupingQuery[T any] interface {
 	Count() (map[interface{}]int64, error)
 	Sum(field string) (map[interface{}]float64, error)
 	Average(field string) (map[interface{}]float64, error)
+	// CountDistinct 每组 COUNT(DISTINCT field)，分组列取自 GroupByColumns
+	CountDistinct(field string) (map[interface{}]int64, error)
 
 	// 高级操作
-	Select(selector func(key interface{}, elements []T) interface{}) IQueryable[T]
+	// Select 在内存中分组并投影每组的结果
+	Select(selector func(key interface{}, elements []T) interface{}) ([]interface{}, error)
 	Having(condition goqu.Ex) IGroupingQuery[T]
+	// HavingAggregate 聚合比较条件，如 HAVING SUM(amount) >= ?
+	HavingAggregate(fn, field, op string, value interface{}) IGroupingQuery[T]
 
 	// 链式聚合操作
 	Aggregate(builder *GroupAggregateBuilder[T]) IQueryable[T]
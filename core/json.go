@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// jsonPath 补全 JSON 路径，"a.b" 和 "$.a.b" 等价，空路径表示整个文档
+func jsonPath(path string) string {
+	if path == "" || strings.HasPrefix(path, "$") {
+		return path
+	}
+	if strings.HasPrefix(path, "[") {
+		return "$" + path
+	}
+	return "$." + path
+}
+
+// WhereJSONContains JSON_CONTAINS(column, value, path) 条件，value 会编码为 JSON 文本
+// path 为空时在整个文档中查找，例如 WhereJSONContains("attrs", "tags", "vip")
+func (q *Queryable[T]) WhereJSONContains(column, path string, value interface{}) IQueryable[T] {
+	q = q.clone()
+	candidate, err := json.Marshal(value)
+	if err != nil {
+		q.query = q.query.SetError(fmt.Errorf("WhereJSONContains: encode value: %w", err))
+		return q
+	}
+	if path == "" {
+		q.query = q.query.Where(goqu.L("JSON_CONTAINS(?, ?)", goqu.I(column), string(candidate)))
+		q.hasWhere = true
+		return q
+	}
+	q.query = q.query.Where(goqu.L("JSON_CONTAINS(?, ?, ?)", goqu.I(column), string(candidate), jsonPath(path)))
+	q.hasWhere = true
+	return q
+}
+
+// WhereJSONExtractEq JSON_EXTRACT(column, path) = value 条件，例如 WhereJSONExtractEq("attrs", "level", 3)
+func (q *Queryable[T]) WhereJSONExtractEq(column, path string, value interface{}) IQueryable[T] {
+	q = q.clone()
+	q.query = q.query.Where(goqu.L("JSON_EXTRACT(?, ?) = ?", goqu.I(column), jsonPath(path), normalizeValue(value)))
+	q.hasWhere = true
+	return q
+}
+
+// SelectJSONField 追加 JSON_UNQUOTE(JSON_EXTRACT(column, path)) AS alias 投影，字符串值不带引号
+// 未指定 Select 时保留实体字段
+func (q *Queryable[T]) SelectJSONField(column, path, alias string) IQueryable[T] {
+	q = q.clone()
+	q.ensureSelectFields()
+	field := goqu.L("JSON_UNQUOTE(JSON_EXTRACT(?, ?))", goqu.I(column), jsonPath(path))
+	q.query = q.query.SelectAppend(field.As(alias))
+	return q
+}
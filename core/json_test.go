@@ -0,0 +1,52 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryableJSONHelpers(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, args, err := repo.Query().
+		WhereJSONContains("attrs", "tags", "vip").
+		WhereJSONExtractEq("attrs", "$.level", 3).
+		Dataset().Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `test_table` WHERE (JSON_CONTAINS(`attrs`, ?, ?) AND JSON_EXTRACT(`attrs`, ?) = ?)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+	expectedArgs := []interface{}{`"vip"`, "$.tags", "$.level", int64(3)}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %#v, got %#v", expectedArgs, args)
+	}
+
+	sql, args, err = repo.Query().
+		WhereJSONContains("roles", "", []int{1, 2}).
+		Dataset().Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if expected := "SELECT * FROM `test_table` WHERE JSON_CONTAINS(`roles`, ?)"; sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"[1,2]"}) {
+		t.Errorf("Unexpected args: %#v", args)
+	}
+
+	sql, _, err = repo.Query().Select("id").SelectJSONField("attrs", "profile.city", "city").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected = "SELECT `id`, JSON_UNQUOTE(JSON_EXTRACT(`attrs`, '$.profile.city')) AS `city` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	if _, _, err := repo.Query().WhereJSONContains("attrs", "tags", make(chan int)).ToSQL(); err == nil {
+		t.Error("Expected error for a value that cannot be encoded as JSON")
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// NameMapper 将结构体字段名映射为列名，用于没有 db tag 的字段
+type NameMapper func(fieldName string) string
+
+// DefaultNameMapper 默认的字段名映射，将 CreatedAt 映射为 created_at
+// 仓储创建时读取该值，生成列名和扫描查询结果使用相同的映射
+var DefaultNameMapper NameMapper = ToSnakeCase
+
+// scanMapper 返回 sqlx 扫描查询结果时使用的字段映射，与生成列名的 mapper 一致，
+// 替代 sqlx 默认的小写映射，否则没有 db tag 的字段（如 UserName）无法匹配 user_name 列
+func scanMapper(mapper NameMapper) *reflectx.Mapper {
+	if mapper == nil {
+		mapper = DefaultNameMapper
+	}
+	return reflectx.NewMapperFunc("db", mapper)
+}
+
+// ToSnakeCase 将驼峰命名转换为下划线命名，如 UserID -> user_id、HTTPStatus -> http_status
+func ToSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// columnName 返回字段对应的列名，返回空字符串表示该字段不映射到列
+// 有 db tag 时使用 tag（逗号前的部分），"-" 表示忽略；没有 tag 时使用 mapper 映射字段名
+func columnName(field reflect.StructField, mapper NameMapper) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag := strings.Split(field.Tag.Get("db"), ",")[0]
+	if tag == "-" {
+		return ""
+	}
+	if tag != "" {
+		return tag
+	}
+	if mapper == nil {
+		mapper = DefaultNameMapper
+	}
+	return mapper(field.Name)
+}
+
+// driverValue 返回字段写入数据库时使用的值
+// 只有指针接收者实现 driver.Valuer 的字段返回其地址，使 database/sql 能调用 Value()，其余字段原样返回
+func driverValue(field reflect.Value) interface{} {
+	value := field.Interface()
+	if _, ok := value.(driver.Valuer); ok || !field.CanAddr() {
+		return value
+	}
+	if valuer, ok := field.Addr().Interface().(driver.Valuer); ok {
+		return valuer
+	}
+	return value
+}
+
+// entityColumn 映射到列的字段，index 是从实体到该字段的路径，嵌入结构体中的字段路径长度大于 1
+type entityColumn struct {
+	name  string
+	field reflect.StructField
+	index []int
+}
+
+// entityColumns 返回结构体类型中映射到列的字段，按声明顺序排列
+// 没有 db tag 的匿名嵌入结构体（值或指针）会展开，如嵌入的 BaseModel；与 Go 的字段提升一致，同名时外层字段优先
+func entityColumns(t reflect.Type, mapper NameMapper) []entityColumn {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns []entityColumn
+	position := make(map[string]int)
+	var walk func(t reflect.Type, prefix []int)
+	walk = func(t reflect.Type, prefix []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			index := append(append([]int(nil), prefix...), i)
+			if embedded, ok := embeddedStruct(field); ok {
+				walk(embedded, index)
+				continue
+			}
+			name := columnName(field, mapper)
+			if name == "" {
+				continue
+			}
+			if pos, ok := position[name]; ok {
+				if len(columns[pos].index) > len(index) {
+					columns[pos] = entityColumn{name: name, field: field, index: index}
+				}
+				continue
+			}
+			position[name] = len(columns)
+			columns = append(columns, entityColumn{name: name, field: field, index: index})
+		}
+	}
+	walk(t, nil)
+	return columns
+}
+
+// embeddedStruct 判断字段是否为需要展开的嵌入结构体，返回结构体类型
+// 带 db tag 或实现 driver.Valuer 的嵌入字段（如 time.Time）作为普通列处理
+func embeddedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous || field.Tag.Get("db") != "" {
+		return nil, false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(valuerType) {
+		return nil, false
+	}
+	return t, true
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// fieldByIndex 按路径读取字段，路径经过 nil 的嵌入指针时返回无效的 reflect.Value
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return field
+}
+
+// columnValue 返回字段写入数据库时使用的值，嵌入指针为 nil 时返回 nil
+func columnValue(v reflect.Value, index []int) interface{} {
+	field := fieldByIndex(v, index)
+	if !field.IsValid() {
+		return nil
+	}
+	return driverValue(field)
+}
+
+// settableField 按路径取可写的字段，路径经过 nil 的嵌入指针时先分配，无法分配时返回无效的 reflect.Value
+func settableField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	if !v.CanSet() {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// TableColumns 返回实体类型映射的列名，按字段声明顺序排列，嵌入结构体的字段会展开
+// 没有 db tag 的字段使用 DefaultNameMapper 映射
+func TableColumns[T any]() []string {
+	columns := entityColumns(reflect.TypeOf((*T)(nil)).Elem(), DefaultNameMapper)
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// TableName 返回仓储使用的表名，repo 为 nil 时返回实体 TableName() 方法的结果
+func TableName[T any](repo *Repository[T]) string {
+	if repo == nil {
+		return entityTableName[T]()
+	}
+	return repo.table
+}
+
+// entityTableName 返回实体实现 TableNamer 时的表名，值接收者和指针接收者都支持，未实现时返回空字符串
+func entityTableName[T any]() string {
+	if namer, ok := any(new(T)).(TableNamer); ok {
+		return namer.TableName()
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testUntaggedEntity struct {
+	ID        int64
+	UserName  string
+	CreatedAt int64
+}
+
+type testMixedEntity struct {
+	ID       int64  `db:"id"`
+	UserName string `db:"login"`
+	Email    string
+	Ignored  string `db:"-"`
+	internal string
+}
+
+func TestToSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"ID":         "id",
+		"Name":       "name",
+		"CreatedAt":  "created_at",
+		"UserID":     "user_id",
+		"HTTPStatus": "http_status",
+		"Address2":   "address2",
+		"already":    "already",
+	}
+	for input, expected := range tests {
+		if got := ToSnakeCase(input); got != expected {
+			t.Errorf("ToSnakeCase(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestRepositoryFieldMapping(t *testing.T) {
+	tagged := NewRepository[TestEntity](nil, "t", MySQL)
+	if got := tagged.getFields(&TestEntity{}); !reflect.DeepEqual(got, []string{"id", "name", "status"}) {
+		t.Errorf("Unexpected tagged fields: %v", got)
+	}
+
+	untagged := NewRepository[testUntaggedEntity](nil, "t", MySQL)
+	if got := untagged.getFields(&testUntaggedEntity{}); !reflect.DeepEqual(got, []string{"id", "user_name", "created_at"}) {
+		t.Errorf("Unexpected untagged fields: %v", got)
+	}
+
+	mixed := NewRepository[testMixedEntity](nil, "t", MySQL)
+	if got := mixed.getFields(&testMixedEntity{}); !reflect.DeepEqual(got, []string{"id", "login", "email"}) {
+		t.Errorf("Unexpected mixed fields: %v", got)
+	}
+	values := mixed.getValues(&testMixedEntity{ID: 1, UserName: "bob", Email: "b@x", Ignored: "x"})
+	if !reflect.DeepEqual(values, []interface{}{int64(1), "bob", "b@x"}) {
+		t.Errorf("Unexpected mixed values: %v", values)
+	}
+
+	upper := mixed.WithNameMapper(strings.ToUpper)
+	if got := upper.getFields(&testMixedEntity{}); !reflect.DeepEqual(got, []string{"id", "login", "EMAIL"}) {
+		t.Errorf("Expected custom mapper to apply only to untagged fields, got %v", got)
+	}
+}
+
+func TestQueryableUntaggedSelect(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[testUntaggedEntity](db, "users", MySQL)
+
+	fake.queueResult([]string{"id", "user_name", "created_at"}, []driver.Value{int64(1), "alice", int64(100)})
+	items, err := repo.Query().ToList()
+	if err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected := "SELECT `id`, `user_name`, `created_at` FROM `users`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+	if len(items) != 1 || items[0].UserName != "alice" || items[0].CreatedAt != 100 {
+		t.Errorf("Expected untagged fields to be scanned, got %+v", items)
+	}
+
+	// 自定义映射同时用于生成列名和扫描结果，在事务中读取时也一样
+	upper := repo.WithNameMapper(strings.ToUpper)
+	fake.queueResult([]string{"ID", "USERNAME", "CREATEDAT"}, []driver.Value{int64(2), "bob", int64(200)})
+	err = NewUnitOfWork(db).RunInTransaction(func(tx IUnitOfWork) error {
+		entity, err := upper.WithUnitOfWork(tx).Query().FirstOrDefault()
+		if err == nil && (entity == nil || entity.UserName != "bob" || entity.CreatedAt != 200) {
+			err = fmt.Errorf("unexpected entity %+v", entity)
+		}
+		return err
+	})
+	if err != nil {
+		t.Errorf("Expected the custom mapper to scan untagged fields: %v", err)
+	}
+}
+
+type TestBaseModel struct {
+	ID        int64 `db:"id"`
+	CreatedAt int64 `db:"created_at"`
+	UpdatedAt int64 `db:"updated_at"`
+}
+
+type testEmbeddedEntity struct {
+	TestBaseModel
+	Name string `db:"name"`
+}
+
+type testEmbeddedPtrEntity struct {
+	*TestBaseModel
+	Name string `db:"name"`
+}
+
+func TestRepositoryEmbeddedFields(t *testing.T) {
+	columns := []string{"id", "created_at", "updated_at", "name"}
+
+	embedded := NewRepository[testEmbeddedEntity](nil, "t", MySQL)
+	if got := embedded.getFields(&testEmbeddedEntity{}); !reflect.DeepEqual(got, columns) {
+		t.Errorf("Unexpected embedded fields: %v", got)
+	}
+	values := embedded.getValues(&testEmbeddedEntity{TestBaseModel: TestBaseModel{ID: 1, CreatedAt: 2, UpdatedAt: 3}, Name: "a"})
+	if !reflect.DeepEqual(values, []interface{}{int64(1), int64(2), int64(3), "a"}) {
+		t.Errorf("Unexpected embedded values: %v", values)
+	}
+
+	pointer := NewRepository[testEmbeddedPtrEntity](nil, "t", MySQL)
+	if got := pointer.getFields(&testEmbeddedPtrEntity{}); !reflect.DeepEqual(got, columns) {
+		t.Errorf("Unexpected embedded pointer fields: %v", got)
+	}
+	if values := pointer.getValues(&testEmbeddedPtrEntity{Name: "a"}); !reflect.DeepEqual(values, []interface{}{nil, nil, nil, "a"}) {
+		t.Errorf("Expected nil values for a nil embedded pointer, got %v", values)
+	}
+	if id, ok := pointer.entityID(&testEmbeddedPtrEntity{TestBaseModel: &TestBaseModel{ID: 7}}); !ok || id != 7 {
+		t.Errorf("Expected id 7 from the embedded model, got %d (%v)", id, ok)
+	}
+
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[testEmbeddedPtrEntity](db, "users", MySQL).WithTimestamps()
+	if _, err := repo.Query().ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected := "SELECT `id`, `created_at`, `updated_at`, `name` FROM `users`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	fixTimeNow(t, time.Unix(100, 0))
+	entity := &testEmbeddedPtrEntity{Name: "a"}
+	if err := repo.BatchInsert([]*testEmbeddedPtrEntity{entity}, nil); err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if entity.TestBaseModel == nil || entity.CreatedAt != 100 || entity.UpdatedAt != 100 {
+		t.Errorf("Expected timestamps set on the embedded model, got %+v", entity.TestBaseModel)
+	}
+	insert := "INSERT INTO users (id,created_at,updated_at,name) VALUES (?,?,?,?)"
+	if execs := fake.Execs(); len(execs) != 1 || execs[0] != insert {
+		t.Errorf("Expected SQL %q, got %v", insert, execs)
+	}
+}
+
+type testNamedEntity struct {
+	ID   int64 `db:"id"`
+	Name string
+}
+
+func (*testNamedEntity) TableName() string { return "named_things" }
+
+func TestTableIntrospection(t *testing.T) {
+	if got := TableColumns[testMixedEntity](); !reflect.DeepEqual(got, []string{"id", "login", "email"}) {
+		t.Errorf("Unexpected columns: %v", got)
+	}
+	if got := TableColumns[testEmbeddedEntity](); !reflect.DeepEqual(got, []string{"id", "created_at", "updated_at", "name"}) {
+		t.Errorf("Unexpected embedded columns: %v", got)
+	}
+
+	if got := TableName(NewRepository[testNamedEntity](nil, "", MySQL)); got != "named_things" {
+		t.Errorf("Expected table from TableName(), got %q", got)
+	}
+	if got := TableName(NewRepository[testNamedEntity](nil, "archive", MySQL)); got != "archive" {
+		t.Errorf("Expected the explicit table to win, got %q", got)
+	}
+	if got := TableName[testNamedEntity](nil); got != "named_things" {
+		t.Errorf("Expected table from the type, got %q", got)
+	}
+	if got := TableName[TestEntity](nil); got != "" {
+		t.Errorf("Expected no table for an entity without TableName(), got %q", got)
+	}
+
+	db, fake := newFakeDBLogger(t)
+	db.prefix = "app1_"
+	if _, err := NewRepositoryWithPrefix[testNamedEntity](db, "", MySQL).Query().ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected := "SELECT `id`, `name` FROM `app1_named_things`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+}
+
+type testValueNamedEntity struct {
+	ID int64 `db:"id"`
+}
+
+func (testValueNamedEntity) TableName() string { return "value_named" }
+
+func TestNewRepositoryTableFromType(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	if _, err := NewRepository[testValueNamedEntity](db, "", MySQL).Query().ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected := "SELECT `id` FROM `value_named`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "TableName()") {
+			t.Errorf("Expected a panic without a table name, got %v", r)
+		}
+	}()
+	NewRepository[TestEntity](nil, "", MySQL)
+}
+
+func TestNewRepositoryWithPrefixRequiresTable(t *testing.T) {
+	db, _ := newFakeDBLogger(t)
+	db.prefix = "app1_"
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "NewRepositoryWithPrefix") {
+			t.Errorf("Expected a panic instead of the bare prefix as the table, got %v", r)
+		}
+	}()
+	NewRepositoryWithPrefix[TestEntity](db, "", MySQL)
+}
@@ -0,0 +1,485 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// MemoryQueryable 基于内存切片的 IQueryable 实现，用于在单元测试中替代数据库：
+//
+//	q := core.NewMemoryQueryable([]*User{{ID: 1, Status: 1}, {ID: 2, Status: 0}})
+//	users, err := service.ActiveUsers(q) // service 中的 q.Where(goqu.Ex{"status": 1}).ToList()
+//
+// 已实现 Where/WhereEq 等比较条件、WherePredicate、OrderBy/OrderByRaw、Scope、Skip/Take/Limit、WithContext、
+// ToList、Count、FirstOrDefault、Any 及其 Tx 版本和 HasWhere/HasOrder/HasLimit，调用其他方法会 panic
+// Where 支持的 goqu.Ex 值：普通值（等于）、nil（IS NULL）、切片（IN）以及
+// goqu.Op 的 eq/neq/gt/gte/lt/lte/in/notin/is/isnot，列名按 db tag 匹配字段
+type MemoryQueryable[T any] struct {
+	IQueryable[T] // 未实现的方法
+
+	items      IEnumerable[*T]                             // 全部数据
+	filters    []func(m *MemoryQueryable[T], item *T) bool // 过滤条件，执行时按顺序应用
+	orders     []memoryOrder
+	offset     int
+	limit      int // 0 表示不限制
+	hasWhere   bool
+	ctx        context.Context
+	nameMapper NameMapper
+	err        error // 执行时遇到的第一个错误，如未知列，由执行方法返回
+}
+
+// NewMemoryQueryable 创建基于 items 的内存查询，items 本身不会被修改
+func NewMemoryQueryable[T any](items []*T) *MemoryQueryable[T] {
+	data := make([]*T, len(items))
+	copy(data, items)
+	return &MemoryQueryable[T]{items: NewEnumerable(data)}
+}
+
+// clone 与 Queryable 一致，链式方法返回副本，不修改原查询
+func (m *MemoryQueryable[T]) clone() *MemoryQueryable[T] {
+	c := *m
+	c.filters = slices.Clip(m.filters)
+	return &c
+}
+
+// WithNameMapper 设置没有 db tag 的字段的列名映射，应与仓储使用的映射一致
+func (m *MemoryQueryable[T]) WithNameMapper(mapper NameMapper) *MemoryQueryable[T] {
+	m = m.clone()
+	m.nameMapper = mapper
+	return m
+}
+
+// WherePredicate 使用 Go 函数过滤，适合 goqu.Ex 无法表达的条件
+func (m *MemoryQueryable[T]) WherePredicate(predicate func(T) bool) *MemoryQueryable[T] {
+	m = m.clone()
+	m.filters = append(m.filters, func(_ *MemoryQueryable[T], item *T) bool { return predicate(*item) })
+	m.hasWhere = true
+	return m
+}
+
+func (m *MemoryQueryable[T]) Where(condition goqu.Ex) IQueryable[T] {
+	m = m.clone()
+	for column, value := range condition {
+		column, value := column, value
+		m.filters = append(m.filters, func(m *MemoryQueryable[T], item *T) bool {
+			ok, err := m.match(item, column, value)
+			if err != nil {
+				m.setErr(err)
+			}
+			return ok
+		})
+		m.hasWhere = true
+	}
+	return m
+}
+
+// matched 返回满足全部条件的数据
+func (m *MemoryQueryable[T]) matched() IEnumerable[*T] {
+	return m.items.Where(func(item *T) bool {
+		for _, filter := range m.filters {
+			if !filter(m, item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (m *MemoryQueryable[T]) WhereEq(column string, value interface{}) IQueryable[T] {
+	return m.Where(Cond().Eq(column, value).Build())
+}
+
+func (m *MemoryQueryable[T]) WhereNe(column string, value interface{}) IQueryable[T] {
+	return m.Where(Cond().Ne(column, value).Build())
+}
+
+func (m *MemoryQueryable[T]) WhereGt(column string, value interface{}) IQueryable[T] {
+	return m.Where(Cond().Gt(column, value).Build())
+}
+
+func (m *MemoryQueryable[T]) WhereGte(column string, value interface{}) IQueryable[T] {
+	return m.Where(Cond().Gte(column, value).Build())
+}
+
+func (m *MemoryQueryable[T]) WhereLt(column string, value interface{}) IQueryable[T] {
+	return m.Where(Cond().Lt(column, value).Build())
+}
+
+func (m *MemoryQueryable[T]) WhereLte(column string, value interface{}) IQueryable[T] {
+	return m.Where(Cond().Lte(column, value).Build())
+}
+
+func (m *MemoryQueryable[T]) OrderBy(cols ...string) IQueryable[T] {
+	orders := make([]memoryOrder, len(cols))
+	for i, col := range cols {
+		orders[i] = memoryOrder{column: col}
+	}
+	return m.orderBy(orders)
+}
+
+// OrderByRaw 支持 "name DESC, id" 形式的排序
+func (m *MemoryQueryable[T]) OrderByRaw(order string) IQueryable[T] {
+	var orders []memoryOrder
+	for _, part := range strings.Split(order, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		orders = append(orders, memoryOrder{
+			column: fields[0],
+			desc:   len(fields) > 1 && strings.EqualFold(fields[1], "DESC"),
+		})
+	}
+	if len(orders) == 0 {
+		return m
+	}
+	return m.orderBy(orders)
+}
+
+func (m *MemoryQueryable[T]) Scope(fn func(IQueryable[T]) IQueryable[T]) IQueryable[T] {
+	if fn == nil {
+		return m
+	}
+	return fn(m)
+}
+
+// Skip 与 SQL 一样在排序之后生效，与调用顺序无关
+func (m *MemoryQueryable[T]) Skip(offset int) IQueryable[T] {
+	m = m.clone()
+	m.offset = offset
+	return m
+}
+
+func (m *MemoryQueryable[T]) Take(take int) IQueryable[T] {
+	m = m.clone()
+	m.limit = take
+	return m
+}
+
+func (m *MemoryQueryable[T]) Limit(limit int) IQueryable[T] {
+	return m.Take(limit)
+}
+
+// WithContext 保存 ctx，不带 ctx 的执行方法在 ctx 已取消时返回 ctx.Err()
+func (m *MemoryQueryable[T]) WithContext(ctx context.Context) IQueryable[T] {
+	m = m.clone()
+	m.ctx = ctx
+	return m
+}
+
+func (m *MemoryQueryable[T]) baseContext() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+func (m *MemoryQueryable[T]) ToList() ([]*T, error) {
+	return m.ToListTx(m.baseContext())
+}
+
+func (m *MemoryQueryable[T]) ToListTx(ctx context.Context) ([]*T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.err = nil
+	items := m.matched()
+	if len(m.orders) > 0 {
+		items = items.OrderBy(m.less)
+	}
+	if m.offset > 0 {
+		items = items.Skip(m.offset)
+	}
+	if m.limit > 0 {
+		items = items.Take(m.limit)
+	}
+	list := items.ToList()
+	if m.err != nil {
+		return nil, m.err
+	}
+	return list, nil
+}
+
+func (m *MemoryQueryable[T]) Count() (int64, error) {
+	return m.CountTx(m.baseContext())
+}
+
+// CountTx 与 SELECT COUNT(*) 一致，只受 Where 条件影响
+func (m *MemoryQueryable[T]) CountTx(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.err = nil
+	count := m.matched().Count()
+	if m.err != nil {
+		return 0, m.err
+	}
+	return int64(count), nil
+}
+
+// FirstOrDefault 与 Queryable 一致，没有数据时返回零值和 sql.ErrNoRows
+func (m *MemoryQueryable[T]) FirstOrDefault() (*T, error) {
+	items, err := m.ToList()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return new(T), sql.ErrNoRows
+	}
+	return items[0], nil
+}
+
+func (m *MemoryQueryable[T]) Any(condition goqu.Ex) (bool, error) {
+	return m.AnyTx(m.baseContext(), condition)
+}
+
+func (m *MemoryQueryable[T]) AnyTx(ctx context.Context, condition goqu.Ex) (bool, error) {
+	count, err := m.Where(condition).CountTx(ctx)
+	return count > 0, err
+}
+
+func (m *MemoryQueryable[T]) HasWhere() bool {
+	return m.hasWhere
+}
+
+func (m *MemoryQueryable[T]) HasOrder() bool {
+	return len(m.orders) > 0
+}
+
+func (m *MemoryQueryable[T]) HasLimit() bool {
+	return m.limit > 0
+}
+
+type memoryOrder struct {
+	column string
+	desc   bool
+}
+
+// orderBy 替换排序列，与 Queryable 的 OrderBy 一致
+func (m *MemoryQueryable[T]) orderBy(orders []memoryOrder) IQueryable[T] {
+	m = m.clone()
+	m.orders = orders
+	return m
+}
+
+func (m *MemoryQueryable[T]) less(a, b *T) bool {
+	for _, order := range m.orders {
+		left, err := m.field(a, order.column)
+		if err != nil {
+			m.setErr(err)
+			return false
+		}
+		right, _ := m.field(b, order.column)
+		c, err := compareValues(left, right)
+		if err != nil {
+			m.setErr(err)
+			return false
+		}
+		if c != 0 {
+			return (c < 0) != order.desc
+		}
+	}
+	return false
+}
+
+func (m *MemoryQueryable[T]) setErr(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// field 按列名读取实体字段的值，列名可以带表名前缀
+func (m *MemoryQueryable[T]) field(item *T, column string) (interface{}, error) {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	v := reflect.ValueOf(item).Elem()
+	for _, c := range entityColumns(v.Type(), m.nameMapper) {
+		if c.name != column {
+			continue
+		}
+		if field := fieldByIndex(v, c.index); field.IsValid() {
+			return field.Interface(), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("memory queryable: unknown column %q on %s", column, v.Type().Name())
+}
+
+// match 判断实体是否满足单列条件
+func (m *MemoryQueryable[T]) match(item *T, column string, value interface{}) (bool, error) {
+	actual, err := m.field(item, column)
+	if err != nil {
+		return false, err
+	}
+	ops, ok := value.(goqu.Op)
+	if !ok {
+		return matchOp(actual, defaultOp(value), value)
+	}
+	for op, expected := range ops {
+		ok, err := matchOp(actual, strings.ToLower(op), expected)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// defaultOp 返回 goqu.Ex 普通值对应的操作符
+func defaultOp(value interface{}) string {
+	if value == nil {
+		return "is"
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		return "in"
+	}
+	return "eq"
+}
+
+func matchOp(actual interface{}, op string, expected interface{}) (bool, error) {
+	switch op {
+	case "is", "isnot":
+		if expected != nil {
+			return false, fmt.Errorf("memory queryable: unsupported %s value %v", op, expected)
+		}
+		return isNil(actual) == (op == "is"), nil
+	case "in", "notin":
+		v := reflect.ValueOf(expected)
+		if v.Kind() != reflect.Slice {
+			return false, fmt.Errorf("memory queryable: %s expects a slice, got %T", op, expected)
+		}
+		found := false
+		for i := 0; i < v.Len() && !found; i++ {
+			c, err := compareValues(actual, v.Index(i).Interface())
+			if err != nil {
+				return false, err
+			}
+			found = c == 0
+		}
+		return found == (op == "in"), nil
+	}
+
+	c, err := compareValues(actual, expected)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "eq":
+		return c == 0, nil
+	case "neq":
+		return c != 0, nil
+	case "gt":
+		return c > 0, nil
+	case "gte":
+		return c >= 0, nil
+	case "lt":
+		return c < 0, nil
+	case "lte":
+		return c <= 0, nil
+	}
+	return false, fmt.Errorf("memory queryable: unsupported operator %q", op)
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+// compareValues 比较两个值，支持整数、浮点数、字符串、布尔值和 time.Time，指针会先解引用
+func compareValues(a, b interface{}) (int, error) {
+	av, bv := indirectValue(a), indirectValue(b)
+	if !av.IsValid() || !bv.IsValid() {
+		switch {
+		case !av.IsValid() && !bv.IsValid():
+			return 0, nil
+		case !av.IsValid():
+			return -1, nil
+		}
+		return 1, nil
+	}
+
+	switch {
+	case isSigned(av) && isSigned(bv):
+		return compareOrdered(av.Int(), bv.Int()), nil
+	case isNumber(av) && isNumber(bv):
+		return compareOrdered(toFloat64(av), toFloat64(bv)), nil
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return strings.Compare(av.String(), bv.String()), nil
+	case av.Kind() == reflect.Bool && bv.Kind() == reflect.Bool:
+		if av.Bool() == bv.Bool() {
+			return 0, nil
+		}
+		if av.Bool() {
+			return 1, nil
+		}
+		return -1, nil
+	}
+	if at, ok := av.Interface().(time.Time); ok {
+		if bt, ok := bv.Interface().(time.Time); ok {
+			return at.Compare(bt), nil
+		}
+	}
+	return 0, fmt.Errorf("memory queryable: cannot compare %T with %T", a, b)
+}
+
+func indirectValue(value interface{}) reflect.Value {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isSigned(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return isSigned(v)
+}
+
+func toFloat64(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return float64(v.Int())
+}
+
+func compareOrdered[N int64 | float64](a, b N) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// topActive 模拟只依赖 IQueryable 的业务代码
+func topActive(q IQueryable[TestEntity], n int) ([]*TestEntity, error) {
+	return q.Where(goqu.Ex{"status": 1}).OrderBy("name").Take(n).ToList()
+}
+
+func memoryEntities() []*TestEntity {
+	return []*TestEntity{
+		{ID: 1, Name: "carol", Status: 1},
+		{ID: 2, Name: "alice", Status: 1},
+		{ID: 3, Name: "bob", Status: 0},
+		{ID: 4, Name: "dave", Status: 1},
+	}
+}
+
+func entityIDs(items []*TestEntity) []int64 {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestMemoryQueryable(t *testing.T) {
+	items, err := topActive(NewMemoryQueryable(memoryEntities()), 2)
+	if err != nil {
+		t.Fatalf("topActive failed: %v", err)
+	}
+	if got := entityIDs(items); len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Errorf("expected ids [2 1], got %v", got)
+	}
+
+	q := NewMemoryQueryable(memoryEntities())
+	items, err = q.Where(goqu.Ex{"id": goqu.Op{"gte": 2}, "status": []int{0, 1}}).
+		OrderByRaw("status DESC, name").Skip(1).ToList()
+	if err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if got := entityIDs(items); len(got) != 2 || got[0] != 4 || got[1] != 3 {
+		t.Errorf("expected ids [4 3], got %v", got)
+	}
+
+	count, err := NewMemoryQueryable(memoryEntities()).
+		WherePredicate(func(e TestEntity) bool { return len(e.Name) > 3 }).
+		WhereNe("id", 1).Count()
+	if err != nil || count != 2 {
+		t.Errorf("expected count 2, got %d (%v)", count, err)
+	}
+
+	_, err = NewMemoryQueryable(memoryEntities()).Where(goqu.Ex{"status": 9}).FirstOrDefault()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	_, err = NewMemoryQueryable(memoryEntities()).Where(goqu.Ex{"missing": 1}).ToList()
+	if err == nil {
+		t.Error("expected error for unknown column")
+	}
+	_, err = NewMemoryQueryable(memoryEntities()).Where(goqu.Ex{"id": goqu.Op{"like": "a%"}}).ToList()
+	if err == nil {
+		t.Error("expected error for unsupported operator")
+	}
+}
+
+func TestMemoryQueryableBranches(t *testing.T) {
+	base := NewMemoryQueryable(memoryEntities()).Where(goqu.Ex{"status": 1})
+	named := base.WhereGt("name", "b")
+	bad := base.Where(goqu.Ex{"missing": 1})
+
+	if items, err := base.ToList(); err != nil || len(items) != 3 {
+		t.Errorf("expected the base query to keep 3 rows, got %v (%v)", entityIDs(items), err)
+	}
+	if items, err := named.ToList(); err != nil || len(items) != 2 {
+		t.Errorf("expected 2 rows for the name branch, got %v (%v)", entityIDs(items), err)
+	}
+	if _, err := bad.ToList(); err == nil {
+		t.Error("expected error for unknown column on the bad branch")
+	}
+	if _, err := named.Count(); err != nil {
+		t.Errorf("expected the bad branch not to affect its sibling, got %v", err)
+	}
+}
@@ -2,42 +2,356 @@ package core
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 type Queryable[T any] struct {
-	db    *DBLogger
-	query *goqu.SelectDataset
+	db           *DBLogger
+	query        *goqu.SelectDataset
+	dbType       DialectType      // 数据库类型，用于生成方言相关的 SQL
+	nameMapper   NameMapper       // 没有 db tag 的字段的列名映射
+	alias        string           // FROM 表的别名，设置后实体字段按别名限定，避免自连接时列名歧义
+	table        string           // 表名，用于执行失败时的错误信息
+	cursorColumn string           // 游标分页的列，由 AfterCursor 设置
+	timeout      time.Duration    // 单条语句的超时，由 WithTimeout 设置
+	ctx          context.Context  // 不带 ctx 的执行方法使用的 context，由 WithContext 设置
+	tenant       []exp.Expression // 仓储的租户条件，FromDataset 替换查询时重新附加
+
+	// 由构造方法设置的状态，见 HasWhere/HasOrder/HasLimit
+	hasWhere bool
+	hasOrder bool
+	hasLimit bool
+
+	unordered bool // 分页时不追加默认排序，由 Unordered 设置
+}
+
+// clone 返回查询的副本，链式方法都在副本上修改并返回副本，
+// 同一个查询可以分别派生出多个互不影响的查询，如 base.Where(a) 和 base.Where(b)
+func (q *Queryable[T]) clone() *Queryable[T] {
+	c := *q
+	return &c
+}
+
+// HasWhere 是否通过 Where 系列方法添加过条件，仓储自动附加的租户条件不计入
+func (q *Queryable[T]) HasWhere() bool {
+	return q.hasWhere
+}
+
+// HasOrder 是否设置了排序，可用于只在调用方未排序时追加默认排序，保证分页稳定
+func (q *Queryable[T]) HasOrder() bool {
+	return q.hasOrder
+}
+
+// HasLimit 是否通过 Take/Limit 设置了行数限制
+func (q *Queryable[T]) HasLimit() bool {
+	return q.hasLimit
+}
+
+// Unordered 关闭分页方法的默认排序，用于确实不关心顺序（如导出后再排序）的分页查询
+func (q *Queryable[T]) Unordered() IQueryable[T] {
+	q = q.clone()
+	q.unordered = true
+	return q
+}
+
+// ensurePageOrder 分页查询没有 ORDER BY 时按主键升序排序并记录警告，避免各页之间重复或遗漏数据
+// 主键列取自 PrimaryKeyer，默认 id；分组查询和调用过 Unordered 的查询不追加
+// 会修改接收者，执行方法需先 clone，避免共享的基础查询被追加排序
+func (q *Queryable[T]) ensurePageOrder() {
+	if q.hasOrder || q.unordered {
+		return
+	}
+	clauses := q.query.GetClauses()
+	if clauses.GroupBy() != nil {
+		return
+	}
+
+	column := primaryKeyColumn[T]()
+	order := goqu.I(column).Asc()
+	switch {
+	case q.alias != "":
+		order = goqu.T(q.alias).Col(column).Asc()
+	case clauses.Joins() != nil:
+		order = goqu.T(q.table).Col(column).Asc()
+	}
+	q.query = q.query.Order(order)
+	q.hasOrder = true
+
+	if q.db != nil && q.db.logger != nil {
+		q.db.logger.Warn("Paged query without ORDER BY, ordering by primary key",
+			zap.String("table", q.table), zap.String("column", column))
+	}
+}
+
+// primaryKeyColumn 返回实体的主键列
+func primaryKeyColumn[T any]() string {
+	if pk, ok := any(new(T)).(PrimaryKeyer); ok {
+		return pk.PrimaryKey()
+	}
+	return "id"
 }
 
 func (q *Queryable[T]) Where(condition goqu.Ex) IQueryable[T] {
-	q.query = q.query.Where(condition)
+	q = q.clone()
+	q.query = q.query.Where(normalizeEx(condition))
+	if len(condition) > 0 {
+		q.hasWhere = true
+	}
 	return q
 }
 
 func (q *Queryable[T]) WhereRaw(condition string, args ...interface{}) IQueryable[T] {
-	q.query = q.query.Where(goqu.L(condition, args...))
+	q = q.clone()
+	q.query = q.query.Where(goqu.L(condition, normalizeArgs(args)...))
+	q.hasWhere = true
+	return q
+}
+
+// WhereGroup 追加由 fn 构造的嵌套 AND/OR 条件组，组为空时不追加条件，见 ConditionGroup
+func (q *Queryable[T]) WhereGroup(fn func(g ConditionGroup)) IQueryable[T] {
+	q = q.clone()
+	if g := newConditionGroup(fn); g.expr != nil {
+		q.query = q.query.Where(g.expr)
+		q.hasWhere = true
+	}
+	return q
+}
+
+// WhereColumns 比较两列的条件，如 WhereColumns("updated_at", ">", "created_at")、WhereColumns("a.x", "=", "b.y")
+// 两侧都作为标识符处理，op 支持 = != <> > >= < <=
+func (q *Queryable[T]) WhereColumns(left, op, right string) IQueryable[T] {
+	q = q.clone()
+	l, r := goqu.I(left), goqu.I(right)
+	var cond exp.BooleanExpression
+	switch op {
+	case "=":
+		cond = l.Eq(r)
+	case "!=", "<>":
+		cond = l.Neq(r)
+	case ">":
+		cond = l.Gt(r)
+	case ">=":
+		cond = l.Gte(r)
+	case "<":
+		cond = l.Lt(r)
+	case "<=":
+		cond = l.Lte(r)
+	default:
+		q.query = q.query.SetError(fmt.Errorf("WhereColumns: unsupported operator %q", op))
+		return q
+	}
+	q.query = q.query.Where(cond)
+	q.hasWhere = true
+	return q
+}
+
+// WhereEq 等于条件 column = value，value 为 nil 时生成 IS NULL，多次调用以 AND 连接
+func (q *Queryable[T]) WhereEq(column string, value interface{}) IQueryable[T] {
+	return q.Where(Cond().Eq(column, value).Build())
+}
+
+// WhereNe 不等于条件 column != value，多次调用以 AND 连接
+func (q *Queryable[T]) WhereNe(column string, value interface{}) IQueryable[T] {
+	return q.Where(Cond().Ne(column, value).Build())
+}
+
+// WhereGt 大于条件 column > value，多次调用以 AND 连接
+func (q *Queryable[T]) WhereGt(column string, value interface{}) IQueryable[T] {
+	return q.Where(Cond().Gt(column, value).Build())
+}
+
+// WhereGte 大于等于条件 column >= value，多次调用以 AND 连接
+func (q *Queryable[T]) WhereGte(column string, value interface{}) IQueryable[T] {
+	return q.Where(Cond().Gte(column, value).Build())
+}
+
+// WhereLt 小于条件 column < value，多次调用以 AND 连接
+func (q *Queryable[T]) WhereLt(column string, value interface{}) IQueryable[T] {
+	return q.Where(Cond().Lt(column, value).Build())
+}
+
+// WhereLte 小于等于条件 column <= value，多次调用以 AND 连接
+func (q *Queryable[T]) WhereLte(column string, value interface{}) IQueryable[T] {
+	return q.Where(Cond().Lte(column, value).Build())
+}
+
+// WhereEqTx 等系列方法只构造条件，不执行查询，ctx 仅为与其他 Tx 方法保持一致
+func (q *Queryable[T]) WhereEqTx(ctx context.Context, column string, value interface{}) IQueryable[T] {
+	return q.WhereEq(column, value)
+}
+
+func (q *Queryable[T]) WhereNeTx(ctx context.Context, column string, value interface{}) IQueryable[T] {
+	return q.WhereNe(column, value)
+}
+
+func (q *Queryable[T]) WhereGtTx(ctx context.Context, column string, value interface{}) IQueryable[T] {
+	return q.WhereGt(column, value)
+}
+
+func (q *Queryable[T]) WhereGteTx(ctx context.Context, column string, value interface{}) IQueryable[T] {
+	return q.WhereGte(column, value)
+}
+
+func (q *Queryable[T]) WhereLtTx(ctx context.Context, column string, value interface{}) IQueryable[T] {
+	return q.WhereLt(column, value)
+}
+
+func (q *Queryable[T]) WhereLteTx(ctx context.Context, column string, value interface{}) IQueryable[T] {
+	return q.WhereLte(column, value)
+}
+
+// WhereTupleIn 多列行值 IN 条件，用于按复合主键批量查询，生成 (a, b) IN ((?, ?), (?, ?))
+// tuples 为空时生成恒假条件，不返回任何行
+func (q *Queryable[T]) WhereTupleIn(columns []string, tuples [][]interface{}) IQueryable[T] {
+	q = q.clone()
+	if len(columns) == 0 {
+		q.query = q.query.SetError(fmt.Errorf("WhereTupleIn requires at least one column"))
+		return q
+	}
+	if len(tuples) == 0 {
+		q.query = q.query.Where(goqu.L("1 = 0"))
+		q.hasWhere = true
+		return q
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	row := "(" + strings.Join(placeholders, ", ") + ")"
+
+	args := make([]interface{}, 0, len(columns)*(len(tuples)+1))
+	for _, column := range columns {
+		args = append(args, goqu.I(column))
+	}
+	rows := make([]string, len(tuples))
+	for i, tuple := range tuples {
+		if len(tuple) != len(columns) {
+			q.query = q.query.SetError(fmt.Errorf("tuple %d has %d values, expected %d", i, len(tuple), len(columns)))
+			return q
+		}
+		rows[i] = row
+		args = append(args, tuple...)
+	}
+
+	q.query = q.query.Where(goqu.L(row+" IN ("+strings.Join(rows, ", ")+")", args...))
+	q.hasWhere = true
+	return q
+}
+
+// WhereStruct 根据过滤结构体构造查询条件
+// 每个带 db tag 的非零字段（指针字段为非 nil）都会生成一个条件，零值字段会被跳过，
+// 默认是等值条件，可通过 query tag 指定操作符，如 `query:"like"`、`query:"gte"`、`query:"in"`
+func (q *Queryable[T]) WhereStruct(filter interface{}) IQueryable[T] {
+	q = q.clone()
+	v := reflect.ValueOf(filter)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return q
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		q.query = q.query.SetError(fmt.Errorf("WhereStruct expects a struct, got %T", filter))
+		return q
+	}
+
+	cond := Cond()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		column := strings.Split(field.Tag.Get("db"), ",")[0]
+		if !field.IsExported() || column == "" || column == "-" {
+			continue
+		}
+
+		value := v.Field(i)
+		if value.IsZero() {
+			continue
+		}
+		if value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Slice && value.Len() == 0 {
+			continue
+		}
+
+		op := strings.ToLower(field.Tag.Get("query"))
+		switch op {
+		case "":
+			op = "eq"
+		case "ne":
+			op = "neq"
+		}
+		cond.op(column, op, value.Interface())
+	}
+
+	if ex := cond.Build(); len(ex) > 0 {
+		q.query = q.query.Where(ex)
+		q.hasWhere = true
+	}
 	return q
 }
 
 func (q *Queryable[T]) OrderBy(cols ...string) IQueryable[T] {
+	q = q.clone()
 	orderedExpressions := make([]exp.OrderedExpression, len(cols))
 	for i, col := range cols {
 		orderedExpressions[i] = goqu.I(col).Asc()
 	}
 	q.query = q.query.Order(orderedExpressions...)
+	q.hasOrder = len(cols) > 0
+	return q
+}
+
+// OrderByAggregate 按 SELECT 中的聚合别名排序，如 ORDER BY `total` DESC，用于取前 N 个分组
+// 追加在已有排序之后；alias 不是当前 SELECT 的别名时错误由执行方法返回
+func (q *Queryable[T]) OrderByAggregate(alias string, desc bool) IQueryable[T] {
+	q = q.clone()
+	if !q.hasSelectAlias(alias) {
+		q.query = q.query.SetError(fmt.Errorf("OrderByAggregate: %q is not a select alias", alias))
+		return q
+	}
+	order := goqu.I(alias).Asc()
+	if desc {
+		order = goqu.I(alias).Desc()
+	}
+	q.query = q.query.OrderAppend(order)
+	q.hasOrder = true
 	return q
 }
 
+// hasSelectAlias 判断 SELECT 中是否有指定别名的列
+func (q *Queryable[T]) hasSelectAlias(alias string) bool {
+	for _, col := range q.query.GetClauses().Select().Columns() {
+		aliased, ok := col.(exp.AliasedExpression)
+		if !ok {
+			continue
+		}
+		if name, ok := aliased.GetAs().GetCol().(string); ok && name == alias {
+			return true
+		}
+	}
+	return false
+}
+
 // OrderByRaw 支持原始排序语句
 // OrderByRaw 支持原始排序语句
 func (q *Queryable[T]) OrderByRaw(column string) IQueryable[T] {
+	q = q.clone()
 	// 多字段（含逗号）处理逻辑
 	if strings.Contains(column, ",") {
 		orderParts := strings.Split(column, ",")
@@ -65,6 +379,7 @@ func (q *Queryable[T]) OrderByRaw(column string) IQueryable[T] {
 
 		if len(orderedExpressions) > 0 {
 			q.query = q.query.Order(orderedExpressions...)
+			q.hasOrder = true
 		}
 		return q
 	}
@@ -83,25 +398,108 @@ func (q *Queryable[T]) OrderByRaw(column string) IQueryable[T] {
 
 	if dir == "DESC" {
 		q.query = q.query.Order(goqu.I(col).Desc())
+		q.hasOrder = true
 	} else {
 		q.query = q.query.Order(goqu.I(col).Asc())
+		q.hasOrder = true
 	}
 	return q
 }
 
+// OrderByRandom 随机排序，配合 Take(1) 可随机取一行
+func (q *Queryable[T]) OrderByRandom() IQueryable[T] {
+	q = q.clone()
+	q.query = q.query.OrderAppend(goqu.L(randomFunc).Asc())
+	q.hasOrder = true
+	return q
+}
+
+// randomFunc 随机函数，MySQL 和 StarRocks 都使用 RAND()
+const randomFunc = "RAND()"
+
+// Scope 将一组可复用的筛选条件应用到当前查询上
+// 例如: q.Scope(ActiveScope).Scope(TenantScope(42))
+func (q *Queryable[T]) Scope(fn func(IQueryable[T]) IQueryable[T]) IQueryable[T] {
+	if fn == nil {
+		return q
+	}
+	return fn(q)
+}
+
 func (q *Queryable[T]) Skip(offset int) IQueryable[T] {
+	q = q.clone()
 	q.query = q.query.Offset(uint(offset))
 	return q
 }
 
 func (q *Queryable[T]) Take(limit int) IQueryable[T] {
+	q = q.clone()
 	q.query = q.query.Limit(uint(limit))
+	q.hasLimit = limit > 0
+	return q
+}
+
+// WithContext 保存 ctx，之后 ToList、Count 等不带 ctx 的执行方法都使用它，ctx 取消或超时时中止查询
+// Tx 系列方法仍使用各自传入的 ctx；未设置时使用 context.Background()
+func (q *Queryable[T]) WithContext(ctx context.Context) IQueryable[T] {
+	q = q.clone()
+	q.ctx = ctx
 	return q
 }
 
+// baseContext 返回不带 ctx 的执行方法使用的 context
+func (q *Queryable[T]) baseContext() context.Context {
+	if q.ctx != nil {
+		return q.ctx
+	}
+	return context.Background()
+}
+
+// WithTimeout 为每条语句设置超时，执行时派生带超时的 ctx
+// 与 Tx 方法传入的 ctx 同时存在时以较早的截止时间为准，d 不大于 0 时不设超时
+// Rows/RowsTx 返回的结果集由调用方关闭，不受 WithTimeout 影响
+func (q *Queryable[T]) WithTimeout(d time.Duration) IQueryable[T] {
+	q = q.clone()
+	q.timeout = d
+	return q
+}
+
+// statementContext 按 WithTimeout 派生执行语句用的 ctx，未设置超时时原样返回
+func (q *Queryable[T]) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if q.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, q.timeout)
+}
+
+func (q *Queryable[T]) get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := q.statementContext(ctx)
+	defer cancel()
+	return q.db.GetContext(ctx, dest, query, args...)
+}
+
+func (q *Queryable[T]) selectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := q.statementContext(ctx)
+	defer cancel()
+	return q.db.SelectContext(ctx, dest, query, args...)
+}
+
+// queryx 按 WithTimeout 执行查询，返回的 cancel 需在关闭 rows 之后调用
+func (q *Queryable[T]) queryx(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, context.CancelFunc, error) {
+	ctx, cancel := q.statementContext(ctx)
+	rows, err := q.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return rows, cancel, nil
+}
+
 // 需要添加的方法
-func (q *Queryable[T]) FirstOrDefault() (*T, error) {
+func (q *Queryable[T]) FirstOrDefault() (_ *T, err error) {
+	defer wrapQueryError("FirstOrDefault", q.table, &err)
 	// 🔥 优化：确保使用结构体字段
+	q = q.clone()
 	q.ensureSelectFields()
 
 	query, args, err := q.query.Limit(1).ToSQL()
@@ -109,13 +507,15 @@ func (q *Queryable[T]) FirstOrDefault() (*T, error) {
 		return nil, err
 	}
 	var result T
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	return &result, err
 }
 
 // FirstOrDefaultTx(ctx context.Context) (*T, error)
-func (q *Queryable[T]) FirstOrDefaultTx(ctx context.Context) (*T, error) {
+func (q *Queryable[T]) FirstOrDefaultTx(ctx context.Context) (_ *T, err error) {
+	defer wrapQueryError("FirstOrDefaultTx", q.table, &err)
 	// 🔥 优化：确保使用结构体字段
+	q = q.clone()
 	q.ensureSelectFields()
 
 	query, args, err := q.query.Limit(1).ToSQL()
@@ -123,11 +523,13 @@ func (q *Queryable[T]) FirstOrDefaultTx(ctx context.Context) (*T, error) {
 		return nil, err
 	}
 	var result T
-	err = q.db.GetContext(ctx, &result, query, args...)
+	err = q.get(ctx, &result, query, args...)
 	return &result, err
 }
-func (q *Queryable[T]) ToListTx(ctx context.Context) ([]*T, error) {
+func (q *Queryable[T]) ToListTx(ctx context.Context) (_ []*T, err error) {
+	defer wrapQueryError("ToListTx", q.table, &err)
 	// 🔥 优化：确保使用结构体字段
+	q = q.clone()
 	q.ensureSelectFields()
 
 	query, args, err := q.query.ToSQL()
@@ -135,33 +537,37 @@ func (q *Queryable[T]) ToListTx(ctx context.Context) ([]*T, error) {
 		return nil, err
 	}
 	var results []*T
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) CountTx(ctx context.Context) (int64, error) {
-	query, args, err := q.query.Select(goqu.COUNT("*")).ToSQL()
+func (q *Queryable[T]) CountTx(ctx context.Context) (_ int64, err error) {
+	defer wrapQueryError("CountTx", q.table, &err)
+	query, args, err := q.countQuery().ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var count int64
-	err = q.db.GetContext(ctx, &count, query, args...)
+	err = q.get(ctx, &count, query, args...)
 	return count, err
 }
 
 // sum
-func (q *Queryable[T]) SumTx(ctx context.Context, field string) (float64, error) {
+func (q *Queryable[T]) SumTx(ctx context.Context, field string) (_ float64, err error) {
+	defer wrapQueryError("SumTx", q.table, &err)
 	query, args, err := q.query.Select(goqu.SUM(field)).ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var sum float64
-	err = q.db.GetContext(ctx, &sum, query, args...)
+	err = q.get(ctx, &sum, query, args...)
 	return sum, err
 }
 
-func (q *Queryable[T]) ToGroupedListTx(ctx context.Context) ([]*T, error) {
+func (q *Queryable[T]) ToGroupedListTx(ctx context.Context) (_ []*T, err error) {
+	defer wrapQueryError("ToGroupedListTx", q.table, &err)
 	// 🔥 优化：确保使用结构体字段
+	q = q.clone()
 	q.ensureSelectFields()
 
 	query, args, err := q.query.ToSQL()
@@ -169,33 +575,39 @@ func (q *Queryable[T]) ToGroupedListTx(ctx context.Context) ([]*T, error) {
 		return nil, err
 	}
 	var results []*T
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) AnyTx(ctx context.Context, condition goqu.Ex) (bool, error) {
+func (q *Queryable[T]) AnyTx(ctx context.Context, condition goqu.Ex) (_ bool, err error) {
+	defer wrapQueryError("AnyTx", q.table, &err)
 	count, err := q.Where(condition).CountTx(ctx)
 	return count > 0, err
 }
 
 // Min
-func (q *Queryable[T]) MinTx(ctx context.Context, field string) (interface{}, error) {
+func (q *Queryable[T]) MinTx(ctx context.Context, field string) (_ interface{}, err error) {
+	defer wrapQueryError("MinTx", q.table, &err)
 	query, args, err := q.query.Select(goqu.MIN(field)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var min interface{}
-	err = q.db.GetContext(ctx, &min, query, args...)
+	err = q.get(ctx, &min, query, args...)
 	return min, err
 }
 
-func (q *Queryable[T]) ToPagedListTx(ctx context.Context, page, size int, condition goqu.Ex) (*PageResult[T], error) {
+func (q *Queryable[T]) ToPagedListTx(ctx context.Context, page, size int, condition goqu.Ex) (_ *PageResult[T], err error) {
+	defer wrapQueryError("ToPagedListTx", q.table, &err)
+	q = q.clone()
+	q.ensurePageOrder()
 	offset := (page - 1) * size
-	items, err := q.Where(condition).Skip(offset).Take(size).ToListTx(ctx)
+	filtered := q.Where(condition)
+	items, err := filtered.Skip(offset).Take(size).ToListTx(ctx)
 	if err != nil {
 		return nil, err
 	}
-	count, err := q.Where(condition).CountTx(ctx)
+	count, err := filtered.CountTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -207,87 +619,97 @@ func (q *Queryable[T]) ToPagedListTx(ctx context.Context, page, size int, condit
 	}, nil
 }
 
-func (q *Queryable[T]) ToInt64SliceTx(ctx context.Context) ([]int64, error) {
+func (q *Queryable[T]) ToInt64SliceTx(ctx context.Context) (_ []int64, err error) {
+	defer wrapQueryError("ToInt64SliceTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []int64
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToStringSliceTx(ctx context.Context) ([]string, error) {
+func (q *Queryable[T]) ToStringSliceTx(ctx context.Context) (_ []string, err error) {
+	defer wrapQueryError("ToStringSliceTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []string
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToFloat64SliceTx(ctx context.Context) ([]float64, error) {
+func (q *Queryable[T]) ToFloat64SliceTx(ctx context.Context) (_ []float64, err error) {
+	defer wrapQueryError("ToFloat64SliceTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []float64
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToMapSliceTx(ctx context.Context) ([]map[string]interface{}, error) {
+func (q *Queryable[T]) ToMapSliceTx(ctx context.Context) (_ []map[string]interface{}, err error) {
+	defer wrapQueryError("ToMapSliceTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []map[string]interface{}
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToMapTx(ctx context.Context) (map[string]interface{}, error) {
+func (q *Queryable[T]) ToMapTx(ctx context.Context) (_ map[string]interface{}, err error) {
+	defer wrapQueryError("ToMapTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var result map[string]interface{}
-	err = q.db.GetContext(ctx, &result, query, args...)
+	err = q.get(ctx, &result, query, args...)
 	return result, err
 }
 
-func (q *Queryable[T]) ToStructTx(ctx context.Context) (*T, error) {
+func (q *Queryable[T]) ToStructTx(ctx context.Context) (_ *T, err error) {
+	defer wrapQueryError("ToStructTx", q.table, &err)
 	query, args, err := q.query.Limit(1).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var result T
-	err = q.db.GetContext(ctx, &result, query, args...)
+	err = q.get(ctx, &result, query, args...)
 	return &result, err
 }
 
-func (q *Queryable[T]) ToResultTx(ctx context.Context, result interface{}) error {
+func (q *Queryable[T]) ToResultTx(ctx context.Context, result interface{}) (err error) {
+	defer wrapQueryError("ToResultTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return err
 	}
-	return q.db.SelectContext(ctx, result, query, args...)
+	return q.selectContext(ctx, result, query, args...)
 }
 
 // MaxTx
-func (q *Queryable[T]) MaxTx(ctx context.Context, field string) (interface{}, error) {
+func (q *Queryable[T]) MaxTx(ctx context.Context, field string) (_ interface{}, err error) {
+	defer wrapQueryError("MaxTx", q.table, &err)
 	query, args, err := q.query.Select(goqu.MAX(field)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var max interface{}
-	err = q.db.GetContext(ctx, &max, query, args...)
+	err = q.get(ctx, &max, query, args...)
 	return max, err
 }
 
-func (q *Queryable[T]) ToList() ([]*T, error) {
+func (q *Queryable[T]) ToList() (_ []*T, err error) {
+	defer wrapQueryError("ToList", q.table, &err)
 	// 🔥 优化：如果没有指定 Select 字段，自动使用结构体中定义的字段
+	q = q.clone()
 	q.ensureSelectFields()
 
 	query, args, err := q.query.ToSQL()
@@ -295,22 +717,178 @@ func (q *Queryable[T]) ToList() ([]*T, error) {
 		return nil, err
 	}
 	var results []*T
-	err = q.db.Select(&results, query, args...)
+	err = q.selectContext(q.baseContext(), &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) Count() (int64, error) {
-	query, args, err := q.query.Select(goqu.COUNT("*")).ToSQL()
+// ToListDistinct 逐行扫描并按 keyFn 去重，保留每个键第一次出现的实体，结果保持查询顺序
+// 用于一对多连表导致主表记录重复的场景，重复行扫描后即丢弃，不会整体加载后再去重；keyFn 的返回值需可作为 map 键
+func (q *Queryable[T]) ToListDistinct(keyFn func(*T) interface{}) (_ []*T, err error) {
+	defer wrapQueryError("ToListDistinct", q.table, &err)
+	return q.ToListDistinctTx(q.baseContext(), keyFn)
+}
+
+func (q *Queryable[T]) ToListDistinctTx(ctx context.Context, keyFn func(*T) interface{}) (_ []*T, err error) {
+	defer wrapQueryError("ToListDistinctTx", q.table, &err)
+	if keyFn == nil {
+		return nil, fmt.Errorf("keyFn must not be nil")
+	}
+	q = q.clone()
+	q.ensureSelectFields()
+	query, args, err := q.query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, cancel, err := q.queryx(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer rows.Close()
+
+	var results []*T
+	seen := make(map[interface{}]struct{})
+	item := new(T)
+	for rows.Next() {
+		var zero T
+		*item = zero // 被丢弃的行复用同一个实体，先清空未被选择的字段
+		if err := rows.StructScan(item); err != nil {
+			return nil, err
+		}
+		key := keyFn(item)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		results = append(results, item)
+		item = new(T)
+	}
+	return results, rows.Err()
+}
+
+// ToChan 以流的方式返回查询结果，每扫描一行就发送到数据通道
+// 查询结束后关闭两个通道，出错（包括 ctx 取消）时错误会发送到错误通道
+func (q *Queryable[T]) ToChan(ctx context.Context, bufferSize int) (<-chan *T, <-chan error) {
+	out := make(chan *T, bufferSize)
+	errc := make(chan error, 1)
+	fail := func(err error) {
+		wrapQueryError("ToChan", q.table, &err)
+		errc <- err
+	}
+
+	q = q.clone()
+	q.ensureSelectFields()
+	query, args, err := q.query.ToSQL()
+	if err != nil {
+		close(out)
+		fail(err)
+		close(errc)
+		return out, errc
+	}
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+
+		rows, cancel, err := q.queryx(ctx, query, args...)
+		if err != nil {
+			fail(err)
+			return
+		}
+		defer cancel()
+		defer rows.Close()
+
+		for rows.Next() {
+			item := new(T)
+			if err := rows.StructScan(item); err != nil {
+				fail(err)
+				return
+			}
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				fail(ctx.Err())
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			fail(err)
+		}
+	}()
+
+	return out, errc
+}
+
+// EstimatedCount 返回表的估算行数，适合大表上可以接受近似值的展示场景
+// 查询没有 WHERE/JOIN/GROUP BY 等条件时读取 information_schema.TABLES 的 TABLE_ROWS，
+// 该值来自 InnoDB 的统计信息，可能与实际行数相差 40% 以上；
+// 有任何条件（包括租户条件）或统计信息不可用时退回精确的 Count
+func (q *Queryable[T]) EstimatedCount() (_ int64, err error) {
+	defer wrapQueryError("EstimatedCount", q.table, &err)
+	return q.EstimatedCountTx(q.baseContext())
+}
+
+func (q *Queryable[T]) EstimatedCountTx(ctx context.Context) (_ int64, err error) {
+	defer wrapQueryError("EstimatedCountTx", q.table, &err)
+	if !q.isWholeTable() {
+		return q.CountTx(ctx)
+	}
+
+	// db.table 形式的表名按库名匹配，否则使用当前库
+	var schema interface{} = goqu.L("DATABASE()")
+	table := q.table
+	if i := strings.Index(table, "."); i >= 0 {
+		schema, table = table[:i], table[i+1:]
+	}
+	query, args, err := goqu.Dialect("mysql").
+		From(goqu.S("information_schema").Table("TABLES")).
+		Select("TABLE_ROWS").
+		Where(goqu.Ex{"TABLE_SCHEMA": schema, "TABLE_NAME": table}).
+		ToSQL()
+	if err != nil {
+		return 0, err
+	}
+	var rows sql.NullInt64
+	err = q.get(ctx, &rows, query, args...)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !rows.Valid) {
+		return q.CountTx(ctx)
+	}
+	return rows.Int64, err
+}
+
+// isWholeTable 判断查询是否覆盖整张表，即没有过滤、连接、分组和分页
+func (q *Queryable[T]) isWholeTable() bool {
+	clauses := q.query.GetClauses()
+	return q.table != "" &&
+		clauses.Where() == nil &&
+		clauses.Joins() == nil &&
+		clauses.GroupBy() == nil &&
+		clauses.Having() == nil &&
+		clauses.Limit() == nil &&
+		clauses.Offset() == 0
+}
+
+// countQuery 返回统计全部匹配行数的查询，去掉链中已有的 ORDER BY、LIMIT 和 OFFSET
+func (q *Queryable[T]) countQuery() *goqu.SelectDataset {
+	return q.query.ClearOrder().ClearLimit().ClearOffset().Select(goqu.COUNT("*"))
+}
+
+func (q *Queryable[T]) Count() (_ int64, err error) {
+	defer wrapQueryError("Count", q.table, &err)
+	query, args, err := q.countQuery().ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var count int64
-	err = q.db.Get(&count, query, args...)
+	err = q.get(q.baseContext(), &count, query, args...)
 	return count, err
 }
 
-func (q *Queryable[T]) ToGroupedList() ([]*T, error) {
+func (q *Queryable[T]) ToGroupedList() (_ []*T, err error) {
+	defer wrapQueryError("ToGroupedList", q.table, &err)
 	// 🔥 优化：确保使用结构体字段
+	q = q.clone()
 	q.ensureSelectFields()
 
 	query, args, err := q.query.ToSQL()
@@ -318,43 +896,238 @@ func (q *Queryable[T]) ToGroupedList() ([]*T, error) {
 		return nil, err
 	}
 	var results []*T
-	err = q.db.Select(&results, query, args...)
+	err = q.selectContext(q.baseContext(), &results, query, args...)
 	return results, err
 }
-func (q *Queryable[T]) Any(condition goqu.Ex) (bool, error) {
+func (q *Queryable[T]) Any(condition goqu.Ex) (_ bool, err error) {
+	defer wrapQueryError("Any", q.table, &err)
 	count, err := q.Where(condition).Count()
 	return count > 0, err
 }
 
-func (q *Queryable[T]) Sum(field string) (float64, error) {
+// All 判断范围 within 内的所有行是否都满足 predicate
+// 实现为 NOT EXISTS(within AND NOT COALESCE(predicate, FALSE))，范围内没有数据时返回 true
+// predicate 结果为 NULL 的行（如比较的列为 NULL）视为不满足
+func (q *Queryable[T]) All(within goqu.Ex, predicate goqu.Ex) (_ bool, err error) {
+	defer wrapQueryError("All", q.table, &err)
+	return q.AllTx(q.baseContext(), within, predicate)
+}
+
+// AllTx 带 context 的 All
+func (q *Queryable[T]) AllTx(ctx context.Context, within goqu.Ex, predicate goqu.Ex) (_ bool, err error) {
+	defer wrapQueryError("AllTx", q.table, &err)
+	if len(predicate) == 0 {
+		return false, fmt.Errorf("predicate must not be empty")
+	}
+	inner := q.query
+	if len(within) > 0 {
+		inner = inner.Where(within)
+	}
+	innerSQL, args, err := inner.Where(goqu.L("NOT COALESCE(?, FALSE)", predicate)).Select(goqu.L("1")).ToSQL()
+	if err != nil {
+		return false, err
+	}
+	var all bool
+	err = q.get(ctx, &all, "SELECT NOT EXISTS("+innerSQL+")", args...)
+	return all, err
+}
+
+// None 判断是否没有满足条件的行，是 Any 的否定
+func (q *Queryable[T]) None(condition goqu.Ex) (_ bool, err error) {
+	defer wrapQueryError("None", q.table, &err)
+	exists, err := q.Any(condition)
+	return !exists && err == nil, err
+}
+
+// NoneTx 带 context 的 None
+func (q *Queryable[T]) NoneTx(ctx context.Context, condition goqu.Ex) (_ bool, err error) {
+	defer wrapQueryError("NoneTx", q.table, &err)
+	exists, err := q.AnyTx(ctx, condition)
+	return !exists && err == nil, err
+}
+
+func (q *Queryable[T]) Sum(field string) (_ float64, err error) {
+	defer wrapQueryError("Sum", q.table, &err)
 	sumExpr := goqu.L("IFNULL(SUM(?), 0)", goqu.I(field))
 	query, args, err := q.query.Select(sumExpr).ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var sum float64
-	err = q.db.Get(&sum, query, args...)
+	err = q.get(q.baseContext(), &sum, query, args...)
 	return sum, err
 }
-func (q *Queryable[T]) Max(field string) (interface{}, error) {
+
+// SumPtr 返回字段的和，没有匹配的行（SUM 为 NULL）时返回 nil，用于区分"和为 0"与"没有数据"
+func (q *Queryable[T]) SumPtr(field string) (_ *float64, err error) {
+	defer wrapQueryError("SumPtr", q.table, &err)
+	return q.float64Ptr(goqu.SUM(field))
+}
+
+// AvgPtr 返回字段的平均值，没有匹配的行（AVG 为 NULL）时返回 nil
+func (q *Queryable[T]) AvgPtr(field string) (_ *float64, err error) {
+	defer wrapQueryError("AvgPtr", q.table, &err)
+	return q.float64Ptr(goqu.AVG(field))
+}
+
+func (q *Queryable[T]) float64Ptr(expr interface{}) (*float64, error) {
+	value, found, err := q.float64Scalar(expr)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// SumExpr 对任意 SQL 表达式求和，如 SumExpr("price * quantity")，NULL 结果返回 0
+func (q *Queryable[T]) SumExpr(expr string, args ...interface{}) (_ float64, err error) {
+	defer wrapQueryError("SumExpr", q.table, &err)
+	return q.SumExprTx(q.baseContext(), expr, args...)
+}
+
+// SumExprTx 带 context 的 SumExpr
+func (q *Queryable[T]) SumExprTx(ctx context.Context, expr string, args ...interface{}) (_ float64, err error) {
+	defer wrapQueryError("SumExprTx", q.table, &err)
+	return q.aggregateExpr(ctx, "SUM", expr, args...)
+}
+
+// AvgExpr 对任意 SQL 表达式求平均值，如 AvgExpr("price * quantity")，NULL 结果返回 0
+func (q *Queryable[T]) AvgExpr(expr string, args ...interface{}) (_ float64, err error) {
+	defer wrapQueryError("AvgExpr", q.table, &err)
+	return q.AvgExprTx(q.baseContext(), expr, args...)
+}
+
+// AvgExprTx 带 context 的 AvgExpr
+func (q *Queryable[T]) AvgExprTx(ctx context.Context, expr string, args ...interface{}) (_ float64, err error) {
+	defer wrapQueryError("AvgExprTx", q.table, &err)
+	return q.aggregateExpr(ctx, "AVG", expr, args...)
+}
+
+// aggregateExpr 使用聚合函数包裹原始表达式并执行查询
+func (q *Queryable[T]) aggregateExpr(ctx context.Context, function, expr string, args ...interface{}) (float64, error) {
+	if strings.TrimSpace(expr) == "" {
+		return 0, fmt.Errorf("%s expression must not be empty", function)
+	}
+	aggExpr := goqu.L(fmt.Sprintf("IFNULL(%s(?), 0)", function), goqu.L(expr, args...))
+	query, args, err := q.query.Select(aggExpr).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+	var result float64
+	err = q.get(ctx, &result, query, args...)
+	return result, err
+}
+
+// Aggregates 在一次查询中计算多个聚合值，如 SELECT COUNT(*), SUM(a), MIN(b), MAX(c)
+// 返回以别名为键的结果，未设置别名时键为 "函数_字段"（COUNT(*) 为 "count"），NULL 结果按 0 处理
+func (q *Queryable[T]) Aggregates(specs []AggregateInfo) (_ map[string]float64, err error) {
+	defer wrapQueryError("Aggregates", q.table, &err)
+	results := make(map[string]float64, len(specs))
+	if len(specs) == 0 {
+		return results, nil
+	}
+
+	selects := make([]interface{}, 0, len(specs))
+	keys := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if err := spec.validate(); err != nil {
+			return nil, err
+		}
+		spec.Alias = spec.resultKey()
+		expr := spec.expression()
+		if expr == nil {
+			return nil, fmt.Errorf("unsupported aggregate function %q", spec.Function)
+		}
+		selects = append(selects, expr)
+		keys = append(keys, spec.Alias)
+	}
+
+	query, args, err := q.query.Select(selects...).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.db.ready(); err != nil {
+		return nil, err
+	}
+
+	values := make([]sql.NullFloat64, len(keys))
+	dest := make([]interface{}, len(keys))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	ctx, cancel := q.statementContext(q.baseContext())
+	defer cancel()
+	if err := q.db.QueryRowxContext(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		results[key] = values[i].Float64
+	}
+	return results, nil
+}
+
+func (q *Queryable[T]) Max(field string) (_ interface{}, err error) {
+	defer wrapQueryError("Max", q.table, &err)
 	query, args, err := q.query.Select(goqu.MAX(field)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
-	var max interface{}
-	err = q.db.Get(&max, query, args...)
-	return max, err
+	var max interface{}
+	err = q.get(q.baseContext(), &max, query, args...)
+	return max, err
+}
+
+// MaxBy 返回 field 最大的整行记录（argmax），field 为 NULL 的行不参与比较，值相同时取主键最小的行
+// 没有记录时返回 ErrNotFound
+func (q *Queryable[T]) MaxBy(field string) (_ *T, err error) {
+	defer wrapQueryError("MaxBy", q.table, &err)
+	return q.extremeBy(field, goqu.I(field).Desc())
+}
+
+// MinBy 返回 field 最小的整行记录（argmin），规则同 MaxBy
+func (q *Queryable[T]) MinBy(field string) (_ *T, err error) {
+	defer wrapQueryError("MinBy", q.table, &err)
+	return q.extremeBy(field, goqu.I(field).Asc())
+}
+
+// extremeBy 在查询的副本上按 order 排序后取第一行，不修改接收者
+func (q *Queryable[T]) extremeBy(field string, order exp.OrderedExpression) (*T, error) {
+	q = q.clone()
+	q.ensureSelectFields()
+	q.query = q.query.
+		Where(goqu.I(field).IsNotNull()).
+		Order(order, goqu.I(primaryKeyColumn[T]()).Asc()).
+		Limit(1)
+	q.hasWhere, q.hasOrder, q.hasLimit = true, true, true
+
+	query, args, err := q.query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	var result T
+	err = q.get(q.baseContext(), &result, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
 
 // 在 Queryable 中添加
-func (q *Queryable[T]) ToPagedList(page, size int, condition goqu.Ex) (*PageResult[T], error) {
+func (q *Queryable[T]) ToPagedList(page, size int, condition goqu.Ex) (_ *PageResult[T], err error) {
+	defer wrapQueryError("ToPagedList", q.table, &err)
+	q = q.clone()
+	q.ensurePageOrder()
 	offset := (page - 1) * size
-	items, err := q.Where(condition).Skip(offset).Take(size).ToList()
+	filtered := q.Where(condition)
+	items, err := filtered.Skip(offset).Take(size).ToList()
 	if err != nil {
 		return nil, err
 	}
 
-	total, err := q.Count()
+	total, err := filtered.Count()
 	if err != nil {
 		return nil, err
 	}
@@ -366,47 +1139,56 @@ func (q *Queryable[T]) ToPagedList(page, size int, condition goqu.Ex) (*PageResu
 	}, nil
 }
 
-// getSelectColumns 根据结构体的 db tag 自动生成 SELECT 列
-func getSelectColumns[T any]() []interface{} {
-	var entity T
-	t := reflect.TypeOf(entity)
+// Paginate 取第 page 页（从 1 开始）的数据及分页信息，在当前查询的副本上执行一次计数和一次数据查询
+// page 小于 1 时按第 1 页处理，size 小于 1 时按 1 处理；没有 ORDER BY 时按主键排序，超出末页时 Items 为空
+func (q *Queryable[T]) Paginate(page, size int) (_ *Page[T], err error) {
+	defer wrapQueryError("Paginate", q.table, &err)
+	return q.PaginateTx(q.baseContext(), page, size)
+}
 
-	// 处理指针类型
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+func (q *Queryable[T]) PaginateTx(ctx context.Context, page, size int) (_ *Page[T], err error) {
+	defer wrapQueryError("PaginateTx", q.table, &err)
+	if page < 1 {
+		page = 1
 	}
-
-	var columns []interface{}
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		dbTag := field.Tag.Get("db")
-
-		// 跳过没有 db tag 或标记为 "-" 的字段
-		if dbTag == "" || dbTag == "-" {
-			continue
-		}
-
-		// 使用 db tag 的值作为列名
-		columns = append(columns, goqu.I(dbTag))
+	if size < 1 {
+		size = 1
 	}
+	base := q.clone()
+	base.ensurePageOrder()
 
-	// 如果没有找到任何列，返回 * 作为兜底
-	if len(columns) == 0 {
-		return []interface{}{goqu.Star()}
+	total, err := base.CountTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := base.Skip((page - 1) * size).Take(size).ToListTx(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return columns
+	totalPages := int((total + int64(size) - 1) / int64(size))
+	return &Page[T]{
+		Items:      items,
+		Total:      total,
+		Page:       page,
+		PageSize:   size,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}, nil
 }
 
 // Note: Query() method is now defined in repository.go
 
 // Select(cols ...interface{}) IQueryable[T]
 func (q *Queryable[T]) Select(cols ...interface{}) IQueryable[T] {
+	q = q.clone()
 	q.query = q.query.Select(cols...)
 	return q
 }
 
 func (q *Queryable[T]) SelectRaw(cols ...string) IQueryable[T] {
+	q = q.clone()
 	expressions := make([]interface{}, len(cols))
 	for i, col := range cols {
 		expressions[i] = goqu.L(col)
@@ -415,17 +1197,173 @@ func (q *Queryable[T]) SelectRaw(cols ...string) IQueryable[T] {
 	return q
 }
 
+// SelectFields 按 db tag 名称选择实体的部分字段
+// 字段名通过反射校验，未知字段的错误会记录在查询上，由执行方法返回
+func (q *Queryable[T]) SelectFields(fields ...string) IQueryable[T] {
+	q = q.clone()
+	known := make(map[string]bool)
+	for _, field := range q.getStructDBFields() {
+		known[field.(string)] = true
+	}
+
+	columns := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		if !known[field] {
+			var entity T
+			q.query = q.query.SetError(fmt.Errorf("unknown field %q on %T", field, entity))
+			return q
+		}
+		columns = append(columns, goqu.I(field))
+	}
+	q.query = q.query.Select(columns...)
+	return q
+}
+
+// SelectQualified 追加 table.col 形式的投影，用于 Join 后区分同名列，列名可写成 "id AS user_id" 指定别名
+// 如 SelectQualified("users", "id AS user_id", "name") 生成 `users`.`id` AS `user_id`, `users`.`name`，"*" 表示该表的所有列
+// 已有的 SELECT 列保持不变；未指定 Select 时替换默认的 SELECT *
+func (q *Queryable[T]) SelectQualified(table string, cols ...string) IQueryable[T] {
+	q = q.clone()
+	columns := make([]interface{}, len(cols))
+	for i, col := range cols {
+		name, alias := splitColumnAlias(col)
+		if name == "*" {
+			columns[i] = goqu.T(table).All()
+			continue
+		}
+		column := goqu.T(table).Col(name)
+		if alias != "" {
+			columns[i] = column.As(alias)
+		} else {
+			columns[i] = column
+		}
+	}
+	if q.query.GetClauses().IsDefaultSelect() {
+		q.query = q.query.Select(columns...)
+	} else {
+		q.query = q.query.SelectAppend(columns...)
+	}
+	return q
+}
+
+// splitColumnAlias 拆分 "col AS alias"，AS 不区分大小写
+func splitColumnAlias(col string) (name, alias string) {
+	fields := strings.Fields(col)
+	if len(fields) == 3 && strings.EqualFold(fields[1], "as") {
+		return fields[0], fields[2]
+	}
+	return strings.TrimSpace(col), ""
+}
+
+// SelectCoalesce 追加 COALESCE(column, fallback) AS alias 投影，列为 NULL 时返回 fallback，
+// 可以扫描到非指针字段，例如 SelectCoalesce("nickname", "", "nickname")
+// 已有的 SELECT 列保持不变；未指定 Select 时保留实体字段，与 alias 同名的字段由该投影代替
+func (q *Queryable[T]) SelectCoalesce(column string, fallback interface{}, alias string) IQueryable[T] {
+	q = q.clone()
+	q.ensureSelectFields(alias)
+	q.query = q.query.SelectAppend(goqu.COALESCE(goqu.I(column), fallback).As(alias))
+	return q
+}
+
 // Min(field string) (interface{}, error)
-func (q *Queryable[T]) Min(field string) (interface{}, error) {
+func (q *Queryable[T]) Min(field string) (_ interface{}, err error) {
+	defer wrapQueryError("Min", q.table, &err)
 	query, args, err := q.query.Select(goqu.MIN(field)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var min interface{}
-	err = q.db.Get(&min, query, args...)
+	err = q.get(q.baseContext(), &min, query, args...)
 	return min, err
 }
 
+// scanScalar 执行单列聚合查询并扫描到 dest，结果集为空时返回 false
+func (q *Queryable[T]) scanScalar(expr interface{}, dest interface{}) (bool, error) {
+	query, args, err := q.query.Select(expr).ToSQL()
+	if err != nil {
+		return false, err
+	}
+	if err := q.get(q.baseContext(), dest, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (q *Queryable[T]) int64Scalar(expr interface{}) (int64, bool, error) {
+	var v sql.NullInt64
+	found, err := q.scanScalar(expr, &v)
+	return v.Int64, found && v.Valid, err
+}
+
+func (q *Queryable[T]) float64Scalar(expr interface{}) (float64, bool, error) {
+	var v sql.NullFloat64
+	found, err := q.scanScalar(expr, &v)
+	return v.Float64, found && v.Valid, err
+}
+
+func (q *Queryable[T]) stringScalar(expr interface{}) (string, bool, error) {
+	var v sql.NullString
+	found, err := q.scanScalar(expr, &v)
+	return v.String, found && v.Valid, err
+}
+
+func (q *Queryable[T]) timeScalar(expr interface{}) (time.Time, bool, error) {
+	var v sql.NullTime
+	found, err := q.scanScalar(expr, &v)
+	return v.Time, found && v.Valid, err
+}
+
+// MaxInt64 返回字段最大值，结果为 NULL（如空集合）时 found 为 false
+func (q *Queryable[T]) MaxInt64(field string) (value int64, found bool, err error) {
+	defer wrapQueryError("MaxInt64", q.table, &err)
+	return q.int64Scalar(goqu.MAX(field))
+}
+
+// MaxFloat64 返回字段最大值，结果为 NULL（如空集合）时 found 为 false
+func (q *Queryable[T]) MaxFloat64(field string) (value float64, found bool, err error) {
+	defer wrapQueryError("MaxFloat64", q.table, &err)
+	return q.float64Scalar(goqu.MAX(field))
+}
+
+// MaxString 返回字段最大值，结果为 NULL（如空集合）时 found 为 false
+func (q *Queryable[T]) MaxString(field string) (value string, found bool, err error) {
+	defer wrapQueryError("MaxString", q.table, &err)
+	return q.stringScalar(goqu.MAX(field))
+}
+
+// MaxTime 返回字段最大值，MySQL 连接需开启 parseTime=true
+func (q *Queryable[T]) MaxTime(field string) (value time.Time, found bool, err error) {
+	defer wrapQueryError("MaxTime", q.table, &err)
+	return q.timeScalar(goqu.MAX(field))
+}
+
+// MinInt64 返回字段最小值，结果为 NULL（如空集合）时 found 为 false
+func (q *Queryable[T]) MinInt64(field string) (value int64, found bool, err error) {
+	defer wrapQueryError("MinInt64", q.table, &err)
+	return q.int64Scalar(goqu.MIN(field))
+}
+
+// MinFloat64 返回字段最小值，结果为 NULL（如空集合）时 found 为 false
+func (q *Queryable[T]) MinFloat64(field string) (value float64, found bool, err error) {
+	defer wrapQueryError("MinFloat64", q.table, &err)
+	return q.float64Scalar(goqu.MIN(field))
+}
+
+// MinString 返回字段最小值，结果为 NULL（如空集合）时 found 为 false
+func (q *Queryable[T]) MinString(field string) (value string, found bool, err error) {
+	defer wrapQueryError("MinString", q.table, &err)
+	return q.stringScalar(goqu.MIN(field))
+}
+
+// MinTime 返回字段最小值，MySQL 连接需开启 parseTime=true
+func (q *Queryable[T]) MinTime(field string) (value time.Time, found bool, err error) {
+	defer wrapQueryError("MinTime", q.table, &err)
+	return q.timeScalar(goqu.MIN(field))
+}
+
 // //泛型方法 转slice
 // ToInt64Slice() ([]int64, error)
 // ToStringSlice() ([]string, error)
@@ -436,37 +1374,41 @@ func (q *Queryable[T]) Min(field string) (interface{}, error) {
 // ToStruct() (*T, error)
 
 // 在 Queryable 中添加
-func (q *Queryable[T]) ToInt64Slice() ([]int64, error) {
+func (q *Queryable[T]) ToInt64Slice() (_ []int64, err error) {
+	defer wrapQueryError("ToInt64Slice", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []int64
-	err = q.db.Select(&results, query, args...)
+	err = q.selectContext(q.baseContext(), &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToStringSlice() ([]string, error) {
+func (q *Queryable[T]) ToStringSlice() (_ []string, err error) {
+	defer wrapQueryError("ToStringSlice", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []string
-	err = q.db.Select(&results, query, args...)
+	err = q.selectContext(q.baseContext(), &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToFloat64Slice() ([]float64, error) {
+func (q *Queryable[T]) ToFloat64Slice() (_ []float64, err error) {
+	defer wrapQueryError("ToFloat64Slice", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var results []float64
-	err = q.db.Select(&results, query, args...)
+	err = q.selectContext(q.baseContext(), &results, query, args...)
 	return results, err
 }
 
-func (q *Queryable[T]) ToMapSlice() ([]map[string]interface{}, error) {
+func (q *Queryable[T]) ToMapSlice() (_ []map[string]interface{}, err error) {
+	defer wrapQueryError("ToMapSlice", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
@@ -474,7 +1416,7 @@ func (q *Queryable[T]) ToMapSlice() ([]map[string]interface{}, error) {
 
 	// 1. 先扫描到结构体切片
 	var items []*T
-	err = q.db.Select(&items, query, args...)
+	err = q.selectContext(q.baseContext(), &items, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -484,14 +1426,13 @@ func (q *Queryable[T]) ToMapSlice() ([]map[string]interface{}, error) {
 	for i, item := range items {
 		// 使用反射将结构体转为 map
 		v := reflect.ValueOf(item).Elem()
-		t := v.Type()
 		m := make(map[string]interface{})
 
-		for j := 0; j < t.NumField(); j++ {
-			field := t.Field(j)
-			// 获取 db tag
-			if tag := field.Tag.Get("db"); tag != "" {
-				m[tag] = v.Field(j).Interface()
+		for _, c := range entityColumns(v.Type(), q.nameMapper) {
+			if field := fieldByIndex(v, c.index); field.IsValid() {
+				m[c.name] = field.Interface()
+			} else {
+				m[c.name] = nil
 			}
 		}
 		results[i] = m
@@ -500,16 +1441,88 @@ func (q *Queryable[T]) ToMapSlice() ([]map[string]interface{}, error) {
 	return results, nil
 }
 
-func (q *Queryable[T]) ToMap() (map[string]interface{}, error) {
+// Rows 执行查询并返回原始结果集，用于 MapScan/SliceScan 等自定义扫描
+// 调用方负责关闭返回的 rows
+func (q *Queryable[T]) Rows() (_ *sqlx.Rows, err error) {
+	defer wrapQueryError("Rows", q.table, &err)
+	return q.RowsTx(q.baseContext())
+}
+
+// RowsTx 带 context 的 Rows，调用方负责关闭返回的 rows
+// rows 的生命周期由调用方控制，WithTimeout 不作用于 Rows/RowsTx，需要超时时请传入带截止时间的 ctx
+func (q *Queryable[T]) RowsTx(ctx context.Context) (_ *sqlx.Rows, err error) {
+	defer wrapQueryError("RowsTx", q.table, &err)
+	query, args, err := q.query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	return q.db.QueryxContext(ctx, query, args...)
+}
+
+// ToRawMapSlice 直接按查询的结果列扫描为 map 切片，不经过结构体转换
+// 适用于 SelectRaw 等包含计算列的任意投影，文本类列的 []byte 会转换为 string
+func (q *Queryable[T]) ToRawMapSlice() (_ []map[string]interface{}, err error) {
+	defer wrapQueryError("ToRawMapSlice", q.table, &err)
+	return q.ToRawMapSliceTx(q.baseContext())
+}
+
+// ToRawMapSliceTx 带 context 的 ToRawMapSlice
+func (q *Queryable[T]) ToRawMapSliceTx(ctx context.Context) (_ []map[string]interface{}, err error) {
+	defer wrapQueryError("ToRawMapSliceTx", q.table, &err)
+	query, args, err := q.query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, cancel, err := q.queryx(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		row := make(map[string]interface{}, len(types))
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for _, t := range types {
+			if b, ok := row[t.Name()].([]byte); ok && isTextColumnType(t.DatabaseTypeName()) {
+				row[t.Name()] = string(b)
+			}
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// isTextColumnType 判断数据库列类型是否按文本处理，未知类型也按文本处理
+func isTextColumnType(typeName string) bool {
+	switch strings.ToUpper(typeName) {
+	case "", "CHAR", "VARCHAR", "TEXT", "TINYTEXT", "MEDIUMTEXT", "LONGTEXT",
+		"ENUM", "SET", "JSON", "DECIMAL", "DATE", "DATETIME", "TIMESTAMP", "TIME", "YEAR":
+		return true
+	}
+	return false
+}
+
+func (q *Queryable[T]) ToMap() (_ map[string]interface{}, err error) {
+	defer wrapQueryError("ToMap", q.table, &err)
 	//用rows.Next() 的方式
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
-	rows, err := q.db.DB.Queryx(query, args...)
+	rows, cancel, err := q.queryx(q.baseContext(), query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer cancel()
 	defer rows.Close()
 	// 获取列名
 	columns, err := rows.Columns()
@@ -553,13 +1566,14 @@ func (q *Queryable[T]) ToMap() (map[string]interface{}, error) {
 	}
 	return result, nil
 }
-func (q *Queryable[T]) ToStruct() (*T, error) {
+func (q *Queryable[T]) ToStruct() (_ *T, err error) {
+	defer wrapQueryError("ToStruct", q.table, &err)
 	query, args, err := q.query.Limit(1).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var result T
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -568,26 +1582,30 @@ func (q *Queryable[T]) ToStruct() (*T, error) {
 
 // Limit(limit int) IQueryable[T]
 func (q *Queryable[T]) Limit(limit int) IQueryable[T] {
+	q = q.clone()
 	q.query = q.query.Limit(uint(limit))
+	q.hasLimit = limit > 0
 	return q
 }
 
 // 写一个 Scan(&maxSort) 的方法
-func (q *Queryable[T]) Scan(dest interface{}) error {
+func (q *Queryable[T]) Scan(dest interface{}) (err error) {
+	defer wrapQueryError("Scan", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return err
 	}
-	return q.db.Get(dest, query, args...)
+	return q.get(q.baseContext(), dest, query, args...)
 }
 
 // ScanTx(ctx context.Context, dest interface{}) error
-func (q *Queryable[T]) ScanTx(ctx context.Context, dest interface{}) error {
+func (q *Queryable[T]) ScanTx(ctx context.Context, dest interface{}) (err error) {
+	defer wrapQueryError("ScanTx", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return err
 	}
-	return q.db.GetContext(ctx, dest, query, args...)
+	return q.get(ctx, dest, query, args...)
 }
 
 /*
@@ -596,44 +1614,48 @@ ScanInt64() (int64, error)
 	ScanString() (string, error)
 	ScanInt() (int, error)
 */
-func (q *Queryable[T]) ScanInt64() (int64, error) {
+func (q *Queryable[T]) ScanInt64() (_ int64, err error) {
+	defer wrapQueryError("ScanInt64", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var result int64
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	return result, err
 }
 
-func (q *Queryable[T]) ScanString() (string, error) {
+func (q *Queryable[T]) ScanString() (_ string, err error) {
+	defer wrapQueryError("ScanString", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return "", err
 	}
 	var result string
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	return result, err
 }
 
-func (q *Queryable[T]) ScanInt() (int, error) {
+func (q *Queryable[T]) ScanInt() (_ int, err error) {
+	defer wrapQueryError("ScanInt", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var result int
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	return result, err
 }
 
 // ScanVal() (interface{}, error)
-func (q *Queryable[T]) ScanVal() (interface{}, error) {
+func (q *Queryable[T]) ScanVal() (_ interface{}, err error) {
+	defer wrapQueryError("ScanVal", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var result interface{}
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	return result, err
 }
 
@@ -644,7 +1666,7 @@ func (q *Queryable[T]) ToLookup(keySelector func(T) interface{}) map[interface{}
 		return nil
 	}
 	var results []*T
-	err = q.db.Select(&results, query, args...)
+	err = q.selectContext(q.baseContext(), &results, query, args...)
 	if err != nil {
 		return nil
 	}
@@ -657,7 +1679,10 @@ func (q *Queryable[T]) ToLookup(keySelector func(T) interface{}) map[interface{}
 }
 
 // ToPagedListWithTotal(page, size int, condition goqu.Ex) ([]*T, int64, error)
-func (q *Queryable[T]) ToPagedListWithTotal(page, size int, condition goqu.Ex) ([]*T, int64, error) {
+func (q *Queryable[T]) ToPagedListWithTotal(page, size int, condition goqu.Ex) (_ []*T, _ int64, err error) {
+	defer wrapQueryError("ToPagedListWithTotal", q.table, &err)
+	q = q.clone()
+	q.ensurePageOrder()
 	//先查询总数
 	total, err := q.Where(condition).Count()
 	if err != nil {
@@ -674,6 +1699,7 @@ func (q *Queryable[T]) ToPagedListWithTotal(page, size int, condition goqu.Ex) (
 
 // 支持窗口函数，如 ROW_NUMBER, RANK, DENSE_RANK 等
 func (q *Queryable[T]) Over(windowFunc string, partitionBy ...interface{}) IQueryable[T] {
+	q = q.clone()
 	w := goqu.W()
 	if len(partitionBy) > 0 {
 		w = w.PartitionBy(partitionBy...)
@@ -684,59 +1710,289 @@ func (q *Queryable[T]) Over(windowFunc string, partitionBy ...interface{}) IQuer
 	return q
 }
 
+// RowNumber 追加 ROW_NUMBER() OVER (PARTITION BY ... ORDER BY ...) AS alias 到查询列
+// orderBy 可包含方向和多列，如 "created_at DESC, id"
+func (q *Queryable[T]) RowNumber(partitionBy []string, orderBy string, alias string) IQueryable[T] {
+	return q.rankingWindow("ROW_NUMBER", partitionBy, orderBy, alias)
+}
+
+// Rank 追加 RANK() 窗口列，相同排序值的行排名相同，之后的排名会跳过
+func (q *Queryable[T]) Rank(partitionBy []string, orderBy string, alias string) IQueryable[T] {
+	return q.rankingWindow("RANK", partitionBy, orderBy, alias)
+}
+
+// DenseRank 追加 DENSE_RANK() 窗口列，相同排序值的行排名相同，之后的排名连续
+func (q *Queryable[T]) DenseRank(partitionBy []string, orderBy string, alias string) IQueryable[T] {
+	return q.rankingWindow("DENSE_RANK", partitionBy, orderBy, alias)
+}
+
+// WithTotalCount 追加 COUNT(*) OVER () AS alias，每一行都带上结果集总行数，可用于计算占比
+func (q *Queryable[T]) WithTotalCount(alias string) IQueryable[T] {
+	q = q.clone()
+	q.ensureSelectFields()
+	q.query = q.query.SelectAppend(goqu.L("COUNT(*) OVER ()").As(alias))
+	return q
+}
+
+// rankingWindow 构造排名窗口函数并追加到查询列，未指定 Select 时保留实体字段
+func (q *Queryable[T]) rankingWindow(fn string, partitionBy []string, orderBy string, alias string) IQueryable[T] {
+	q = q.clone()
+	window, err := windowExpression(fn, partitionBy, orderBy)
+	if err != nil {
+		q.query = q.query.SetError(err)
+		return q
+	}
+	q.ensureSelectFields()
+	q.query = q.query.SelectAppend(window.As(alias))
+	return q
+}
+
+// windowExpression 构造 FN() OVER (PARTITION BY ... ORDER BY ...)，orderBy 可带方向和多列
+func windowExpression(fn string, partitionBy []string, orderBy string) (exp.LiteralExpression, error) {
+	var clauses []string
+	var args []interface{}
+
+	if len(partitionBy) > 0 {
+		placeholders := make([]string, len(partitionBy))
+		for i, col := range partitionBy {
+			placeholders[i] = "?"
+			args = append(args, goqu.I(col))
+		}
+		clauses = append(clauses, "PARTITION BY "+strings.Join(placeholders, ", "))
+	}
+
+	if orderBy != "" {
+		var items []string
+		for _, term := range strings.Split(orderBy, ",") {
+			parts := strings.Fields(term)
+			if len(parts) == 0 || len(parts) > 2 {
+				return nil, fmt.Errorf("invalid window order clause %q", orderBy)
+			}
+			item := "?"
+			args = append(args, goqu.I(parts[0]))
+			if len(parts) == 2 {
+				dir := strings.ToUpper(parts[1])
+				if dir != "ASC" && dir != "DESC" {
+					return nil, fmt.Errorf("invalid order direction %q in window order clause", parts[1])
+				}
+				item += " " + dir
+			}
+			items = append(items, item)
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(items, ", "))
+	}
+
+	return goqu.L(fn+"() OVER ("+strings.Join(clauses, " ")+")", args...), nil
+}
+
+// distinctRowNumberColumn DistinctBy 子查询中行号列的名称
+const distinctRowNumberColumn = "_rn"
+
+// DistinctBy 每个分组只保留排序后的第一行，相当于 PostgreSQL 的 DISTINCT ON
+// 通过 ROW_NUMBER() 子查询实现，例如每个用户最新的一条订单:
+//
+//	repo.Query().DistinctBy([]string{"user_id"}, "created_at DESC")
+//
+// 之前的条件作用于子查询，之后的 Where/OrderBy/Take 作用于去重后的结果
+// 外层查询选择实体的字段，已有的 Select 需要包含这些字段
+func (q *Queryable[T]) DistinctBy(partitionCols []string, orderBy string) IQueryable[T] {
+	q = q.clone()
+	if len(partitionCols) == 0 {
+		q.query = q.query.SetError(fmt.Errorf("DistinctBy requires at least one partition column"))
+		return q
+	}
+	window, err := windowExpression("ROW_NUMBER", partitionCols, orderBy)
+	if err != nil {
+		q.query = q.query.SetError(err)
+		return q
+	}
+
+	q.ensureSelectFields()
+	inner := q.query.SelectAppend(window.As(distinctRowNumberColumn))
+	q.alias = ""
+	// 外层查询沿用原查询的方言，保持标识符转义一致
+	q.query = goqu.Dialect(q.query.Dialect().Dialect()).
+		From(inner.As("d")).
+		Select(q.getStructDBFields()...).
+		Where(goqu.I(distinctRowNumberColumn).Eq(1))
+	return q
+}
+
 func (q *Queryable[T]) GroupByHaving(having goqu.Ex) IQueryable[T] {
+	q = q.clone()
 	q.query = q.query.Having(having)
 	return q
 }
 
-func (q *Queryable[T]) ToResult(result interface{}) error {
+func (q *Queryable[T]) ToResult(result interface{}) (err error) {
+	defer wrapQueryError("ToResult", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return err
 	}
-	return q.db.Select(result, query, args...)
+	return q.selectContext(q.baseContext(), result, query, args...)
 }
 
-func (q *Queryable[T]) Join(table string, on map[string]string) IQueryable[T] {
+// ScanListAs 将查询结果（通常是连表后的投影）扫描到独立的结果类型 R 中
+// 选择的列名需要与 R 的 db tag 一致，连表时可通过别名对齐，例如:
+//
+//	type UserOrder struct {
+//	    UserName   string  `db:"user_name"`
+//	    OrderTotal float64 `db:"order_total"`
+//	}
+//
+//	var rows []*UserOrder
+//	err := ScanListAs(repo.Query().
+//	    InnerJoin("orders", map[string]string{"orders.user_id": "users.id"}).
+//	    Select(goqu.I("users.name").As("user_name"), goqu.I("orders.total").As("order_total")), &rows)
+func ScanListAs[T any, R any](q IQueryable[T], dest *[]*R) error {
+	if dest == nil {
+		return fmt.Errorf("dest must not be nil")
+	}
+	return q.ToResult(dest)
+}
+
+// ScanListAsTx 带 context 的 ScanListAs
+func ScanListAsTx[T any, R any](ctx context.Context, q IQueryable[T], dest *[]*R) error {
+	if dest == nil {
+		return fmt.Errorf("dest must not be nil")
+	}
+	return q.ToResultTx(ctx, dest)
+}
+
+// ScanSlice 将单列查询结果扫描为 []R，R 可以是任何可扫描的类型，如 int64、bool、time.Time 或实现了 sql.Scanner 的自定义类型
+// 查询返回多列时报错，例如:
+//
+//	days, err := ScanSlice[Order, time.Time](repo.Query().Select("created_at").Where(goqu.Ex{"user_id": 1}))
+func ScanSlice[T any, R any](q IQueryable[T]) ([]R, error) {
+	var results []R
+	if err := q.ToResult(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ScanSliceTx 带 context 的 ScanSlice
+func ScanSliceTx[T any, R any](ctx context.Context, q IQueryable[T]) ([]R, error) {
+	var results []R
+	if err := q.ToResultTx(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Select2 只查询指定的列并扫描到调用方提供的结果类型 R，列名需要与 R 的 db tag 一致
+//
+//	type UserBrief struct {
+//	    ID   int64  `db:"id"`
+//	    Name string `db:"name"`
+//	}
+//
+//	var rows []UserBrief
+//	err := Select2(repo.Query().Where(goqu.Ex{"status": 1}), []string{"id", "name"}, &rows)
+func Select2[T any, R any](q IQueryable[T], cols []string, dest *[]R) error {
+	if dest == nil {
+		return fmt.Errorf("dest must not be nil")
+	}
+	if len(cols) == 0 {
+		return fmt.Errorf("cols must not be empty")
+	}
+	selects := make([]interface{}, len(cols))
+	for i, col := range cols {
+		selects[i] = col
+	}
+	return q.Select(selects...).ToResult(dest)
+}
+
+// ToMapByKey 执行查询并按 keyFn 返回的键建立一对一索引，例如按主键:
+//
+//	users, err := ToMapByKey(repo.Query().Where(goqu.Ex{"id": ids}), func(u *User) int64 { return u.ID })
+//
+// 出现重复键时返回错误
+func ToMapByKey[T any, K comparable](q IQueryable[T], keyFn func(*T) K) (map[K]*T, error) {
+	items, err := q.ToList()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[K]*T, len(items))
+	for _, item := range items {
+		key := keyFn(item)
+		if _, exists := result[key]; exists {
+			return nil, fmt.Errorf("duplicate key %v", key)
+		}
+		result[key] = item
+	}
+	return result, nil
+}
+
+// joinCondition 将 map 形式的连接条件转换为等值连接表达式
+func joinCondition(on map[string]string) goqu.Ex {
 	conditions := make(goqu.Ex)
 	for sourceKey, targetKey := range on {
 		conditions[sourceKey] = goqu.I(targetKey)
 	}
-	q.query = q.query.LeftJoin(goqu.T(table), goqu.On(conditions))
+	return conditions
+}
+
+// Join 内连接，等同于 InnerJoin
+func (q *Queryable[T]) Join(table string, on map[string]string) IQueryable[T] {
+	return q.InnerJoin(table, on)
+}
+
+// JoinOn 使用任意连接条件的内连接，支持字面量条件
+// 例如: JoinOn("orders", goqu.And(goqu.I("orders.user_id").Eq(goqu.I("users.id")), goqu.I("orders.status").Eq(1)))
+func (q *Queryable[T]) JoinOn(table string, on goqu.Expression) IQueryable[T] {
+	q = q.clone()
+	q.query = q.query.InnerJoin(goqu.T(table), goqu.On(on))
+	return q
+}
+
+// JoinTable 使用表达式作为连接表的内连接，可传入带别名的表，如 goqu.T("employees").As("m")
+func (q *Queryable[T]) JoinTable(table exp.Expression, on goqu.Expression) IQueryable[T] {
+	q = q.clone()
+	q.query = q.query.InnerJoin(table, goqu.On(on))
+	return q
+}
+
+// LeftJoinTable 使用表达式作为连接表的左连接，可传入带别名的表
+func (q *Queryable[T]) LeftJoinTable(table exp.Expression, on goqu.Expression) IQueryable[T] {
+	q = q.clone()
+	q.query = q.query.LeftJoin(table, goqu.On(on))
 	return q
 }
 
 func (q *Queryable[T]) LeftJoin(table string, on map[string]string) IQueryable[T] {
-	conditions := make(goqu.Ex)
-	for sourceKey, targetKey := range on {
-		conditions[sourceKey] = goqu.I(targetKey)
-	}
-	q.query = q.query.LeftJoin(goqu.T(table), goqu.On(conditions))
+	q = q.clone()
+	q.query = q.query.LeftJoin(goqu.T(table), goqu.On(joinCondition(on)))
 	return q
 }
 
 // RightJoin 实现
 func (q *Queryable[T]) RightJoin(table string, on map[string]string) IQueryable[T] {
-	conditions := make(goqu.Ex)
-	for sourceKey, targetKey := range on {
-		conditions[sourceKey] = goqu.I(targetKey)
-	}
-	q.query = q.query.RightJoin(goqu.T(table), goqu.On(conditions))
+	q = q.clone()
+	q.query = q.query.RightJoin(goqu.T(table), goqu.On(joinCondition(on)))
 	return q
 }
 
 // InnerJoin 实现
 func (q *Queryable[T]) InnerJoin(table string, on map[string]string) IQueryable[T] {
-	conditions := make(goqu.Ex)
-	for sourceKey, targetKey := range on {
-		conditions[sourceKey] = goqu.I(targetKey)
-	}
-	q.query = q.query.InnerJoin(goqu.T(table), goqu.On(conditions))
+	q = q.clone()
+	q.query = q.query.InnerJoin(goqu.T(table), goqu.On(joinCondition(on)))
+	return q
+}
+
+// FullJoin 全外连接（MySQL 不支持 FULL JOIN，StarRocks 等支持）
+func (q *Queryable[T]) FullJoin(table string, on map[string]string) IQueryable[T] {
+	q = q.clone()
+	q.query = q.query.FullJoin(goqu.T(table), goqu.On(joinCondition(on)))
 	return q
 }
 
 // Queryable 实现
-func (q *Queryable[T]) ToPagedResult(page, pageSize int, dest interface{}) (*PagedResult, error) {
+func (q *Queryable[T]) ToPagedResult(page, pageSize int, dest interface{}) (_ *PagedResult, err error) {
+	defer wrapQueryError("ToPagedResult", q.table, &err)
+	q = q.clone()
+	q.ensurePageOrder()
 	// 1. 获取总记录数
 	total, err := q.Count()
 	if err != nil {
@@ -756,7 +2012,7 @@ func (q *Queryable[T]) ToPagedResult(page, pageSize int, dest interface{}) (*Pag
 	}
 
 	// 4. 执行查询并填充结果
-	err = q.db.Select(dest, sql, args...)
+	err = q.selectContext(q.baseContext(), dest, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
@@ -777,6 +2033,61 @@ type AggregateResult struct {
 	Count  int64                  // 该组的记录数
 }
 
+// GroupCount 多列分组计数的一行结果
+type GroupCount struct {
+	Groups map[string]interface{} // 分组列的值
+	Count  int64                  // 该组的记录数
+}
+
+// CountByColumns 按多列分组计数，结果按数量降序排列，如按类目统计订单量
+func (q *Queryable[T]) CountByColumns(cols ...string) (_ []GroupCount, err error) {
+	defer wrapQueryError("CountByColumns", q.table, &err)
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("CountByColumns requires at least one column")
+	}
+	selects := make([]interface{}, 0, len(cols)+1)
+	for _, col := range cols {
+		selects = append(selects, goqu.I(col))
+	}
+	selects = append(selects, goqu.COUNT("*").As("count"))
+
+	grouped := q.GroupByColumns(cols...).(*Queryable[T])
+	grouped.query = grouped.query.Select(selects...).Order(goqu.I("count").Desc())
+	rows, err := grouped.ToRawMapSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GroupCount, 0, len(rows))
+	for _, row := range rows {
+		count, err := toInt64(row["count"])
+		if err != nil {
+			return nil, err
+		}
+		groups := make(map[string]interface{}, len(cols))
+		for _, col := range cols {
+			groups[col] = row[col]
+		}
+		results = append(results, GroupCount{Groups: groups, Count: count})
+	}
+	return results, nil
+}
+
+// toInt64 转换驱动返回的整数值，MySQL 文本协议下可能是 []byte
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case []byte:
+		return strconv.ParseInt(string(n), 10, 64)
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unexpected count type %T", v)
+}
+
 // GroupField 结构用于定义分组字段
 type GroupField struct {
 	Field string
@@ -784,7 +2095,8 @@ type GroupField struct {
 }
 
 // GroupSumMultiple 实现
-func (q *Queryable[T]) GroupSumMultiple(groupFields []GroupField, sumFields []string) ([]*AggregateResult, error) {
+func (q *Queryable[T]) GroupSumMultiple(groupFields []GroupField, sumFields []string) (_ []*AggregateResult, err error) {
+	defer wrapQueryError("GroupSumMultiple", q.table, &err)
 	// 构造 SELECT 子句
 	selects := make([]interface{}, 0, len(groupFields)+len(sumFields))
 
@@ -819,10 +2131,11 @@ func (q *Queryable[T]) GroupSumMultiple(groupFields []GroupField, sumFields []st
 	}
 
 	// 执行查询
-	rows, err := q.db.DB.Queryx(sql, args...)
+	rows, cancel, err := q.queryx(q.baseContext(), sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
+	defer cancel()
 	defer rows.Close()
 
 	// 处理结果
@@ -880,16 +2193,17 @@ func (q *Queryable[T]) GroupSumMultiple(groupFields []GroupField, sumFields []st
 
 func (q *Queryable[T]) GroupBy(keySelector func(T) interface{}) IGroupingQuery[T] {
 	return &GroupingQuery[T]{
-		parent:      q,
+		parent:      q.clone(),
 		keySelector: keySelector,
 	}
 }
 
-// 在 Queryable 中添加 GroupByColumns 实现
+// GroupByColumns 按列分组，列名作为标识符按方言转义（如 MySQL 的 `order`）
 func (q *Queryable[T]) GroupByColumns(cols ...string) IQueryable[T] {
+	q = q.clone()
 	colsInterface := make([]interface{}, len(cols))
 	for i, col := range cols {
-		colsInterface[i] = col
+		colsInterface[i] = goqu.I(col)
 	}
 	q.query = q.query.GroupBy(colsInterface...)
 	return q
@@ -897,6 +2211,7 @@ func (q *Queryable[T]) GroupByColumns(cols ...string) IQueryable[T] {
 
 // OverTx
 func (q *Queryable[T]) OverTx(ctx context.Context, windowFunc string, partitionBy ...interface{}) IQueryable[T] {
+	q = q.clone()
 	w := goqu.W()
 	if len(partitionBy) > 0 {
 		w = w.PartitionBy(partitionBy...)
@@ -914,7 +2229,7 @@ func (q *Queryable[T]) ToLookupTx(ctx context.Context, keySelector func(T) inter
 		return nil
 	}
 	var results []*T
-	err = q.db.SelectContext(ctx, &results, query, args...)
+	err = q.selectContext(ctx, &results, query, args...)
 	if err != nil {
 		return nil
 	}
@@ -927,7 +2242,10 @@ func (q *Queryable[T]) ToLookupTx(ctx context.Context, keySelector func(T) inter
 }
 
 // ToPagedResultTx
-func (q *Queryable[T]) ToPagedResultTx(ctx context.Context, page, pageSize int, dest interface{}) (*PagedResult, error) {
+func (q *Queryable[T]) ToPagedResultTx(ctx context.Context, page, pageSize int, dest interface{}) (_ *PagedResult, err error) {
+	defer wrapQueryError("ToPagedResultTx", q.table, &err)
+	q = q.clone()
+	q.ensurePageOrder()
 	// 1. 获取总记录数
 	total, err := q.CountTx(ctx)
 	if err != nil {
@@ -947,7 +2265,7 @@ func (q *Queryable[T]) ToPagedResultTx(ctx context.Context, page, pageSize int,
 	}
 
 	// 4. 执行查询并填充结果
-	err = q.db.SelectContext(ctx, dest, sql, args...)
+	err = q.selectContext(ctx, dest, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
@@ -961,42 +2279,80 @@ func (q *Queryable[T]) ToPagedResultTx(ctx context.Context, page, pageSize int,
 }
 
 // ScanInt64Slice() ([]int64, error)
-func (q *Queryable[T]) ScanInt64Slice() ([]int64, error) {
-	query, args, err := q.query.ToSQL()
-	if err != nil {
-		return nil, err
-	}
-	var results []int64
-	err = q.db.Select(&results, query, args...)
-	return results, err
+func (q *Queryable[T]) ScanInt64Slice() (_ []int64, err error) {
+	defer wrapQueryError("ScanInt64Slice", q.table, &err)
+	return ScanSlice[T, int64](q)
 }
 
 // ScanFloat64() (float64, error)
-func (q *Queryable[T]) ScanFloat64() (float64, error) {
+func (q *Queryable[T]) ScanFloat64() (_ float64, err error) {
+	defer wrapQueryError("ScanFloat64", q.table, &err)
 	query, args, err := q.query.ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var result float64
-	err = q.db.Get(&result, query, args...)
+	err = q.get(q.baseContext(), &result, query, args...)
 	return result, err
 }
 
+// Dataset 返回当前的 goqu 查询构造器，用于使用本包未封装的 goqu 功能
+func (q *Queryable[T]) Dataset() *goqu.SelectDataset {
+	return q.query
+}
+
+// FromDataset 用自定义的 goqu 查询构造器替换当前查询，之后仍可使用类型化的执行方法
+// 未指定 SELECT 列时，ToList 等方法仍会自动选择实体的字段；HasWhere 等状态按 ds 的子句重新设置
+// 通过 WithTenant 创建的仓储会在 ds 上追加租户条件
+func (q *Queryable[T]) FromDataset(ds *goqu.SelectDataset) IQueryable[T] {
+	q = q.clone()
+	clauses := ds.GetClauses()
+	q.hasWhere = clauses.Where() != nil
+	q.hasOrder = clauses.Order() != nil
+	q.hasLimit = clauses.Limit() != nil
+	// 租户隔离的仓储重新附加租户条件，避免自定义的查询读到其他租户的数据
+	q.query = ds.Where(q.tenant...)
+	return q
+}
+
 func (q *Queryable[T]) ToSQL() (sql string, params []interface{}, err error) {
 	query, args, err := q.query.ToSQL()
 	return query, args, err
 }
 
-// ensureSelectFields 确保查询中包含 SELECT 字段
-// 如果没有指定 Select，则自动使用结构体中定义的字段
-func (q *Queryable[T]) ensureSelectFields() {
-	// 检查是否已经有 SELECT 子句
-	sql, _, _ := q.query.ToSQL()
+// ToInterpolatedSQL 返回参数已按方言转义并内联的完整 SQL，可直接粘贴到 BI 工具或客户端中查看和分析
+// 未指定 Select 时与 ToList 一样选择实体的列，即使 Dataset 设置了 Prepared(true) 也会内联参数；
+// 生成的语句仅用于调试和分析，执行查询应使用 ToList 等方法
+func (q *Queryable[T]) ToInterpolatedSQL() (_ string, err error) {
+	defer wrapQueryError("ToInterpolatedSQL", q.table, &err)
+	q = q.clone()
+	q.ensureSelectFields()
+	query, _, err := q.query.Prepared(false).ToSQL()
+	return query, err
+}
 
-	// 如果 SQL 包含 "SELECT  FROM" (两个空格) 说明没有指定字段
-	if strings.Contains(sql, "SELECT  FROM") {
+// ensureSelectFields 确保查询中包含 SELECT 字段
+// 如果没有指定 Select，则自动使用结构体中定义的字段，except 中的字段除外
+// 设置了别名时按别名限定字段，存在 JOIN 时按表名限定，避免与连接表的同名列产生歧义
+// 会修改接收者，只在 clone 得到的副本上调用
+func (q *Queryable[T]) ensureSelectFields(except ...string) {
+	// 检查是否已经有 SELECT 子句，默认的 SELECT * 说明没有指定字段
+	clauses := q.query.GetClauses()
+	if clauses.IsDefaultSelect() {
 		// 获取结构体的所有数据库字段
-		fields := q.getStructDBFields()
+		fields := make([]interface{}, 0)
+		for _, field := range q.getStructDBFields() {
+			if slices.Contains(except, field.(string)) {
+				continue
+			}
+			switch {
+			case q.alias != "":
+				field = goqu.T(q.alias).Col(field.(string))
+			case clauses.Joins() != nil && q.table != "":
+				field = goqu.T(q.table).Col(field.(string))
+			}
+			fields = append(fields, field)
+		}
 		if len(fields) > 0 {
 			q.query = q.query.Select(fields...)
 		}
@@ -1020,17 +2376,9 @@ func (q *Queryable[T]) getStructDBFields() []interface{} {
 
 	var fields []interface{}
 
-	// 遍历结构体的所有字段
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-
-		// 获取 db tag
-		dbTag := field.Tag.Get("db")
-		if dbTag != "" && dbTag != "-" {
-			// 只取 tag 的第一部分（逗号之前）
-			dbFieldName := strings.Split(dbTag, ",")[0]
-			fields = append(fields, dbFieldName)
-		}
+	// 遍历结构体的所有字段，没有 db tag 的字段使用 nameMapper 映射，嵌入结构体的字段会展开
+	for _, c := range entityColumns(typ, q.nameMapper) {
+		fields = append(fields, c.name)
 	}
 
 	return fields
@@ -0,0 +1,1656 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func activeScope(q IQueryable[TestEntity]) IQueryable[TestEntity] {
+	return q.Where(goqu.Ex{"status": 1})
+}
+
+func tenantScope(tenantID int64) func(IQueryable[TestEntity]) IQueryable[TestEntity] {
+	return func(q IQueryable[TestEntity]) IQueryable[TestEntity] {
+		return q.Where(goqu.Ex{"tenant_id": tenantID})
+	}
+}
+
+func TestQueryableScope(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, _, err := repo.Query().Scope(activeScope).Scope(tenantScope(42)).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+
+	expected := "SELECT * FROM `test_table` WHERE ((`status` = 1) AND (`tenant_id` = 42))"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().Scope(nil).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if strings.Contains(sql, "WHERE") {
+		t.Errorf("Expected nil scope to be a no-op, got %q", sql)
+	}
+}
+
+func TestQueryableSelectFields(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, _, err := repo.Query().SelectFields("id", "name").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `id`, `name` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	_, _, err = repo.Query().SelectFields("id", "nmae").ToSQL()
+	if err == nil || !strings.Contains(err.Error(), `"nmae"`) {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+
+	db, fake := newFakeDBLogger(t)
+	_, err = NewRepository[TestEntity](db, "test_table", MySQL).Query().SelectFields("nmae").ToList()
+	if err == nil {
+		t.Error("Expected ToList to return the unknown field error")
+	}
+	if len(fake.Queries()) != 0 {
+		t.Errorf("Expected no query to be executed, got %v", fake.Queries())
+	}
+}
+
+func TestQueryableAggregates(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"count", "sum_status", "min_id", "max_id"},
+		[]driver.Value{int64(3), nil, int64(1), int64(9)})
+
+	results, err := repo.Query().Where(goqu.Ex{"name": "a"}).Aggregates([]AggregateInfo{
+		{Field: "*", Function: "COUNT"},
+		{Field: "status", Function: "SUM"},
+		{Field: "id", Function: "MIN"},
+		{Field: "id", Function: "MAX"},
+	})
+	if err != nil {
+		t.Fatalf("Aggregates failed: %v", err)
+	}
+
+	queries := fake.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("Expected a single query, got %d", len(queries))
+	}
+	expected := "SELECT COUNT(*) AS `count`, SUM(`status`) AS `sum_status`, MIN(`id`) AS `min_id`, MAX(`id`) AS `max_id` FROM `test_table` WHERE (`name` = 'a')"
+	if queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries[0])
+	}
+
+	want := map[string]float64{"count": 3, "sum_status": 0, "min_id": 1, "max_id": 9}
+	for key, value := range want {
+		if results[key] != value {
+			t.Errorf("Expected %s = %v, got %v", key, value, results[key])
+		}
+	}
+
+	_, err = repo.Query().Aggregates([]AggregateInfo{{Field: "id", Function: "MEDIAN"}})
+	if err == nil {
+		t.Error("Expected error for unsupported aggregate function")
+	}
+}
+
+func TestGroupedDistinctAggregate(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"status", "names", "count_distinct_id"},
+		[]driver.Value{int64(0), int64(2), int64(5)},
+		[]driver.Value{int64(1), int64(3), int64(4)})
+
+	builder := NewGroupAggregateBuilder[TestEntity]().
+		CountDistinct("name").WithAlias("names").
+		CountDistinct("id")
+	byStatus := func(e TestEntity) interface{} { return e.Status }
+	rows, err := repo.Query().GroupByColumns("status").GroupBy(byStatus).Aggregate(builder).ToRawMapSlice()
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	expected := "SELECT `status`, COUNT(DISTINCT `name`) AS `names`, COUNT(DISTINCT `id`) AS `count_distinct_id` FROM `test_table` GROUP BY `status`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+	want := []map[string]interface{}{
+		{"status": int64(0), "names": int64(2), "count_distinct_id": int64(5)},
+		{"status": int64(1), "names": int64(3), "count_distinct_id": int64(4)},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Expected one row per group %v, got %v", want, rows)
+	}
+
+	distinctAll := AggregateInfo{Field: "*", Function: "COUNT", Distinct: true}
+	if _, err := repo.Query().Aggregates([]AggregateInfo{distinctAll}); err == nil || !strings.Contains(err.Error(), "DISTINCT *") {
+		t.Errorf("Expected COUNT(DISTINCT *) to be rejected, got %v", err)
+	}
+	builder = NewGroupAggregateBuilder[TestEntity]().CountDistinct("*")
+	if _, err := repo.Query().GroupByColumns("status").GroupBy(byStatus).Aggregate(builder).ToRawMapSlice(); err == nil {
+		t.Error("Expected Aggregate to reject COUNT(DISTINCT *)")
+	}
+	if _, err := repo.Query().GroupBy(byStatus).Aggregate(NewGroupAggregateBuilder[TestEntity]().Count()).ToRawMapSlice(); err == nil {
+		t.Error("Expected Aggregate without GroupByColumns to fail")
+	}
+	if len(fake.Queries()) != 1 {
+		t.Errorf("Expected rejected aggregates not to run, got %v", fake.Queries())
+	}
+}
+
+func TestQueryableToRawMapSlice(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueTypedResult([]string{"name", "name_length", "payload"}, []string{"VARCHAR", "BIGINT", "BLOB"},
+		[]driver.Value{[]byte("alice"), int64(5), []byte{0x01}},
+	)
+
+	results, err := repo.Query().SelectRaw("name", "CHAR_LENGTH(name) AS name_length", "payload").ToRawMapSlice()
+	if err != nil {
+		t.Fatalf("ToRawMapSlice failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(results))
+	}
+
+	row := results[0]
+	if row["name"] != "alice" {
+		t.Errorf("Expected name to be converted to string, got %#v", row["name"])
+	}
+	if row["name_length"] != int64(5) {
+		t.Errorf("Expected computed column name_length = 5, got %#v", row["name_length"])
+	}
+	if _, ok := row["payload"].([]byte); !ok {
+		t.Errorf("Expected binary column to stay []byte, got %#v", row["payload"])
+	}
+}
+
+func TestQueryableJoin(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "users", MySQL)
+
+	sql, _, err := repo.Query().Join("orders", map[string]string{"orders.user_id": "users.id"}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `users` INNER JOIN `orders` ON (`orders`.`user_id` = `users`.`id`)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().FullJoin("orders", map[string]string{"orders.user_id": "users.id"}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if !strings.Contains(sql, "FULL JOIN `orders`") {
+		t.Errorf("Expected FULL JOIN, got %q", sql)
+	}
+
+	sql, _, err = repo.Query().JoinOn("orders", goqu.And(
+		goqu.I("orders.user_id").Eq(goqu.I("users.id")),
+		goqu.L("orders.status = 1"),
+	)).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected = "SELECT * FROM `users` INNER JOIN `orders` ON ((`orders`.`user_id` = `users`.`id`) AND orders.status = 1)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+}
+
+func TestQueryableSelectQualified(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "users", MySQL)
+	join := repo.Query().InnerJoin("orders", map[string]string{"orders.user_id": "users.id"})
+
+	sql, _, err := join.
+		SelectQualified("users", "id AS user_id", "name").
+		SelectQualified("orders", "id as order_id", "total").
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `users`.`id` AS `user_id`, `users`.`name`, `orders`.`id` AS `order_id`, `orders`.`total` " +
+		"FROM `users` INNER JOIN `orders` ON (`orders`.`user_id` = `users`.`id`)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	// 追加到已有的 SELECT 列之后
+	sql, _, err = join.Select(goqu.COUNT("*").As("n")).SelectQualified("orders", "*").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if !strings.HasPrefix(sql, "SELECT COUNT(*) AS `n`, `orders`.* FROM") {
+		t.Errorf("Expected qualified columns to be appended, got %q", sql)
+	}
+}
+
+type testUserOrder struct {
+	UserName   string  `db:"user_name"`
+	OrderTotal float64 `db:"order_total"`
+}
+
+func TestScanListAs(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "users", MySQL)
+	fake.queueResult([]string{"user_name", "order_total"},
+		[]driver.Value{"alice", 12.5},
+		[]driver.Value{"bob", 7.0},
+	)
+
+	var rows []*testUserOrder
+	err := ScanListAs(repo.Query().
+		InnerJoin("orders", map[string]string{"orders.user_id": "users.id"}).
+		Select(goqu.I("users.name").As("user_name"), goqu.I("orders.total").As("order_total")), &rows)
+	if err != nil {
+		t.Fatalf("ScanListAs failed: %v", err)
+	}
+
+	expected := "SELECT `users`.`name` AS `user_name`, `orders`.`total` AS `order_total` FROM `users` INNER JOIN `orders` ON (`orders`.`user_id` = `users`.`id`)"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+	if len(rows) != 2 || rows[0].UserName != "alice" || rows[1].OrderTotal != 7.0 {
+		t.Errorf("Unexpected rows: %+v", rows)
+	}
+}
+
+func TestToListDistinct(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"id", "name", "status"},
+		[]driver.Value{int64(1), "a", int64(1)},
+		[]driver.Value{int64(1), "a", int64(1)},
+		[]driver.Value{int64(2), "b", int64(0)},
+		[]driver.Value{int64(1), "a", int64(1)},
+		[]driver.Value{int64(3), "c", int64(1)},
+	)
+
+	items, err := repo.Query().
+		InnerJoin("orders", map[string]string{"orders.entity_id": "test_table.id"}).
+		ToListDistinct(func(e *TestEntity) interface{} { return e.ID })
+	if err != nil {
+		t.Fatalf("ToListDistinct failed: %v", err)
+	}
+	if got := entityIDs(items); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("Expected ids [1 2 3], got %v", got)
+	}
+	if items[1].Name != "b" || items[2].Status != 1 {
+		t.Errorf("Unexpected entities: %+v %+v", items[1], items[2])
+	}
+	// JOIN 时默认选择的实体字段按主表限定，避免与连接表的同名列冲突
+	expected := "SELECT `test_table`.`id`, `test_table`.`name`, `test_table`.`status` FROM `test_table` " +
+		"INNER JOIN `orders` ON (`orders`.`entity_id` = `test_table`.`id`)"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries)
+	}
+
+	if _, err := repo.Query().ToListDistinct(nil); err == nil {
+		t.Error("Expected error for nil keyFn")
+	}
+}
+
+func TestQueryableBranchesAreIndependent(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	base := repo.Query().Where(goqu.Ex{"status": 1})
+
+	first := base.WhereEq("name", "a").OrderBy("id")
+	second := base.WhereEq("name", "b").Take(5)
+
+	expected := map[IQueryable[TestEntity]]string{
+		base:   "SELECT * FROM `test_table` WHERE (`status` = 1)",
+		first:  "SELECT * FROM `test_table` WHERE ((`status` = 1) AND (`name` = 'a')) ORDER BY `id` ASC",
+		second: "SELECT * FROM `test_table` WHERE ((`status` = 1) AND (`name` = 'b')) LIMIT 5",
+	}
+	for q, want := range expected {
+		if sql, _, err := q.ToSQL(); err != nil || sql != want {
+			t.Errorf("Expected SQL %q, got %q (%v)", want, sql, err)
+		}
+	}
+	if base.HasOrder() || base.HasLimit() {
+		t.Error("Expected the base query to be unchanged by its branches")
+	}
+
+	db, fake := newFakeDBLogger(t)
+	repo = NewRepository[TestEntity](db, "test_table", MySQL)
+	base = repo.Query().Where(goqu.Ex{"status": 1})
+	fake.queueResult([]string{"id", "name", "status"})
+	fake.queueResult([]string{"count"}, []driver.Value{int64(0)})
+	if _, err := base.ToPagedList(1, 10, goqu.Ex{}); err != nil {
+		t.Fatalf("ToPagedList failed: %v", err)
+	}
+	fake.queueResult([]string{"id", "name", "status"})
+	if _, err := base.ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if sql, _, err := base.ToSQL(); err != nil || sql != "SELECT * FROM `test_table` WHERE (`status` = 1)" {
+		t.Errorf("Expected terminal methods to leave the base query unchanged, got %q (%v)", sql, err)
+	}
+	if base.HasOrder() {
+		t.Error("Expected paging not to add an order to the base query")
+	}
+}
+
+func TestQueryableStateFlags(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL).WithTenant("tenant_id", 1)
+
+	q := repo.Query().(*Queryable[TestEntity])
+	if q.HasWhere() || q.HasOrder() || q.HasLimit() {
+		t.Fatal("Expected a fresh query to report no where/order/limit")
+	}
+	empty := q.Where(goqu.Ex{}).OrderBy().Take(0)
+	if empty.HasWhere() || empty.HasOrder() || empty.HasLimit() {
+		t.Error("Expected empty conditions, orders and limits not to set the flags")
+	}
+
+	filtered := q.WhereGt("id", 1)
+	if !filtered.HasWhere() || filtered.HasOrder() || filtered.HasLimit() {
+		t.Error("Expected only HasWhere after WhereGt")
+	}
+	ordered := filtered.OrderByRaw("name DESC")
+	if !ordered.HasOrder() || ordered.HasLimit() {
+		t.Error("Expected HasOrder after OrderByRaw")
+	}
+	if !ordered.Take(10).HasLimit() {
+		t.Error("Expected HasLimit after Take")
+	}
+	if q.HasWhere() || filtered.HasOrder() || ordered.HasLimit() {
+		t.Error("Expected chain methods to leave the receiver unchanged")
+	}
+
+	// 中间件只在调用方未排序时追加默认排序
+	defaultOrder := func(q IQueryable[TestEntity]) IQueryable[TestEntity] {
+		if q.HasOrder() {
+			return q
+		}
+		return q.OrderBy("id")
+	}
+	sql, _, _ := repo.Query().Scope(defaultOrder).ToSQL()
+	if !strings.HasSuffix(sql, "ORDER BY `id` ASC") {
+		t.Errorf("Expected default order, got %q", sql)
+	}
+	sql, _, _ = repo.Query().OrderBy("name").Scope(defaultOrder).ToSQL()
+	if !strings.HasSuffix(sql, "ORDER BY `name` ASC") {
+		t.Errorf("Expected caller's order to be kept, got %q", sql)
+	}
+
+	ds := repo.Query().Dataset().Order(goqu.I("id").Asc())
+	fromDS := repo.Query().FromDataset(ds)
+	if !fromDS.HasWhere() || !fromDS.HasOrder() || fromDS.HasLimit() {
+		t.Error("Expected FromDataset to derive flags from the dataset clauses")
+	}
+}
+
+type testCodeEntity struct {
+	Code string `db:"code"`
+	Name string `db:"name"`
+}
+
+func (testCodeEntity) PrimaryKey() string { return "code" }
+
+func TestPagedListDefaultOrder(t *testing.T) {
+	sqlDB, fake := newFakeSQLX(t, t.Name())
+	observed, logs := observer.New(zap.WarnLevel)
+	db := NewDBLogger(sqlDB, zap.New(observed), "")
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	page := func(q IQueryable[TestEntity]) string {
+		t.Helper()
+		fake.queueResult([]string{"id", "name", "status"})
+		fake.queueResult([]string{"count"}, []driver.Value{int64(0)})
+		if _, err := q.ToPagedList(2, 10, goqu.Ex{"status": 1}); err != nil {
+			t.Fatalf("ToPagedList failed: %v", err)
+		}
+		queries := fake.Queries()
+		return queries[len(queries)-2]
+	}
+
+	expected := "SELECT `id`, `name`, `status` FROM `test_table` WHERE (`status` = 1) ORDER BY `id` ASC LIMIT 10 OFFSET 10"
+	if got := page(repo.Query()); got != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, got)
+	}
+	if logs.FilterMessage("Paged query without ORDER BY, ordering by primary key").Len() != 1 {
+		t.Errorf("Expected one warning, got %v", logs.All())
+	}
+
+	if got := page(repo.Query().OrderByRaw("name DESC")); !strings.Contains(got, "ORDER BY `name` DESC LIMIT") {
+		t.Errorf("Expected the caller's order to be kept, got %q", got)
+	}
+	if got := page(repo.Query().Unordered()); strings.Contains(got, "ORDER BY") {
+		t.Errorf("Expected no ORDER BY after Unordered, got %q", got)
+	}
+
+	fake.queueResult([]string{"count"}, []driver.Value{int64(0)})
+	fake.queueResult([]string{"code", "name"})
+	var rows []testCodeEntity
+	if _, err := NewRepository[testCodeEntity](db, "codes", MySQL).Query().ToPagedResult(1, 5, &rows); err != nil {
+		t.Fatalf("ToPagedResult failed: %v", err)
+	}
+	queries := fake.Queries()
+	if got := queries[len(queries)-1]; got != "SELECT * FROM `codes` ORDER BY `code` ASC LIMIT 5" {
+		t.Errorf("Expected ORDER BY the PrimaryKey column, got %q", got)
+	}
+}
+
+func TestScanSlice(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	fake.queueResult([]string{"created_at"}, []driver.Value{day}, []driver.Value{day.AddDate(0, 0, 1)})
+	fake.queueResult([]string{"enabled"}, []driver.Value{int64(1)}, []driver.Value{int64(0)})
+
+	times, err := ScanSlice[TestEntity, time.Time](repo.Query().Select("created_at"))
+	if err != nil {
+		t.Fatalf("ScanSlice[time.Time] failed: %v", err)
+	}
+	if len(times) != 2 || !times[0].Equal(day) || !times[1].Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("Unexpected times: %v", times)
+	}
+
+	flags, err := ScanSlice[TestEntity, bool](repo.Query().Select("enabled").Where(goqu.Ex{"status": 1}))
+	if err != nil {
+		t.Fatalf("ScanSlice[bool] failed: %v", err)
+	}
+	if !reflect.DeepEqual(flags, []bool{true, false}) {
+		t.Errorf("Unexpected flags: %v", flags)
+	}
+
+	expected := []string{
+		"SELECT `created_at` FROM `test_table`",
+		"SELECT `enabled` FROM `test_table` WHERE (`status` = 1)",
+	}
+	if queries := fake.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected SQL %q, got %q", expected, queries)
+	}
+
+	fake.queueResult([]string{"id", "name"}, []driver.Value{int64(1), "a"})
+	if _, err := ScanSlice[TestEntity, int64](repo.Query().Select("id", "name")); err == nil {
+		t.Error("Expected error when scanning more than one column")
+	}
+}
+
+type testUserFilter struct {
+	Status   *int     `db:"status"`
+	Name     string   `db:"name" query:"like"`
+	MinAge   int      `db:"age" query:"gte"`
+	Roles    []string `db:"role" query:"in"`
+	Keyword  string
+	internal string `db:"internal"`
+}
+
+func TestQueryableWhereStruct(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "users", MySQL)
+	status := 0
+
+	sql, _, err := repo.Query().WhereStruct(&testUserFilter{
+		Status:  &status,
+		Name:    "%bob%",
+		Keyword: "ignored",
+	}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `users` WHERE ((`name` LIKE BINARY '%bob%') AND (`status` = 0))"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().WhereStruct(testUserFilter{MinAge: 18, Roles: []string{"admin"}}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected = "SELECT * FROM `users` WHERE ((`age` >= 18) AND (`role` IN ('admin')))"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().WhereStruct(&testUserFilter{Roles: []string{}}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if strings.Contains(sql, "WHERE") {
+		t.Errorf("Expected empty filter to add no conditions, got %q", sql)
+	}
+
+	if _, _, err = repo.Query().WhereStruct(42).ToSQL(); err == nil {
+		t.Error("Expected error for non-struct filter")
+	}
+}
+
+func TestQueryableSumExpr(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "orders", MySQL)
+	fake.queueResult([]string{"total"}, []driver.Value{42.5})
+	fake.queueResult([]string{"avg"}, []driver.Value{3.0})
+
+	sum, err := repo.Query().Where(goqu.Ex{"status": 1}).SumExpr("price * quantity")
+	if err != nil {
+		t.Fatalf("SumExpr failed: %v", err)
+	}
+	if sum != 42.5 {
+		t.Errorf("Expected sum 42.5, got %v", sum)
+	}
+
+	if _, err := repo.Query().AvgExpr("price * ?", 0.9); err != nil {
+		t.Fatalf("AvgExpr failed: %v", err)
+	}
+
+	queries := fake.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 queries, got %d", len(queries))
+	}
+	if !strings.Contains(queries[0], "SUM(price * quantity)") {
+		t.Errorf("Expected SUM(price * quantity) in SQL, got %q", queries[0])
+	}
+	if !strings.Contains(queries[1], "AVG(price * 0.9)") {
+		t.Errorf("Expected bound arg in AVG expression, got %q", queries[1])
+	}
+
+	if _, err := repo.Query().SumExpr("  "); err == nil {
+		t.Error("Expected error for empty expression")
+	}
+}
+
+func TestQueryableGroupByColumns(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, _, err := repo.Query().Select(goqu.I("order"), goqu.COUNT("*")).GroupByColumns("order", "test_table.key").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `order`, COUNT(*) FROM `test_table` GROUP BY `order`, `test_table`.`key`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+}
+
+func queueTestEntities(fake *fakeDB, n int) {
+	rows := make([][]driver.Value, n)
+	for i := range rows {
+		rows[i] = []driver.Value{int64(i + 1), "name", int64(1)}
+	}
+	fake.queueResult([]string{"id", "name", "status"}, rows...)
+}
+
+func TestQueryableToChan(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	queueTestEntities(fake, 3)
+
+	items, errc := repo.Query().ToChan(context.Background(), 1)
+	var ids []int64
+	for item := range items {
+		ids = append(ids, item.ID)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("ToChan failed: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("Expected ids [1 2 3], got %v", ids)
+	}
+}
+
+func TestQueryableToChanCancel(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	queueTestEntities(fake, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errc := repo.Query().ToChan(ctx, 0)
+	if first := <-items; first == nil || first.ID != 1 {
+		t.Fatalf("Expected first item with id 1, got %+v", first)
+	}
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	received := 1
+	for range items {
+		received++
+	}
+	if received >= 5 {
+		t.Errorf("Expected the stream to stop early, received %d items", received)
+	}
+}
+
+func TestQueryableAllNone(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "line_items", MySQL)
+
+	// 范围内没有数据时 NOT EXISTS 为真
+	fake.queueResult([]string{"all"}, []driver.Value{int64(1)})
+	all, err := repo.Query().All(goqu.Ex{"order_id": 9}, goqu.Ex{"shipped": 1})
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if !all {
+		t.Error("Expected All to be vacuously true for an empty set")
+	}
+	expected := "SELECT NOT EXISTS(SELECT 1 FROM `line_items` WHERE ((`order_id` = 9) AND NOT COALESCE((`shipped` = 1), FALSE)))"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	// shipped 为 NULL 的行使 predicate 为 NULL，COALESCE 后按不满足计入 NOT EXISTS 子查询
+	fake.queueResult([]string{"all"}, []driver.Value{int64(0)})
+	if all, err = repo.Query().AllTx(context.Background(), nil, goqu.Ex{"shipped": goqu.Op{"gt": 0}}); err != nil || all {
+		t.Errorf("Expected All to be false when a row has a NULL column, got %v (%v)", all, err)
+	}
+	expected = "SELECT NOT EXISTS(SELECT 1 FROM `line_items` WHERE NOT COALESCE((`shipped` > 0), FALSE))"
+	if queries := fake.Queries(); queries[len(queries)-1] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries[len(queries)-1])
+	}
+
+	fake.queueResult([]string{"count"}, []driver.Value{int64(0)})
+	none, err := repo.Query().None(goqu.Ex{"shipped": 0})
+	if err != nil || !none {
+		t.Errorf("Expected None to be true when no rows match, got %v (%v)", none, err)
+	}
+
+	fake.queueResult([]string{"count"}, []driver.Value{int64(3)})
+	if none, err = repo.Query().NoneTx(context.Background(), goqu.Ex{"shipped": 0}); err != nil || none {
+		t.Errorf("Expected None to be false when rows match, got %v (%v)", none, err)
+	}
+
+	if _, err := repo.Query().All(nil, nil); err == nil {
+		t.Error("Expected error for empty predicate")
+	}
+}
+
+func TestQueryableOrderByRandom(t *testing.T) {
+	for _, dbType := range []DialectType{MySQL, StarRocks} {
+		repo := NewRepository[TestEntity](nil, "items", dbType)
+		sql, _, err := repo.Query().Where(goqu.Ex{"featured": 1}).OrderByRandom().Take(1).ToSQL()
+		if err != nil {
+			t.Fatalf("ToSQL failed: %v", err)
+		}
+		expected := "SELECT * FROM `items` WHERE (`featured` = 1) ORDER BY RAND() ASC LIMIT 1"
+		if sql != expected {
+			t.Errorf("%s: expected SQL %q, got %q", dbType, expected, sql)
+		}
+	}
+}
+
+func TestQueryableDatasetRoundTrip(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	ds := repo.Query().Where(goqu.Ex{"status": 1}).Dataset()
+	ds = ds.Where(goqu.I("name").Like("a%")).Order(goqu.I("id").Desc())
+
+	if _, err := repo.Query().FromDataset(ds).ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected := "SELECT `id`, `name`, `status` FROM `test_table` WHERE ((`status` = 1) AND (`name` LIKE BINARY 'a%')) ORDER BY `id` DESC"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+}
+
+func TestQueryableTypedMaxMin(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	fake.queueResult([]string{"MAX(`id`)"}, []driver.Value{[]byte("42")})
+	fake.queueResult([]string{"MIN(`score`)"}, []driver.Value{[]byte("1.5")})
+	fake.queueResult([]string{"MAX(`name`)"}, []driver.Value{[]byte("zoe")})
+	fake.queueResult([]string{"MIN(`created_at`)"}, []driver.Value{created})
+
+	if v, found, err := repo.Query().MaxInt64("id"); err != nil || !found || v != 42 {
+		t.Errorf("MaxInt64 = %v, %v, %v", v, found, err)
+	}
+	if v, found, err := repo.Query().MinFloat64("score"); err != nil || !found || v != 1.5 {
+		t.Errorf("MinFloat64 = %v, %v, %v", v, found, err)
+	}
+	if v, found, err := repo.Query().MaxString("name"); err != nil || !found || v != "zoe" {
+		t.Errorf("MaxString = %v, %v, %v", v, found, err)
+	}
+	if v, found, err := repo.Query().MinTime("created_at"); err != nil || !found || !v.Equal(created) {
+		t.Errorf("MinTime = %v, %v, %v", v, found, err)
+	}
+
+	expected := "SELECT MAX(`id`) FROM `test_table`"
+	if queries := fake.Queries(); queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries[0])
+	}
+}
+
+func TestQueryableTypedMaxMinNotFound(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	// 空集合上的 MAX/MIN 返回一行 NULL
+	for i := 0; i < 4; i++ {
+		fake.queueResult([]string{"agg"}, []driver.Value{nil})
+	}
+	if _, found, err := repo.Query().MinInt64("id"); err != nil || found {
+		t.Errorf("MinInt64 on NULL: found=%v err=%v", found, err)
+	}
+	if _, found, err := repo.Query().MaxFloat64("score"); err != nil || found {
+		t.Errorf("MaxFloat64 on NULL: found=%v err=%v", found, err)
+	}
+	if _, found, err := repo.Query().MinString("name"); err != nil || found {
+		t.Errorf("MinString on NULL: found=%v err=%v", found, err)
+	}
+	if _, found, err := repo.Query().MaxTime("created_at"); err != nil || found {
+		t.Errorf("MaxTime on NULL: found=%v err=%v", found, err)
+	}
+
+	// 没有任何返回行时同样视为未找到
+	if _, found, err := repo.Query().MaxInt64("id"); err != nil || found {
+		t.Errorf("MaxInt64 without rows: found=%v err=%v", found, err)
+	}
+}
+
+func TestQueryableSumAvgPtr(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	fake.queueResult([]string{"SUM(`amount`)"}, []driver.Value{[]byte("0")})
+	fake.queueResult([]string{"AVG(`amount`)"}, []driver.Value{[]byte("2.5")})
+	fake.queueResult([]string{"SUM(`amount`)"}, []driver.Value{nil})
+	fake.queueResult([]string{"AVG(`amount`)"}, []driver.Value{nil})
+
+	if sum, err := repo.Query().SumPtr("amount"); err != nil || sum == nil || *sum != 0 {
+		t.Errorf("Expected a zero sum, got %v (%v)", sum, err)
+	}
+	if avg, err := repo.Query().AvgPtr("amount"); err != nil || avg == nil || *avg != 2.5 {
+		t.Errorf("Expected avg 2.5, got %v (%v)", avg, err)
+	}
+	if sum, err := repo.Query().Where(goqu.Ex{"status": 9}).SumPtr("amount"); err != nil || sum != nil {
+		t.Errorf("Expected nil sum without rows, got %v (%v)", sum, err)
+	}
+	if avg, err := repo.Query().AvgPtr("amount"); err != nil || avg != nil {
+		t.Errorf("Expected nil avg without rows, got %v (%v)", avg, err)
+	}
+
+	expected := []string{
+		"SELECT SUM(`amount`) FROM `test_table`",
+		"SELECT AVG(`amount`) FROM `test_table`",
+		"SELECT SUM(`amount`) FROM `test_table` WHERE (`status` = 9)",
+		"SELECT AVG(`amount`) FROM `test_table`",
+	}
+	if queries := fake.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected SQL %q, got %q", expected, queries)
+	}
+}
+
+func TestQueryableRankingWindows(t *testing.T) {
+	db, _ := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	sql, _, err := repo.Query().
+		Select("id", "name").
+		RowNumber([]string{"status"}, "created_at DESC, id", "rn").
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `id`, `name`, ROW_NUMBER() OVER (PARTITION BY `status` ORDER BY `created_at` DESC, `id`) AS `rn` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	// 未指定 Select 时保留实体字段
+	sql, _, err = repo.Query().DenseRank([]string{"status", "name"}, "id asc", "dr").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected = "SELECT `id`, `name`, `status`, DENSE_RANK() OVER (PARTITION BY `status`, `name` ORDER BY `id` ASC) AS `dr` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().Select("id").Rank(nil, "id", "r").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected = "SELECT `id`, RANK() OVER (ORDER BY `id`) AS `r` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	if _, _, err := repo.Query().RowNumber(nil, "id sideways", "rn").ToSQL(); err == nil {
+		t.Error("Expected error for invalid order direction")
+	}
+}
+
+type testUser struct {
+	ID       int64  `db:"id"`
+	UserName string `db:"user_name"`
+}
+
+func TestToMapByKey(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[testUser](db, "users", MySQL)
+	fake.queueResult([]string{"id", "user_name"},
+		[]driver.Value{int64(1), "alice"},
+		[]driver.Value{int64(2), "bob"})
+
+	users, err := ToMapByKey(repo.Query().Where(goqu.Ex{"id": []int64{1, 2}}), func(u *testUser) int64 { return u.ID })
+	if err != nil {
+		t.Fatalf("ToMapByKey failed: %v", err)
+	}
+	var _ map[int64]*testUser = users
+	if len(users) != 2 || users[1].UserName != "alice" || users[2].UserName != "bob" {
+		t.Errorf("Unexpected map: %+v", users)
+	}
+
+	expected := "SELECT `id`, `user_name` FROM `users` WHERE (`id` IN (1, 2))"
+	if queries := fake.Queries(); queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries[0])
+	}
+
+	fake.queueResult([]string{"id", "user_name"},
+		[]driver.Value{int64(1), "alice"},
+		[]driver.Value{int64(1), "alice again"})
+	if _, err := ToMapByKey(repo.Query(), func(u *testUser) int64 { return u.ID }); err == nil {
+		t.Error("Expected error for duplicate keys")
+	}
+}
+
+func TestQueryableWithTotalCount(t *testing.T) {
+	db, _ := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	sql, _, err := repo.Query().
+		Select(goqu.I("status"), goqu.COUNT("*").As("cnt")).
+		GroupByColumns("status").
+		WithTotalCount("total").
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `status`, COUNT(*) AS `cnt`, COUNT(*) OVER () AS `total` FROM `test_table` GROUP BY `status`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().WithTotalCount("total").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected = "SELECT `id`, `name`, `status`, COUNT(*) OVER () AS `total` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+}
+
+type testEntityBrief struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestSelect2(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"id", "name"},
+		[]driver.Value{int64(1), "a"},
+		[]driver.Value{int64(2), "b"})
+
+	var rows []testEntityBrief
+	if err := Select2(repo.Query().Where(goqu.Ex{"status": 1}), []string{"id", "name"}, &rows); err != nil {
+		t.Fatalf("Select2 failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0] != (testEntityBrief{ID: 1, Name: "a"}) || rows[1] != (testEntityBrief{ID: 2, Name: "b"}) {
+		t.Errorf("Unexpected rows: %+v", rows)
+	}
+
+	expected := "SELECT `id`, `name` FROM `test_table` WHERE (`status` = 1)"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	if err := Select2[TestEntity, testEntityBrief](repo.Query(), nil, &rows); err == nil {
+		t.Error("Expected error for empty column list")
+	}
+}
+
+type testEmployee struct {
+	ID        int64  `db:"id"`
+	Name      string `db:"name"`
+	ManagerID int64  `db:"manager_id"`
+}
+
+func TestQueryAsSelfJoin(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[testEmployee](db, "employees", MySQL)
+
+	sql, _, err := repo.QueryAs("e").
+		LeftJoinTable(goqu.T("employees").As("m"), goqu.I("m.id").Eq(goqu.I("e.manager_id"))).
+		Select(goqu.I("e.name"), goqu.I("m.name").As("manager_name")).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `e`.`name`, `m`.`name` AS `manager_name` FROM `employees` AS `e` LEFT JOIN `employees` AS `m` ON (`m`.`id` = `e`.`manager_id`)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	// 未指定 Select 时实体字段按别名限定
+	if _, err := repo.WithTenant("tenant_id", 7).QueryAs("e").
+		JoinTable(goqu.T("employees").As("m"), goqu.I("m.id").Eq(goqu.I("e.manager_id"))).
+		Where(goqu.Ex{"m.name": "boss"}).
+		ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected = "SELECT `e`.`id`, `e`.`name`, `e`.`manager_id` FROM `employees` AS `e` INNER JOIN `employees` AS `m` ON (`m`.`id` = `e`.`manager_id`) WHERE ((`e`.`tenant_id` = 7) AND (`m`.`name` = 'boss'))"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+}
+
+func TestQueryableWhereTupleIn(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, args, err := repo.Query().
+		WhereTupleIn([]string{"id", "status"}, [][]interface{}{{1, 2}, {3, 4}}).
+		Dataset().Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `test_table` WHERE (`id`, `status`) IN ((?, ?), (?, ?))"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+	if len(args) != 4 || args[0] != int64(1) || args[1] != int64(2) || args[2] != int64(3) || args[3] != int64(4) {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	sql, _, err = repo.Query().WhereTupleIn([]string{"id", "status"}, nil).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if sql != "SELECT * FROM `test_table` WHERE 1 = 0" {
+		t.Errorf("Expected a false predicate for empty tuples, got %q", sql)
+	}
+
+	if _, _, err := repo.Query().WhereTupleIn([]string{"id", "status"}, [][]interface{}{{1}}).ToSQL(); err == nil {
+		t.Error("Expected error for a tuple with the wrong arity")
+	}
+}
+
+func TestQueryableDistinctBy(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"id", "name", "status"}, []driver.Value{int64(9), "latest", int64(1)})
+
+	items, err := repo.Query().
+		Where(goqu.Ex{"status": []int{1, 2}}).
+		DistinctBy([]string{"status"}, "id DESC").
+		OrderBy("id").
+		ToList()
+	if err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "latest" {
+		t.Errorf("Unexpected items: %+v", items)
+	}
+
+	expected := "SELECT `id`, `name`, `status` FROM (" +
+		"SELECT `id`, `name`, `status`, ROW_NUMBER() OVER (PARTITION BY `status` ORDER BY `id` DESC) AS `_rn` " +
+		"FROM `test_table` WHERE (`status` IN (1, 2))) AS `d` " +
+		"WHERE (`_rn` = 1) ORDER BY `id` ASC"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	if _, _, err := repo.Query().DistinctBy(nil, "id").ToSQL(); err == nil {
+		t.Error("Expected error without partition columns")
+	}
+
+	// 外层查询使用与内层相同的方言，默认方言用双引号转义
+	sql, _, err := repo.Query().FromDataset(goqu.From("test_table")).DistinctBy([]string{"status"}, "id DESC").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if expected := `SELECT "id", "name", "status" FROM (SELECT`; !strings.HasPrefix(sql, expected) || strings.Contains(sql, "`") {
+		t.Errorf("Expected the outer query to keep the dataset's dialect, got %q", sql)
+	}
+}
+
+func TestQueryErrorWrapping(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	driverErr := errors.New("table is locked")
+	fake.queueExecErr(driverErr)
+	err := repo.Create(&TestEntity{ID: 1, Name: "a"})
+	if err == nil || err.Error() != "goqu-linq: Create on test_table: table is locked" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+	if !errors.Is(err, driverErr) {
+		t.Errorf("Expected errors.Is to find the driver error, got %v", err)
+	}
+
+	_, err = repo.Query().Where(goqu.Ex{"id": 1}).FirstOrDefault()
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Op != "FirstOrDefault" || queryErr.Table != "test_table" {
+		t.Errorf("Expected a QueryError for FirstOrDefault, got %v", err)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected errors.Is(err, sql.ErrNoRows), got %v", err)
+	}
+
+	// 分页内部调用 Count，错误只包装一次
+	_, err = repo.Query().WhereRaw("bad ?", make(chan int)).ToPagedList(1, 10, goqu.Ex{})
+	if err == nil || strings.Count(err.Error(), "goqu-linq:") != 1 {
+		t.Errorf("Expected a single wrap, got %v", err)
+	}
+}
+
+func TestQueryableCountByColumns(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueTypedResult([]string{"status", "name", "count"}, []string{"INT", "VARCHAR", "BIGINT"},
+		[]driver.Value{int64(1), []byte("a"), []byte("5")},
+		[]driver.Value{int64(2), []byte("b"), int64(3)})
+
+	groups, err := repo.Query().Where(goqu.Ex{"id": goqu.Op{"gt": 0}}).CountByColumns("status", "name")
+	if err != nil {
+		t.Fatalf("CountByColumns failed: %v", err)
+	}
+
+	expected := "SELECT `status`, `name`, COUNT(*) AS `count` FROM `test_table` WHERE (`id` > 0) GROUP BY `status`, `name` ORDER BY `count` DESC"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Count != 5 || groups[0].Groups["status"] != int64(1) || groups[0].Groups["name"] != "a" {
+		t.Errorf("Unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Count != 3 || groups[1].Groups["name"] != "b" {
+		t.Errorf("Unexpected second group: %+v", groups[1])
+	}
+}
+
+func TestQueryableRows(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"month", "total"},
+		[]driver.Value{"2024-01", int64(10)},
+		[]driver.Value{"2024-02", int64(20)})
+
+	rows, err := repo.Query().SelectRaw("month", "SUM(status) AS total").GroupByColumns("month").Rows()
+	if err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	defer rows.Close()
+
+	var months []string
+	var total int64
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			t.Fatalf("SliceScan failed: %v", err)
+		}
+		months = append(months, values[0].(string))
+		total += values[1].(int64)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %v", err)
+	}
+	if len(months) != 2 || months[0] != "2024-01" || total != 30 {
+		t.Errorf("Unexpected rows: %v, total %d", months, total)
+	}
+
+	expected := "SELECT month, SUM(status) AS total FROM `test_table` GROUP BY `month`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+}
+
+func TestQueryableWhereOperators(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	ctx := context.Background()
+
+	cases := []struct {
+		query    IQueryable[TestEntity]
+		expected string
+	}{
+		{repo.Query().WhereEq("status", 1), "(`status` = 1)"},
+		{repo.Query().WhereEq("name", nil), "(`name` IS NULL)"},
+		{repo.Query().WhereNe("status", 1), "(`status` != 1)"},
+		{repo.Query().WhereGt("id", 10), "(`id` > 10)"},
+		{repo.Query().WhereGte("id", 10), "(`id` >= 10)"},
+		{repo.Query().WhereLt("id", 10), "(`id` < 10)"},
+		{repo.Query().WhereLte("id", 10), "(`id` <= 10)"},
+		{repo.Query().WhereGteTx(ctx, "id", 1).WhereLtTx(ctx, "id", 5), "((`id` >= 1) AND (`id` < 5))"},
+		{repo.Query().WhereEqTx(ctx, "status", 1).WhereNeTx(ctx, "name", "a"), "((`status` = 1) AND (`name` != 'a'))"},
+	}
+	for _, c := range cases {
+		sql, _, err := c.query.ToSQL()
+		if err != nil {
+			t.Fatalf("ToSQL failed: %v", err)
+		}
+		expected := "SELECT * FROM `test_table` WHERE " + c.expected
+		if sql != expected {
+			t.Errorf("Expected SQL %q, got %q", expected, sql)
+		}
+	}
+}
+
+func TestSelectCoalesce(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	sql, args, err := repo.Query().Select("id").
+		SelectCoalesce("nickname", "anonymous", "display_name").
+		Dataset().Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `id`, COALESCE(`nickname`, ?) AS `display_name` FROM `test_table`"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"anonymous"}) {
+		t.Errorf("Expected fallback to be bound, got %#v", args)
+	}
+
+	// 未指定 Select 时保留实体字段，同名字段由 COALESCE 代替
+	fake.queueResult([]string{"id", "status", "name"}, []driver.Value{int64(1), int64(0), ""})
+	items, err := repo.Query().SelectCoalesce("name", "", "name").ToList()
+	if err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	expected = "SELECT `id`, `status`, COALESCE(`name`, '') AS `name` FROM `test_table`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Errorf("Unexpected items: %v", items)
+	}
+}
+
+func TestToInterpolatedSQL(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	created := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+
+	q := repo.Query().
+		Where(goqu.Ex{"name": "O'Brien", "status": 2}).
+		WhereGte("created_at", created).
+		WhereRaw("score > ?", 1.5)
+	q.(*Queryable[TestEntity]).query = q.Dataset().Prepared(true)
+
+	sql, err := q.ToInterpolatedSQL()
+	if err != nil {
+		t.Fatalf("ToInterpolatedSQL failed: %v", err)
+	}
+	expected := "SELECT `id`, `name`, `status` FROM `test_table` WHERE (((`name` = 'O\\'Brien') AND (`status` = 2)) AND (`created_at` >= '2024-05-01 08:30:00') AND score > 1.5)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+	if strings.Contains(sql, "?") {
+		t.Errorf("Expected no placeholders, got %q", sql)
+	}
+
+	if _, err := repo.Query().WhereRaw("bad ?", make(chan int)).ToInterpolatedSQL(); err == nil {
+		t.Error("Expected error for an unsupported argument")
+	}
+	// 与 ToList 实际执行的语句一致，包括 JOIN 时按表名限定的列
+	db, fake := newFakeDBLogger(t)
+	joined := NewRepository[TestEntity](db, "test_table", MySQL).Query().
+		Join("orders", map[string]string{"orders.user_id": "test_table.id"}).
+		Where(goqu.Ex{"orders.status": 2})
+	sql, err = joined.ToInterpolatedSQL()
+	if err != nil {
+		t.Fatalf("ToInterpolatedSQL failed: %v", err)
+	}
+	if _, err := joined.ToList(); err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != sql {
+		t.Errorf("Expected ToInterpolatedSQL %q to match the executed SQL %v", sql, queries)
+	}
+}
+
+func TestQueryableWithContext(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.Query().WithContext(ctx).ToList(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled context to abort ToList, got %v", err)
+	}
+	if _, err := repo.Query().WithContext(ctx).Count(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled context to abort Count, got %v", err)
+	}
+	if len(fake.Queries()) != 0 {
+		t.Errorf("Expected no queries to run, got %v", fake.Queries())
+	}
+
+	fake.setQueryDelay(time.Second)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := repo.Query().WithContext(ctx).ScanInt64Slice(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the stashed deadline to abort a running query, got %v", err)
+	}
+
+	fake.setQueryDelay(0)
+	if _, err := repo.Query().ToList(); err != nil {
+		t.Errorf("Expected ToList without a context to succeed, got %v", err)
+	}
+}
+
+func TestQueryableWithTimeout(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.setQueryDelay(time.Second)
+
+	start := time.Now()
+	_, err := repo.Query().WithTimeout(10 * time.Millisecond).ToList()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected timeout to abort the query early, took %v", elapsed)
+	}
+
+	// 调用方 ctx 的截止时间更早时以 ctx 为准
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = repo.Query().WithTimeout(time.Minute).CountTx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected caller deadline to win, took %v", elapsed)
+	}
+
+	fake.setQueryDelay(0)
+	if _, err := repo.Query().WithTimeout(time.Second).ToList(); err != nil {
+		t.Errorf("Expected query within timeout to succeed, got %v", err)
+	}
+}
+
+func TestQueryableWhereGroup(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, _, err := repo.Query().
+		WhereEq("status", 1).
+		WhereGroup(func(g ConditionGroup) {
+			g.And(goqu.C("a").Eq(1), goqu.C("b").Eq(2)).
+				OrGroup(func(g ConditionGroup) {
+					g.And(goqu.C("c").Eq(3)).
+						AndGroup(func(g ConditionGroup) {
+							g.Or(goqu.C("d").Eq(4)).Or(goqu.C("e").Eq(5))
+						})
+				})
+		}).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `test_table` WHERE ((`status` = 1) AND " +
+		"(((`a` = 1) AND (`b` = 2)) OR ((`c` = 3) AND ((`d` = 4) OR (`e` = 5)))))"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, err = repo.Query().WhereGroup(func(g ConditionGroup) {}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if expected := "SELECT * FROM `test_table`"; sql != expected {
+		t.Errorf("Expected empty group to add no condition, got %q", sql)
+	}
+}
+
+func TestGroupingQuerySelect(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"id", "name", "status"},
+		[]driver.Value{int64(1), "a", int64(2)},
+		[]driver.Value{int64(2), "b", int64(1)},
+		[]driver.Value{int64(3), "c", int64(2)},
+	)
+
+	type statusGroup struct {
+		Status int
+		Names  []string
+	}
+	results, err := repo.Query().
+		Where(goqu.Ex{"id": goqu.Op{"gt": 0}}).
+		GroupBy(func(e TestEntity) interface{} { return e.Status }).
+		Select(func(key interface{}, elements []TestEntity) interface{} {
+			g := statusGroup{Status: key.(int)}
+			for _, e := range elements {
+				g.Names = append(g.Names, e.Name)
+			}
+			return g
+		})
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	expected := []interface{}{
+		statusGroup{Status: 2, Names: []string{"a", "c"}},
+		statusGroup{Status: 1, Names: []string{"b"}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, results)
+	}
+
+	query := "SELECT `id`, `name`, `status` FROM `test_table` WHERE (`id` > 0)"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != query {
+		t.Errorf("Expected SQL %q, got %v", query, queries)
+	}
+}
+
+func TestQueryableOrderByAggregate(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	sql, _, err := repo.Query().
+		Where(goqu.Ex{"status": goqu.Op{"gt": 0}}).
+		GroupByColumns("name").
+		Select(goqu.I("name"), goqu.SUM("status").As("total")).
+		OrderByAggregate("total", true).
+		Take(3).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `name`, SUM(`status`) AS `total` FROM `test_table` WHERE (`status` > 0) " +
+		"GROUP BY `name` ORDER BY `total` DESC LIMIT 3"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	_, _, err = repo.Query().
+		GroupByColumns("name").
+		Select(goqu.I("name"), goqu.SUM("status").As("total")).
+		OrderByAggregate("sum", false).
+		ToSQL()
+	if err == nil {
+		t.Error("Expected error for an unknown select alias")
+	}
+}
+
+func TestQueryableCountIgnoresPaging(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"COUNT(*)"}, []driver.Value{int64(42)})
+	fake.queueResult([]string{"COUNT(*)"}, []driver.Value{int64(42)})
+
+	q := repo.Query().Where(goqu.Ex{"status": 1}).OrderBy("name").Skip(20).Take(10)
+	count, err := q.Count()
+	if err != nil || count != 42 {
+		t.Errorf("Expected count 42 ignoring the limit, got %d, %v", count, err)
+	}
+	count, err = q.CountTx(context.Background())
+	if err != nil || count != 42 {
+		t.Errorf("Expected CountTx 42 ignoring the limit, got %d, %v", count, err)
+	}
+
+	expected := "SELECT COUNT(*) FROM `test_table` WHERE (`status` = 1)"
+	if queries := fake.Queries(); !reflect.DeepEqual(queries, []string{expected, expected}) {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+	if sql, _, _ := q.ToSQL(); !strings.Contains(sql, "LIMIT 10 OFFSET 20") {
+		t.Errorf("Expected Count to leave the query unchanged, got %q", sql)
+	}
+}
+
+func TestQueryablePaginate(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	q := repo.Query().Where(goqu.Ex{"status": 1})
+
+	tests := []struct {
+		page, size int
+		total      int64
+		rows       int
+		limit      string
+		expected   Page[TestEntity]
+	}{
+		{1, 10, 25, 10, "LIMIT 10", Page[TestEntity]{Page: 1, PageSize: 10, TotalPages: 3, HasNext: true}},
+		{2, 10, 25, 10, "LIMIT 10 OFFSET 10", Page[TestEntity]{Page: 2, PageSize: 10, TotalPages: 3, HasNext: true, HasPrev: true}},
+		{3, 10, 25, 5, "LIMIT 10 OFFSET 20", Page[TestEntity]{Page: 3, PageSize: 10, TotalPages: 3, HasPrev: true}},
+		{4, 10, 25, 0, "LIMIT 10 OFFSET 30", Page[TestEntity]{Page: 4, PageSize: 10, TotalPages: 3, HasPrev: true}},
+		{0, 10, 25, 10, "LIMIT 10", Page[TestEntity]{Page: 1, PageSize: 10, TotalPages: 3, HasNext: true}},
+		{-3, 0, 2, 1, "LIMIT 1", Page[TestEntity]{Page: 1, PageSize: 1, TotalPages: 2, HasNext: true}},
+		{1, 10, 0, 0, "LIMIT 10", Page[TestEntity]{Page: 1, PageSize: 10}},
+	}
+	for _, tt := range tests {
+		rows := make([][]driver.Value, tt.rows)
+		for i := range rows {
+			rows[i] = []driver.Value{int64(i + 1), "n", int64(1)}
+		}
+		fake.queueResult([]string{"COUNT(*)"}, []driver.Value{tt.total})
+		fake.queueResult([]string{"id", "name", "status"}, rows...)
+
+		result, err := q.Paginate(tt.page, tt.size)
+		if err != nil {
+			t.Fatalf("Paginate(%d, %d) failed: %v", tt.page, tt.size, err)
+		}
+		if len(result.Items) != tt.rows {
+			t.Errorf("Paginate(%d, %d): expected %d items, got %d", tt.page, tt.size, tt.rows, len(result.Items))
+		}
+		tt.expected.Items, tt.expected.Total = result.Items, tt.total
+		if !reflect.DeepEqual(*result, tt.expected) {
+			t.Errorf("Paginate(%d, %d) = %+v, expected %+v", tt.page, tt.size, *result, tt.expected)
+		}
+
+		queries := fake.Queries()
+		queries = queries[len(queries)-2:]
+		expected := []string{
+			"SELECT COUNT(*) FROM `test_table` WHERE (`status` = 1)",
+			"SELECT `id`, `name`, `status` FROM `test_table` WHERE (`status` = 1) ORDER BY `id` ASC " + tt.limit,
+		}
+		if !reflect.DeepEqual(queries, expected) {
+			t.Errorf("Paginate(%d, %d): expected SQL %q, got %q", tt.page, tt.size, expected, queries)
+		}
+	}
+
+	if q.HasOrder() || q.HasLimit() {
+		t.Error("Expected Paginate to leave the base query unchanged")
+	}
+}
+
+func TestQueryableMaxByMinBy(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	columns := []string{"id", "name", "status"}
+	fake.queueResult(columns, []driver.Value{int64(4), "top", int64(9)})
+	fake.queueResult(columns, []driver.Value{int64(2), "bottom", int64(1)})
+	fake.queueResult(columns)
+
+	top, err := repo.Query().Where(goqu.Ex{"name": goqu.Op{"neq": ""}}).MaxBy("status")
+	if err != nil || top.ID != 4 || top.Status != 9 {
+		t.Errorf("Expected the row with the highest status, got %+v, %v", top, err)
+	}
+	bottom, err := repo.Query().MinBy("status")
+	if err != nil || bottom.ID != 2 || bottom.Status != 1 {
+		t.Errorf("Expected the row with the lowest status, got %+v, %v", bottom, err)
+	}
+	if _, err := repo.Query().MaxBy("status"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for no rows, got %v", err)
+	}
+
+	expected := []string{
+		"SELECT `id`, `name`, `status` FROM `test_table` WHERE ((`name` != '') AND (`status` IS NOT NULL)) ORDER BY `status` DESC, `id` ASC LIMIT 1",
+		"SELECT `id`, `name`, `status` FROM `test_table` WHERE (`status` IS NOT NULL) ORDER BY `status` ASC, `id` ASC LIMIT 1",
+		"SELECT `id`, `name`, `status` FROM `test_table` WHERE (`status` IS NOT NULL) ORDER BY `status` DESC, `id` ASC LIMIT 1",
+	}
+	if queries := fake.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected SQL %v, got %v", expected, queries)
+	}
+
+	// MaxBy 不修改基础查询，之后仍可用于其他查询
+	base := repo.Query().Where(goqu.Ex{"status": goqu.Op{"gt": 0}})
+	fake.queueResult(columns, []driver.Value{int64(4), "top", int64(9)})
+	fake.queueResult(columns, []driver.Value{int64(4), "top", int64(9)}, []driver.Value{int64(2), "bottom", int64(1)})
+	if _, err := base.MaxBy("status"); err != nil {
+		t.Fatalf("MaxBy failed: %v", err)
+	}
+	items, err := base.ToList()
+	if err != nil || len(items) != 2 {
+		t.Errorf("Expected both rows from the base query, got %v, %v", items, err)
+	}
+	queries := fake.Queries()
+	if last := queries[len(queries)-1]; last != "SELECT `id`, `name`, `status` FROM `test_table` WHERE (`status` > 0)" {
+		t.Errorf("Expected the base query without MaxBy's clauses, got %q", last)
+	}
+	if base.HasOrder() || base.HasLimit() {
+		t.Error("Expected MaxBy to leave the base query flags unchanged")
+	}
+}
+
+func TestQueryableEstimatedCount(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"TABLE_ROWS"}, []driver.Value{int64(12000000)})
+	fake.queueResult([]string{"COUNT(*)"}, []driver.Value{int64(42)})
+	fake.queueResult([]string{"TABLE_ROWS"}, []driver.Value{nil})
+	fake.queueResult([]string{"COUNT(*)"}, []driver.Value{int64(7)})
+
+	count, err := repo.Query().EstimatedCount()
+	if err != nil || count != 12000000 {
+		t.Errorf("Expected estimated 12000000 rows, got %d, %v", count, err)
+	}
+	count, err = repo.Query().Where(goqu.Ex{"status": 1}).EstimatedCount()
+	if err != nil || count != 42 {
+		t.Errorf("Expected exact count 42 with a filter, got %d, %v", count, err)
+	}
+	count, err = repo.Query().EstimatedCount()
+	if err != nil || count != 7 {
+		t.Errorf("Expected exact count 7 without statistics, got %d, %v", count, err)
+	}
+
+	expected := []string{
+		"SELECT `TABLE_ROWS` FROM `information_schema`.`TABLES` WHERE ((`TABLE_NAME` = 'test_table') AND (`TABLE_SCHEMA` = DATABASE()))",
+		"SELECT COUNT(*) FROM `test_table` WHERE (`status` = 1)",
+		"SELECT `TABLE_ROWS` FROM `information_schema`.`TABLES` WHERE ((`TABLE_NAME` = 'test_table') AND (`TABLE_SCHEMA` = DATABASE()))",
+		"SELECT COUNT(*) FROM `test_table`",
+	}
+	if queries := fake.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected SQL %v, got %v", expected, queries)
+	}
+	// 带库名的表按库名和表名分别匹配
+	fake.queueResult([]string{"TABLE_ROWS"}, []driver.Value{int64(500)})
+	qualified := NewRepository[TestEntity](db, "analytics.test_table", MySQL)
+	if count, err = qualified.Query().EstimatedCount(); err != nil || count != 500 {
+		t.Errorf("Expected estimated 500 rows for a qualified table, got %d, %v", count, err)
+	}
+	want := "SELECT `TABLE_ROWS` FROM `information_schema`.`TABLES` WHERE ((`TABLE_NAME` = 'test_table') AND (`TABLE_SCHEMA` = 'analytics'))"
+	if queries := fake.Queries(); queries[len(queries)-1] != want {
+		t.Errorf("Expected SQL %q, got %q", want, queries[len(queries)-1])
+	}
+}
+
+func TestGroupingQueryCountDistinct(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	byStatus := func(e TestEntity) interface{} { return e.Status }
+	fake.queueResult([]string{"name", "count"},
+		[]driver.Value{[]byte("books"), int64(3)},
+		[]driver.Value{[]byte("games"), int64(1)},
+	)
+
+	counts, err := repo.Query().Where(goqu.Ex{"status": 1}).GroupByColumns("name").GroupBy(byStatus).CountDistinct("id")
+	if err != nil {
+		t.Fatalf("CountDistinct failed: %v", err)
+	}
+	if !reflect.DeepEqual(counts, map[interface{}]int64{"books": 3, "games": 1}) {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+	expected := "SELECT `name`, COUNT(DISTINCT `id`) AS `count` FROM `test_table` WHERE (`status` = 1) GROUP BY `name`"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+
+	if _, err := repo.Query().GroupBy(byStatus).CountDistinct("id"); err == nil {
+		t.Error("Expected error without GroupByColumns")
+	}
+}
+
+func TestGroupingQueryHavingAggregate(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	byStatus := func(e TestEntity) interface{} { return e.Status }
+
+	// GroupBy 在查询的副本上分组，通过 parent 取得分组后的 SQL
+	groupingSQL := func(g IGroupingQuery[TestEntity]) (string, []interface{}, error) {
+		return g.(*GroupingQuery[TestEntity]).parent.Dataset().Prepared(true).ToSQL()
+	}
+
+	q := repo.Query().GroupByColumns("status").Select("status", goqu.SUM("amount").As("total"))
+	sql, args, err := groupingSQL(q.GroupBy(byStatus).HavingAggregate("SUM", "amount", ">=", 100).HavingAggregate("count", "*", ">", 5))
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT `status`, SUM(`amount`) AS `total` FROM `test_table` GROUP BY `status` " +
+		"HAVING (SUM(`amount`) >= ? AND COUNT(*) > ?)"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{int64(100), int64(5)}) {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	q = repo.Query().GroupByColumns("status")
+	if _, _, err := groupingSQL(q.GroupBy(byStatus).HavingAggregate("SLEEP", "amount", ">", 1)); err == nil {
+		t.Error("Expected error for an unsupported aggregate function")
+	}
+
+	q = repo.Query().GroupByColumns("status")
+	if _, _, err := groupingSQL(q.GroupBy(byStatus).HavingAggregate("SUM", "amount", "> 0 OR 1 =", 1)); err == nil {
+		t.Error("Expected error for an unsupported operator")
+	}
+}
+
+func TestQueryableWhereColumns(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+
+	cases := []struct {
+		query    IQueryable[TestEntity]
+		expected string
+	}{
+		{repo.Query().WhereColumns("updated_at", ">", "created_at"), "(`updated_at` > `created_at`)"},
+		{repo.Query().WhereColumns("a.x", "=", "b.y"), "(`a`.`x` = `b`.`y`)"},
+		{repo.Query().WhereColumns("a", "<>", "b"), "(`a` != `b`)"},
+		{repo.Query().WhereColumns("a", "<=", "b").WhereColumns("b", "<", "c"), "((`a` <= `b`) AND (`b` < `c`))"},
+	}
+	for _, c := range cases {
+		sql, args, err := c.query.(*Queryable[TestEntity]).Dataset().Prepared(true).ToSQL()
+		if err != nil {
+			t.Fatalf("ToSQL failed: %v", err)
+		}
+		if expected := "SELECT * FROM `test_table` WHERE " + c.expected; sql != expected {
+			t.Errorf("Expected SQL %q, got %q", expected, sql)
+		}
+		if len(args) != 0 {
+			t.Errorf("Expected no bound args for column comparison, got %v", args)
+		}
+	}
+
+	if _, _, err := repo.Query().WhereColumns("a", "LIKE", "b").ToSQL(); err == nil {
+		t.Error("Expected error for an unsupported operator")
+	}
+}
@@ -2,12 +2,18 @@ package core
 
 import (
 	"context"
+	stdsql "database/sql"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 type DialectType string
@@ -24,25 +30,150 @@ type Repository[T any] struct {
 	dialect goqu.DialectWrapper
 	dbType  DialectType
 	uow     IUnitOfWork // 工作单元
+
+	tenantColumn string      // 租户隔离字段
+	tenantValue  interface{} // 租户隔离字段的值
+
+	nameMapper NameMapper       // 没有 db tag 的字段的列名映射，为空时使用 DefaultNameMapper
+	scanMapper *reflectx.Mapper // 扫描查询结果时与 nameMapper 一致的 sqlx 映射
+	timestamps bool             // 是否自动维护 created_at/updated_at
+
+	shards *ShardResolver[T] // 分库路由，见 WithShards
 }
 
 func (r *Repository[T]) WithUnitOfWork(uow IUnitOfWork) *Repository[T] {
-	return &Repository[T]{
-		db:      r.db,
-		table:   r.table,
-		dialect: r.dialect,
-		dbType:  r.dbType,
-		uow:     uow,
+	clone := *r
+	clone.uow = uow
+	return &clone
+}
+
+// WithNameMapper 返回使用指定字段名映射的仓储副本，映射只作用于没有 db tag 的字段
+func (r *Repository[T]) WithNameMapper(mapper NameMapper) *Repository[T] {
+	clone := *r
+	clone.nameMapper = mapper
+	clone.scanMapper = scanMapper(mapper)
+	return &clone
+}
+
+// WithTimestamps 返回自动维护时间戳的仓储副本
+// 插入时设置 created_at 和 updated_at，更新时设置 updated_at，支持 int64 Unix 秒和 time.Time 字段
+// 实体实现 Timestamped 时调用其方法代替反射赋值
+func (r *Repository[T]) WithTimestamps() *Repository[T] {
+	clone := *r
+	clone.timestamps = true
+	return &clone
+}
+
+// WithTenant 返回一个自动附加租户条件的仓储副本
+// 之后所有的查询、更新和删除都会自动追加 column = value 条件，防止跨租户读写
+func (r *Repository[T]) WithTenant(column string, value interface{}) *Repository[T] {
+	clone := *r
+	clone.tenantColumn = column
+	clone.tenantValue = value
+	return &clone
+}
+
+// tenantWhere 返回租户隔离条件，未设置租户时返回空
+func (r *Repository[T]) tenantWhere() []exp.Expression {
+	if r.tenantColumn == "" {
+		return nil
+	}
+	return []exp.Expression{goqu.Ex{r.tenantColumn: r.tenantValue}}
+}
+
+// QueryAs 使用表别名构造查询，用于自连接等同一张表出现多次的场景，例如:
+//
+//	repo.QueryAs("e").
+//	    LeftJoinTable(goqu.T("employees").As("m"), goqu.I("m.id").Eq(goqu.I("e.manager_id"))).
+//	    Select(goqu.I("e.name"), goqu.I("m.name").As("manager_name"))
+func (r *Repository[T]) QueryAs(alias string) IQueryable[T] {
+	var tenant []exp.Expression
+	if r.tenantColumn != "" {
+		tenant = []exp.Expression{goqu.Ex{alias + "." + r.tenantColumn: r.tenantValue}}
+	}
+	return &Queryable[T]{
+		db:         r.readDB(),
+		query:      r.dialect.From(goqu.T(r.table).As(alias)).Where(tenant...),
+		dbType:     r.dbType,
+		nameMapper: r.nameMapper,
+		table:      r.table,
+		alias:      alias,
+		tenant:     tenant,
 	}
 }
-func (r *Repository[T]) CreateWithTx(entity *T) error {
-	query := r.dialect.Insert(r.table).Rows(entity)
+
+// JoinQuery 内连接 table 并将结果扫描为组合结构体 J，on 的写法与 Queryable.Join 相同
+// J 的 db tag 使用 "表名.列名" 指定列所属的表，如 `db:"orders.total"`，查询时生成
+// `orders`.`total` AS `orders.total`；没有表名前缀的 tag 视为主表的列。例如:
+//
+//	type UserOrder struct {
+//	    ID    int64   `db:"id"`           // users.id
+//	    Name  string  `db:"name"`         // users.name
+//	    Total float64 `db:"orders.total"` // orders.total
+//	}
+//	core.JoinQuery[UserOrder](users, "orders", map[string]string{"orders.user_id": "users.id"}).ToList()
+func JoinQuery[J any, T any](r *Repository[T], table string, on map[string]string) IQueryable[J] {
+	q := &Queryable[J]{
+		db:         r.readDB(),
+		dbType:     r.dbType,
+		nameMapper: r.nameMapper,
+		table:      r.table,
+	}
+
+	columns := q.getStructDBFields()
+	for i, column := range columns {
+		name := column.(string)
+		if strings.Contains(name, ".") {
+			columns[i] = goqu.I(name).As(goqu.C(name))
+		} else {
+			columns[i] = goqu.T(r.table).Col(name)
+		}
+	}
+
+	if r.tenantColumn != "" {
+		q.tenant = []exp.Expression{goqu.Ex{r.table + "." + r.tenantColumn: r.tenantValue}}
+	}
+	query := r.dialect.From(r.table).InnerJoin(goqu.T(table), goqu.On(joinCondition(on))).Where(q.tenant...)
+	if len(columns) > 0 {
+		query = query.Select(columns...)
+	}
+	q.query = query
+	return q
+}
+
+// selectFrom 构造带租户条件的查询
+func (r *Repository[T]) selectFrom() *goqu.SelectDataset {
+	return r.dialect.From(r.table).Where(r.tenantWhere()...)
+}
+
+// selectEntity 构造带租户条件并显式选择实体字段的查询，字段推导与 Queryable 一致
+func (r *Repository[T]) selectEntity() *goqu.SelectDataset {
+	q := &Queryable[T]{query: r.selectFrom(), nameMapper: r.nameMapper}
+	q.ensureSelectFields()
+	return q.query
+}
+
+// updateTable 构造带租户条件的更新
+func (r *Repository[T]) updateTable() *goqu.UpdateDataset {
+	return r.dialect.Update(r.table).Where(r.tenantWhere()...)
+}
+
+// deleteFrom 构造带租户条件的删除
+func (r *Repository[T]) deleteFrom() *goqu.DeleteDataset {
+	return r.dialect.Delete(r.table).Where(r.tenantWhere()...)
+}
+func (r *Repository[T]) CreateWithTx(entity *T) (err error) {
+	defer wrapQueryError("CreateWithTx", r.table, &err)
+	if err := r.beforeInsert(entity); err != nil {
+		return err
+	}
+	query := r.dialect.Insert(r.table).Rows(r.entityRecord(entity))
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
 	if r.uow != nil {
-		_, err = r.uow.GetTx().Exec(sql, args...)
+		_, err = r.uow.Exec(sql, args...)
 	} else {
 		_, err = r.db.Exec(sql, args...)
 	}
@@ -50,14 +181,16 @@ func (r *Repository[T]) CreateWithTx(entity *T) error {
 }
 
 // UpdateWithTx(entity)
-func (r *Repository[T]) UpdateWithTx(entity *T) error {
-	query := r.dialect.Update(r.table).Set(entity)
+func (r *Repository[T]) UpdateWithTx(entity *T) (err error) {
+	defer wrapQueryError("UpdateWithTx", r.table, &err)
+	r.touchUpdated(entity)
+	query := r.updateTable().Set(r.entityRecord(entity))
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
 	if r.uow != nil {
-		_, err = r.uow.GetTx().Exec(sql, args...)
+		_, err = r.uow.Exec(sql, args...)
 	} else {
 		_, err = r.db.Exec(sql, args...)
 	}
@@ -65,14 +198,16 @@ func (r *Repository[T]) UpdateWithTx(entity *T) error {
 }
 
 // UpdateByConditionWithTx
-func (r *Repository[T]) UpdateByConditionWithTx(condition goqu.Ex, entity *T) error {
-	query := r.dialect.Update(r.table).Set(entity).Where(condition)
+func (r *Repository[T]) UpdateByConditionWithTx(condition goqu.Ex, entity *T) (err error) {
+	defer wrapQueryError("UpdateByConditionWithTx", r.table, &err)
+	r.touchUpdated(entity)
+	query := r.updateTable().Set(r.entityRecord(entity)).Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
 	if r.uow != nil {
-		_, err = r.uow.GetTx().Exec(sql, args...)
+		_, err = r.uow.Exec(sql, args...)
 	} else {
 		_, err = r.db.Exec(sql, args...)
 	}
@@ -80,7 +215,9 @@ func (r *Repository[T]) UpdateByConditionWithTx(condition goqu.Ex, entity *T) er
 }
 
 // UpdateFieldsByConditionWithTx
-func (r *Repository[T]) UpdateFieldsByConditionWithTx(condition goqu.Ex, fields map[string]interface{}) error {
+func (r *Repository[T]) UpdateFieldsByConditionWithTx(condition goqu.Ex, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsByConditionWithTx", r.table, &err)
+	fields = r.touchFields(fields)
 	updateExp := make(map[string]interface{})
 	for field, value := range fields {
 		if _, ok := value.(exp.LiteralExpression); ok {
@@ -89,13 +226,13 @@ func (r *Repository[T]) UpdateFieldsByConditionWithTx(condition goqu.Ex, fields
 			updateExp[field] = value
 		}
 	}
-	query := r.dialect.Update(r.table).Set(updateExp).Where(condition)
+	query := r.updateTable().Set(updateExp).Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
 	if r.uow != nil {
-		_, err = r.uow.GetTx().Exec(sql, args...)
+		_, err = r.uow.Exec(sql, args...)
 	} else {
 		_, err = r.db.Exec(sql, args...)
 	}
@@ -103,35 +240,61 @@ func (r *Repository[T]) UpdateFieldsByConditionWithTx(condition goqu.Ex, fields
 }
 
 // BatchDeleteWithTx
-func (r *Repository[T]) BatchDeleteWithTx(condition goqu.Ex) error {
-	query := r.dialect.Delete(r.table).Where(condition)
+func (r *Repository[T]) BatchDeleteWithTx(condition goqu.Ex) (err error) {
+	defer wrapQueryError("BatchDeleteWithTx", r.table, &err)
+	query := r.deleteFrom().Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
 	if r.uow != nil {
-		_, err = r.uow.GetTx().Exec(sql, args...)
+		_, err = r.uow.Exec(sql, args...)
 	} else {
 		_, err = r.db.Exec(sql, args...)
 	}
 	return err
 }
 
+// readDB 返回读操作使用的连接，存在工作单元时在事务中读取（事务未开始时读主库），保证事务内读写一致
+// 扫描结果时按仓储的 nameMapper 映射没有 db tag 的字段
+func (r *Repository[T]) readDB() *DBLogger {
+	if r.uow != nil && r.db != nil {
+		if tx := r.uow.GetTx(); tx != nil {
+			return r.db.InTx(tx).withMapper(r.scanMapper)
+		}
+		return r.db.Primary().withMapper(r.scanMapper)
+	}
+	return r.db.withMapper(r.scanMapper)
+}
+
 // Query returns a queryable interface for building queries
 func (r *Repository[T]) Query() IQueryable[T] {
 	return &Queryable[T]{
-		db:    r.db,
-		query: r.dialect.From(r.table),
+		db:         r.readDB(),
+		query:      r.selectFrom(),
+		dbType:     r.dbType,
+		nameMapper: r.nameMapper,
+		table:      r.table,
+		tenant:     r.tenantWhere(),
 	}
 }
 
 func (r *Repository[T]) QueryFrom(dbType DialectType) IQueryable[T] {
 	return &Queryable[T]{
-		db:    r.db,
-		query: goqu.Dialect("mysql").From(r.table),
+		db:         r.readDB(),
+		query:      goqu.Dialect("mysql").From(r.table).Where(r.tenantWhere()...),
+		dbType:     dbType,
+		nameMapper: r.nameMapper,
+		table:      r.table,
+		tenant:     r.tenantWhere(),
 	}
 }
 
+// checkDB 检查数据库连接，避免在 sqlx 内部出现空指针 panic
+func (r *Repository[T]) checkDB() error {
+	return r.db.ready()
+}
+
 // 获取db
 func (r *Repository[T]) GetDB() *DBLogger {
 	return r.db
@@ -142,7 +305,13 @@ func (r *Repository[T]) GetPrefix() string {
 	return r.db.prefix
 }
 
+// NewRepository 创建仓储，dbType 为空时使用 db 的方言（见 NewDBLoggerWithDialect）
+// table 为空时使用实体的 TableName()（见 TableNamer），两者都没有时 panic
 func NewRepository[T any](db *DBLogger, table string, dbType DialectType) *Repository[T] {
+	table = resolveTable[T]("NewRepository", table)
+	if dbType == "" {
+		dbType = db.Dialect()
+	}
 	var dialect goqu.DialectWrapper
 	switch dbType {
 	case StarRocks:
@@ -151,21 +320,66 @@ func NewRepository[T any](db *DBLogger, table string, dbType DialectType) *Repos
 		dialect = goqu.Dialect("mysql")
 	}
 	return &Repository[T]{
-		db:      db,
-		table:   table,
-		dialect: dialect,
-		dbType:  dbType,
+		db:         db,
+		table:      table,
+		dialect:    dialect,
+		dbType:     dbType,
+		scanMapper: scanMapper(nil),
+	}
+}
+
+// NewRepositoryTx 创建绑定到工作单元的仓储，写操作和 Query() 都在 uow 的事务中执行
+// 用于在 RunInTransaction 中按需创建其他实体的仓储，无需传递已有的仓储实例：
+//
+//	uow.RunInTransaction(func(tx core.IUnitOfWork) error {
+//	    orders := core.NewRepositoryTx[Order](tx, "orders", core.MySQL)
+//	    items := core.NewRepositoryTx[OrderItem](tx, "order_items", core.MySQL)
+//	    ...
+//	})
+func NewRepositoryTx[T any](uow IUnitOfWork, table string, dbType DialectType) *Repository[T] {
+	return NewRepository[T](uow.DB(), table, dbType).WithUnitOfWork(uow)
+}
+
+// resolveTable 返回仓储的表名，table 为空时取实体的 TableName()，两者都没有时 panic
+func resolveTable[T any](constructor, table string) string {
+	if table == "" {
+		table = entityTableName[T]()
+	}
+	if table == "" {
+		var entity T
+		panic(fmt.Sprintf("goqu-linq: %s[%T] requires a table name or a TableName() method", constructor, entity))
+	}
+	return table
+}
+
+// NewRepositoryWithPrefix 创建仓储，并在表名前拼接 db 的前缀
+// 例如前缀为 "app1_"、表名为 "users" 时，所有生成的 SQL（包括手动拼接的批量语句）都使用 "app1_users"
+func NewRepositoryWithPrefix[T any](db *DBLogger, table string, dbType DialectType) *Repository[T] {
+	table = resolveTable[T]("NewRepositoryWithPrefix", table)
+	if db != nil {
+		table = db.prefix + table
 	}
+	return NewRepository[T](db, table, dbType)
 }
 
-func (r *Repository[T]) Create(entity *T) error {
+func (r *Repository[T]) Create(entity *T) (err error) {
+	defer wrapQueryError("Create", r.table, &err)
+	if shard := r.Shard(entity); shard != r {
+		return shard.Create(entity)
+	}
+	if err := r.checkDB(); err != nil {
+		return err
+	}
 	// 如果有工作单元，调用 CreateWithTx
 	if r.uow != nil {
 		return r.CreateWithTx(entity)
 	}
+	if err := r.beforeInsert(entity); err != nil {
+		return err
+	}
 
 	// 否则直接执行 SQL
-	query := r.dialect.Insert(r.table).Rows(entity)
+	query := r.dialect.Insert(r.table).Rows(r.entityRecord(entity))
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
@@ -174,12 +388,119 @@ func (r *Repository[T]) Create(entity *T) error {
 	return err
 }
 
-func (r *Repository[T]) Update(entity *T) error {
+// beforeInsert 插入前设置时间戳并校验实体
+func (r *Repository[T]) beforeInsert(entity *T) error {
+	r.touchCreated(entity)
+	return validateEntity(entity)
+}
+
+// beforeBatchInsert 批量插入前设置时间戳并校验全部实体
+func (r *Repository[T]) beforeBatchInsert(entities []*T) error {
+	for _, entity := range entities {
+		r.touchCreated(entity)
+	}
+	return validateEntities(entities)
+}
+
+// validateEntity 实体实现 Validatable 时调用其 Validate
+func validateEntity[T any](entity *T) error {
+	if v, ok := any(entity).(Validatable); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// validateEntities 校验全部实体，返回第一个失败的下标和错误
+func validateEntities[T any](entities []*T) error {
+	for i, entity := range entities {
+		if err := validateEntity(entity); err != nil {
+			return fmt.Errorf("entity at index %d is invalid: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// InsertOptions 单条插入的配置选项
+type InsertOptions struct {
+	OmitZeroValues bool     // 忽略零值字段，让数据库默认值（如 DEFAULT CURRENT_TIMESTAMP、自增 id）生效
+	Omit           []string // 始终忽略的字段（db tag 名称）
+}
+
+// CreateWithOptions 按选项插入实体，被忽略的字段不会出现在 INSERT 的列中
+func (r *Repository[T]) CreateWithOptions(entity *T, opt *InsertOptions) (err error) {
+	defer wrapQueryError("CreateWithOptions", r.table, &err)
+	if shard := r.Shard(entity); shard != r {
+		return shard.CreateWithOptions(entity, opt)
+	}
+	if err := r.checkDB(); err != nil {
+		return err
+	}
+	if opt == nil {
+		return r.Create(entity)
+	}
+	if err := r.beforeInsert(entity); err != nil {
+		return err
+	}
+	sql, args, err := r.dialect.Insert(r.table).Rows(r.insertRecord(entity, opt)).ToSQL()
+	if err != nil {
+		return err
+	}
+	if r.uow != nil {
+		_, err = r.uow.Exec(sql, args...)
+	} else {
+		_, err = r.db.Exec(sql, args...)
+	}
+	return err
+}
+
+// CreateOmitting 插入实体并忽略指定字段
+func (r *Repository[T]) CreateOmitting(entity *T, omit ...string) (err error) {
+	defer wrapQueryError("CreateOmitting", r.table, &err)
+	return r.CreateWithOptions(entity, &InsertOptions{Omit: omit})
+}
+
+// insertRecord 通过反射将实体转换为插入用的 goqu.Record
+func (r *Repository[T]) insertRecord(entity *T, opt *InsertOptions) goqu.Record {
+	omit := make(map[string]bool, len(opt.Omit))
+	for _, field := range opt.Omit {
+		omit[field] = true
+	}
+
+	v := reflect.ValueOf(entity).Elem()
+	record := make(goqu.Record)
+	for _, c := range entityColumns(v.Type(), r.nameMapper) {
+		if omit[c.name] {
+			continue
+		}
+		value := fieldByIndex(v, c.index)
+		if opt.OmitZeroValues && (!value.IsValid() || value.IsZero()) {
+			continue
+		}
+		record[c.name] = columnValue(v, c.index)
+	}
+	return record
+}
+
+// entityRecord 返回实体所有列的记录，用于 INSERT 和 UPDATE ... SET
+// 与 insertRecord 一样经过 driverValue 转换，指针接收者实现的 driver.Valuer 也会被调用
+func (r *Repository[T]) entityRecord(entity *T) goqu.Record {
+	return r.insertRecord(entity, &InsertOptions{})
+}
+
+func (r *Repository[T]) Update(entity *T) (err error) {
+	defer wrapQueryError("Update", r.table, &err)
+	if shard := r.Shard(entity); shard != r {
+		return shard.Update(entity)
+	}
+	if err := r.checkDB(); err != nil {
+		return err
+	}
 	if r.uow != nil {
 		// 需要实现 UpdateWithTx 方法
 		return r.UpdateWithTx(entity)
 	}
-	query := r.dialect.Update(r.table).Set(entity)
+	r.touchUpdated(entity)
+	query := r.updateTable().Set(r.entityRecord(entity))
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
@@ -195,12 +516,47 @@ type PageResult[T any] struct {
 	PageSize int
 }
 
+// TotalPages 总页数，PageSize 不大于 0 时返回 0
+func (p *PageResult[T]) TotalPages() int {
+	if p.PageSize <= 0 {
+		return 0
+	}
+	return int((p.Total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
+
+// HasNext 是否存在下一页
+func (p *PageResult[T]) HasNext() bool {
+	return p.Page < p.TotalPages()
+}
+
+// HasPrev 是否存在上一页
+func (p *PageResult[T]) HasPrev() bool {
+	return p.Page > 1
+}
+
+// Page Paginate 返回的分页结果，包含当前页数据和分页信息，推荐用于新的分页代码
+// PageResult 和 PagedResult 保留用于兼容
+type Page[T any] struct {
+	Items      []*T
+	Total      int64
+	Page       int
+	PageSize   int
+	TotalPages int
+	HasNext    bool
+	HasPrev    bool
+}
+
 // 继续写
-func (r *Repository[T]) UpdateByCondition(condition goqu.Ex, entity *T) error {
+func (r *Repository[T]) UpdateByCondition(condition goqu.Ex, entity *T) (err error) {
+	defer wrapQueryError("UpdateByCondition", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return err
+	}
 	if r.uow != nil {
 		return r.UpdateByConditionWithTx(condition, entity)
 	}
-	query := r.dialect.Update(r.table).Set(entity).Where(condition)
+	r.touchUpdated(entity)
+	query := r.updateTable().Set(r.entityRecord(entity)).Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
@@ -210,10 +566,15 @@ func (r *Repository[T]) UpdateByCondition(condition goqu.Ex, entity *T) error {
 }
 
 // 更新指定字段 根据指定条件
-func (r *Repository[T]) UpdateFieldsByCondition(condition goqu.Ex, fields map[string]interface{}) error {
+func (r *Repository[T]) UpdateFieldsByCondition(condition goqu.Ex, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsByCondition", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return err
+	}
 	if r.uow != nil {
 		return r.UpdateFieldsByConditionWithTx(condition, fields)
 	}
+	fields = r.touchFields(fields)
 	updateExp := make(map[string]interface{})
 
 	for field, value := range fields {
@@ -225,7 +586,7 @@ func (r *Repository[T]) UpdateFieldsByCondition(condition goqu.Ex, fields map[st
 		}
 	}
 
-	query := r.dialect.Update(r.table).Set(updateExp).Where(condition)
+	query := r.updateTable().Set(updateExp).Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
@@ -234,17 +595,59 @@ func (r *Repository[T]) UpdateFieldsByCondition(condition goqu.Ex, fields map[st
 	return err
 }
 
+// UpdateFieldsByConditionSafe 与 UpdateFieldsByCondition 相同，但会先校验字段名
+// 只允许实体中定义的列，适用于字段来自不可信输入（如 JSON PATCH）的场景
+func (r *Repository[T]) UpdateFieldsByConditionSafe(condition goqu.Ex, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsByConditionSafe", r.table, &err)
+	if err := r.validateColumns(fields); err != nil {
+		return err
+	}
+	return r.UpdateFieldsByCondition(condition, fields)
+}
+
+// validateColumns 校验字段名都是实体中定义的列
+func (r *Repository[T]) validateColumns(fields map[string]interface{}) error {
+	var entity T
+	known := make(map[string]bool)
+	for _, column := range r.getFields(&entity) {
+		known[column] = true
+	}
+
+	var unknown []string
+	for field := range fields {
+		if !known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown columns for %T: %s", entity, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
 // BatchCreate - 批量创建
 
-func (r *Repository[T]) BatchCreate(entities []*T) error {
-	query := r.dialect.Insert(r.table).Rows(entities)
+func (r *Repository[T]) BatchCreate(entities []*T) (err error) {
+	defer wrapQueryError("BatchCreate", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return err
+	}
+	if err := r.beforeBatchInsert(entities); err != nil {
+		return err
+	}
+	rows := make([]interface{}, len(entities))
+	for i, entity := range entities {
+		rows[i] = r.entityRecord(entity)
+	}
+	query := r.dialect.Insert(r.table).Rows(rows...)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
 
 	if r.uow != nil {
-		_, err = r.uow.GetTx().Exec(sql, args...)
+		_, err = r.uow.Exec(sql, args...)
 	} else {
 		_, err = r.db.Exec(sql, args...)
 	}
@@ -252,11 +655,15 @@ func (r *Repository[T]) BatchCreate(entities []*T) error {
 }
 
 // BatchDelete - 批量删除
-func (r *Repository[T]) BatchDelete(condition goqu.Ex) error {
+func (r *Repository[T]) BatchDelete(condition goqu.Ex) (err error) {
+	defer wrapQueryError("BatchDelete", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return err
+	}
 	if r.uow != nil {
 		return r.BatchDeleteWithTx(condition)
 	}
-	query := r.dialect.Delete(r.table).Where(condition)
+	query := r.deleteFrom().Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
@@ -265,10 +672,272 @@ func (r *Repository[T]) BatchDelete(condition goqu.Ex) error {
 	return err
 }
 
+// execContext 执行写语句，存在工作单元时在事务中执行
+func (r *Repository[T]) execContext(ctx context.Context, query string, args ...interface{}) (stdsql.Result, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, err
+	}
+	if r.uow != nil {
+		return r.uow.ExecContext(ctx, query, args...)
+	}
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+// CreateContext 带 context 的 Create
+func (r *Repository[T]) CreateContext(ctx context.Context, entity *T) (err error) {
+	defer wrapQueryError("CreateContext", r.table, &err)
+	if shard := r.Shard(entity); shard != r {
+		return shard.CreateContext(ctx, entity)
+	}
+	if err := r.beforeInsert(entity); err != nil {
+		return err
+	}
+	sql, args, err := r.dialect.Insert(r.table).Rows(r.entityRecord(entity)).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = r.execContext(ctx, sql, args...)
+	return err
+}
+
+// UpdateContext 带 context 的 Update
+func (r *Repository[T]) UpdateContext(ctx context.Context, entity *T) (err error) {
+	defer wrapQueryError("UpdateContext", r.table, &err)
+	if shard := r.Shard(entity); shard != r {
+		return shard.UpdateContext(ctx, entity)
+	}
+	r.touchUpdated(entity)
+	sql, args, err := r.updateTable().Set(r.entityRecord(entity)).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = r.execContext(ctx, sql, args...)
+	return err
+}
+
+// UpdateFieldsByConditionContext 带 context 的 UpdateFieldsByCondition
+func (r *Repository[T]) UpdateFieldsByConditionContext(ctx context.Context, condition goqu.Ex, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsByConditionContext", r.table, &err)
+	fields = r.touchFields(fields)
+	sql, args, err := r.updateTable().Set(fields).Where(condition).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = r.execContext(ctx, sql, args...)
+	return err
+}
+
+// BatchDeleteContext 带 context 的 BatchDelete
+func (r *Repository[T]) BatchDeleteContext(ctx context.Context, condition goqu.Ex) (err error) {
+	defer wrapQueryError("BatchDeleteContext", r.table, &err)
+	sql, args, err := r.deleteFrom().Where(condition).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = r.execContext(ctx, sql, args...)
+	return err
+}
+
+// DeleteReturning 删除满足条件的记录并返回被删除的行，用于审计
+// MySQL/StarRocks 不支持 DELETE ... RETURNING，因此必须在事务中先 SELECT ... FOR UPDATE 锁定并读取，再按同一条件删除；
+// 已绑定工作单元时使用其事务，否则自动开启事务，读取与删除之间其他事务无法修改这些行
+func (r *Repository[T]) DeleteReturning(condition goqu.Ex) (_ []*T, err error) {
+	defer wrapQueryError("DeleteReturning", r.table, &err)
+	return r.DeleteReturningContext(context.Background(), condition)
+}
+
+// DeleteReturningContext 带 context 的 DeleteReturning
+func (r *Repository[T]) DeleteReturningContext(ctx context.Context, condition goqu.Ex) (_ []*T, err error) {
+	defer wrapQueryError("DeleteReturningContext", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return nil, err
+	}
+	if r.uow != nil && r.uow.GetTx() != nil {
+		return r.deleteReturning(ctx, condition)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	deleted, err := r.WithUnitOfWork(&UnitOfWork{db: r.db, tx: tx}).deleteReturning(ctx, condition)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, stdsql.ErrTxDone) {
+			return nil, fmt.Errorf("原始错误: %v, 回滚失败: %w", err, rollbackErr)
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// deleteReturning 在当前事务中锁定并读取匹配的行，再删除它们
+func (r *Repository[T]) deleteReturning(ctx context.Context, condition goqu.Ex) ([]*T, error) {
+	q := r.Query().Where(condition).(*Queryable[T])
+	q.query = q.query.ForUpdate(exp.Wait)
+	deleted, err := q.ToListTx(ctx)
+	if err != nil || len(deleted) == 0 {
+		return deleted, err
+	}
+
+	sql, args, err := r.deleteFrom().Where(condition).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.execContext(ctx, sql, args...); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// ErrTruncateWithTenant 设置了租户隔离时拒绝 TRUNCATE，避免清空其他租户的数据
+var ErrTruncateWithTenant = errors.New("truncate is not allowed on a tenant-scoped repository")
+
+// ErrTruncateInTx 工作单元中有进行中的事务时拒绝 TRUNCATE，MySQL 执行 TRUNCATE 会隐式提交该事务
+var ErrTruncateInTx = errors.New("truncate is not allowed inside a transaction")
+
+// Truncate 清空整张表并重置自增 ID，仅用于测试数据和全表重置
+// 注意：TRUNCATE 不可回滚且会绕过外键检查，按条件删除请使用 BatchDelete；在事务中调用返回 ErrTruncateInTx
+func (r *Repository[T]) Truncate() (err error) {
+	defer wrapQueryError("Truncate", r.table, &err)
+	return r.TruncateTx(context.Background())
+}
+
+// TruncateTx 带 context 的 Truncate
+func (r *Repository[T]) TruncateTx(ctx context.Context) (err error) {
+	defer wrapQueryError("TruncateTx", r.table, &err)
+	if r.tenantColumn != "" {
+		return ErrTruncateWithTenant
+	}
+	if r.uow != nil && r.uow.GetTx() != nil {
+		return ErrTruncateInTx
+	}
+	sql, _, err := r.dialect.Truncate(r.table).ToSQL()
+	if err != nil {
+		return err
+	}
+	// goqu 生成 TRUNCATE `t`，StarRocks 要求带 TABLE 关键字，MySQL 两种写法都支持
+	_, err = r.execContext(ctx, strings.Replace(sql, "TRUNCATE", "TRUNCATE TABLE", 1))
+	return err
+}
+
+// Increment 原子地增加计数列：SET column = column + delta
+func (r *Repository[T]) Increment(condition goqu.Ex, column string, delta int64) (err error) {
+	defer wrapQueryError("Increment", r.table, &err)
+	return r.IncrementFieldsTx(context.Background(), condition, map[string]int64{column: delta})
+}
+
+// IncrementTx 带 context 的 Increment
+func (r *Repository[T]) IncrementTx(ctx context.Context, condition goqu.Ex, column string, delta int64) (err error) {
+	defer wrapQueryError("IncrementTx", r.table, &err)
+	return r.IncrementFieldsTx(ctx, condition, map[string]int64{column: delta})
+}
+
+// Decrement 原子地减少计数列：SET column = column - delta
+func (r *Repository[T]) Decrement(condition goqu.Ex, column string, delta int64) (err error) {
+	defer wrapQueryError("Decrement", r.table, &err)
+	return r.IncrementFieldsTx(context.Background(), condition, map[string]int64{column: -delta})
+}
+
+// DecrementTx 带 context 的 Decrement
+func (r *Repository[T]) DecrementTx(ctx context.Context, condition goqu.Ex, column string, delta int64) (err error) {
+	defer wrapQueryError("DecrementTx", r.table, &err)
+	return r.IncrementFieldsTx(ctx, condition, map[string]int64{column: -delta})
+}
+
+// IncrementFields 在一条语句中增加多个计数列，负数表示减少
+func (r *Repository[T]) IncrementFields(condition goqu.Ex, deltas map[string]int64) (err error) {
+	defer wrapQueryError("IncrementFields", r.table, &err)
+	return r.IncrementFieldsTx(context.Background(), condition, deltas)
+}
+
+// IncrementFieldsTx 带 context 的 IncrementFields，避免先读后写的并发问题
+func (r *Repository[T]) IncrementFieldsTx(ctx context.Context, condition goqu.Ex, deltas map[string]int64) (err error) {
+	defer wrapQueryError("IncrementFieldsTx", r.table, &err)
+	if len(deltas) == 0 {
+		return fmt.Errorf("no columns to increment")
+	}
+	record := make(goqu.Record, len(deltas))
+	for column, delta := range deltas {
+		record[column] = goqu.L("? + ?", goqu.I(column), delta)
+	}
+	sql, args, err := r.updateTable().Prepared(true).Set(record).Where(condition).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = r.execContext(ctx, sql, args...)
+	return err
+}
+
+// ChunkedDeleteOption 分批删除的配置选项
+type ChunkedDeleteOption struct {
+	ChunkSize int           // 每次 DELETE 的最大行数
+	Interval  time.Duration // 每批之间的休眠时间，用于降低主从延迟和锁竞争
+}
+
+// BatchDeleteChunked 分批删除满足条件的数据，返回删除的总行数
+// 每次执行 DELETE ... WHERE cond LIMIT chunkSize，直到影响行数为 0，避免长时间持有锁
+func (r *Repository[T]) BatchDeleteChunked(condition goqu.Ex, chunkSize int) (_ int64, err error) {
+	defer wrapQueryError("BatchDeleteChunked", r.table, &err)
+	return r.BatchDeleteChunkedTx(context.Background(), condition, &ChunkedDeleteOption{ChunkSize: chunkSize})
+}
+
+// BatchDeleteChunkedTx 带 context 的分批删除，每批之间检查 context 是否已取消
+func (r *Repository[T]) BatchDeleteChunkedTx(ctx context.Context, condition goqu.Ex, opt *ChunkedDeleteOption) (_ int64, err error) {
+	defer wrapQueryError("BatchDeleteChunkedTx", r.table, &err)
+	if opt == nil || opt.ChunkSize <= 0 {
+		return 0, fmt.Errorf("chunk size must be greater than 0")
+	}
+
+	sql, args, err := r.deleteFrom().Where(condition).Limit(uint(opt.ChunkSize)).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for {
+		result, err := r.execContext(ctx, sql, args...)
+		if err != nil {
+			return total, fmt.Errorf("chunked delete failed after %d rows: %w", total, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += affected
+		if affected == 0 {
+			return total, nil
+		}
+
+		if opt.Interval > 0 {
+			timer := time.NewTimer(opt.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return total, ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
 // BatchInsertOption 批量插入的配置选项
 type BatchInsertOption struct {
 	BatchSize    int  // 每批次处理的数据量
 	UseNamedExec bool // 是否使用NamedExec方式
+	InsertIgnore bool // 是否使用 INSERT IGNORE，跳过主键/唯一键冲突的行（实际插入条数由 BatchInsertWithCount 返回）
+	// Atomic 为 true 时所有批次在同一个事务中执行，任一批失败或 ctx 取消时全部回滚；已绑定工作单元时使用其事务
+	Atomic bool
+
+	// OnBatch 每批执行后调用，done 为已成功执行的条数，total 为总条数，用于显示导入进度
+	// 某批失败时 err 为该批的错误（done 不含该批），之后 BatchInsert 返回该错误；
+	// 在工作单元中执行时，数据要到事务提交后才真正写入
+	OnBatch func(done, total int, err error)
 }
 
 // DefaultBatchInsertOption 默认的批量插入配置
@@ -278,28 +947,85 @@ var DefaultBatchInsertOption = &BatchInsertOption{
 }
 
 // BatchInsert 批量插入数据的通用方法
-func (r *Repository[T]) BatchInsert(entities []*T, opt *BatchInsertOption) error {
+func (r *Repository[T]) BatchInsert(entities []*T, opt *BatchInsertOption) (err error) {
+	defer wrapQueryError("BatchInsert", r.table, &err)
+	return r.BatchInsertContext(context.Background(), entities, opt)
+}
+
+// BatchInsertContext 带 context 的 BatchInsert，每个批次都记录日志并可随 ctx 取消
+// 每批执行前检查 ctx，取消后不再执行剩余批次并返回 ctx 的错误，已执行的批次不会回滚，需要全部回滚时设置 Atomic
+func (r *Repository[T]) BatchInsertContext(ctx context.Context, entities []*T, opt *BatchInsertOption) (err error) {
+	defer wrapQueryError("BatchInsertContext", r.table, &err)
+	_, err = r.batchInsert(ctx, entities, opt)
+	return err
+}
+
+// BatchInsertWithCount 批量插入并返回影响行数，配合 InsertIgnore 可得知实际插入的条数
+// 被 INSERT IGNORE 跳过的冲突行不计入；DryRun 模式下返回 0
+func (r *Repository[T]) BatchInsertWithCount(entities []*T, opt *BatchInsertOption) (_ int64, err error) {
+	defer wrapQueryError("BatchInsertWithCount", r.table, &err)
+	return r.batchInsert(context.Background(), entities, opt)
+}
+
+// batchInsert 校验实体并分批插入，返回各批影响行数之和
+func (r *Repository[T]) batchInsert(ctx context.Context, entities []*T, opt *BatchInsertOption) (int64, error) {
+	if err := r.checkDB(); err != nil {
+		return 0, err
+	}
 	if len(entities) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	if opt == nil {
 		opt = DefaultBatchInsertOption
 	}
 
+	// 先校验全部实体，避免部分批次已写入
+	if err := r.beforeBatchInsert(entities); err != nil {
+		return 0, err
+	}
+
 	// 获取字段数量
 	fields := r.getFields(entities[0])
 	if len(fields) == 0 {
-		return fmt.Errorf("no fields found in entity")
+		return 0, fmt.Errorf("no fields found in entity")
 	}
 
 	// 计算安全的批次大小
-	safeBatchSize := calculateSafeBatchSize(len(fields), 16384)
+	safeBatchSize := calculateSafeBatchSize(len(fields), maxPlaceholders(r.dbType))
 	if opt.BatchSize > safeBatchSize {
 		opt.BatchSize = safeBatchSize
 	}
 
-	// 分批处理
+	if opt.Atomic && r.uow == nil {
+		return r.insertBatchesAtomic(ctx, entities, opt)
+	}
+	return r.insertBatches(ctx, entities, opt)
+}
+
+// insertBatchesAtomic 在以 ctx 开始的事务中执行全部批次，ctx 取消时 database/sql 会自动回滚事务
+func (r *Repository[T]) insertBatchesAtomic(ctx context.Context, entities []*T, opt *BatchInsertOption) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %w", err)
+	}
+	txRepo := r.WithUnitOfWork(&UnitOfWork{db: r.db, tx: tx})
+	affected, err := txRepo.insertBatches(ctx, entities, opt)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, stdsql.ErrTxDone) {
+			return 0, fmt.Errorf("原始错误: %v, 回滚失败: %w", err, rollbackErr)
+		}
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// insertBatches 按 opt.BatchSize 分批插入，每批执行前检查 ctx，返回已执行批次的影响行数之和
+func (r *Repository[T]) insertBatches(ctx context.Context, entities []*T, opt *BatchInsertOption) (int64, error) {
+	var total int64
 	for i := 0; i < len(entities); i += opt.BatchSize {
 		end := i + opt.BatchSize
 		if end > len(entities) {
@@ -307,29 +1033,41 @@ func (r *Repository[T]) BatchInsert(entities []*T, opt *BatchInsertOption) error
 		}
 
 		batch := entities[i:end]
-		if opt.UseNamedExec {
-			if err := r.batchInsertByNamedExec(batch); err != nil {
-				return fmt.Errorf("batch insert failed at offset %d: %w", i, err)
+		// 取消后不再开始新的批次
+		var affected int64
+		err := ctx.Err()
+		if err == nil {
+			if opt.UseNamedExec {
+				affected, err = r.batchInsertByNamedExec(ctx, batch, opt.InsertIgnore)
+			} else {
+				affected, err = r.batchInsertByExec(ctx, batch, opt.InsertIgnore)
 			}
-		} else {
-			if err := r.batchInsertByExec(batch); err != nil {
-				return fmt.Errorf("batch insert failed at offset %d: %w", i, err)
+		}
+		if err != nil {
+			err = fmt.Errorf("batch insert failed at offset %d: %w", i, err)
+			if opt.OnBatch != nil {
+				opt.OnBatch(i, len(entities), err)
 			}
+			return total, err
+		}
+		total += affected
+		if opt.OnBatch != nil {
+			opt.OnBatch(end, len(entities), nil)
 		}
 	}
 
-	return nil
+	return total, nil
 }
 
 // batchInsertByExec 使用手动拼接SQL的方式批量插入
-func (r *Repository[T]) batchInsertByExec(entities []*T) error {
+func (r *Repository[T]) batchInsertByExec(ctx context.Context, entities []*T, ignore bool) (int64, error) {
 	if len(entities) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	fields := r.getFields(entities[0])
 	if len(fields) == 0 {
-		return fmt.Errorf("no fields found in entity")
+		return 0, fmt.Errorf("no fields found in entity")
 	}
 
 	// 构造占位符
@@ -346,7 +1084,8 @@ func (r *Repository[T]) batchInsertByExec(entities []*T) error {
 
 	// 构造SQL
 	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES %s",
+		"%s %s (%s) VALUES %s",
+		insertKeyword(ignore),
 		r.table,
 		strings.Join(fields, ","),
 		strings.Join(placeholders, ","),
@@ -359,14 +1098,36 @@ func (r *Repository[T]) batchInsertByExec(entities []*T) error {
 		values = append(values, vals...)
 	}
 
-	// 执行SQL
-	_, err := r.db.Exec(query, values...)
-	return err
+	// 执行SQL，存在工作单元时在事务中执行
+	result, err := r.execContext(ctx, query, values...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// insertKeyword 返回插入语句的关键字，ignore 为 true 时使用 MySQL 的 INSERT IGNORE
+func insertKeyword(ignore bool) string {
+	if ignore {
+		return "INSERT IGNORE INTO"
+	}
+	return "INSERT INTO"
+}
+
+// maxPlaceholders 返回方言单条语句允许的最大占位符数
+// MySQL 协议用 2 字节表示参数个数，上限为 65535，StarRocks 兼容 MySQL 协议，未知方言使用保守的 16384
+func maxPlaceholders(dbType DialectType) int {
+	switch dbType {
+	case MySQL, StarRocks:
+		return 65535
+	default:
+		return 16384
+	}
 }
 
 // 计算安全的批次大小
 func calculateSafeBatchSize(fieldCount int, maxParams int) int {
-	// MySQL 默认 max_prepared_stmt_count 是 16384
+	// maxParams 为单条语句的最大占位符数，见 maxPlaceholders
 	// 每条记录占用 fieldCount 个参数
 	// 为安全起见，取最大参数数的80%
 	safeMaxParams := maxParams * 80 / 100
@@ -374,9 +1135,9 @@ func calculateSafeBatchSize(fieldCount int, maxParams int) int {
 }
 
 // batchInsertByNamedExec 使用NamedExec的方式批量插入
-func (r *Repository[T]) batchInsertByNamedExec(entities []*T) error {
+func (r *Repository[T]) batchInsertByNamedExec(ctx context.Context, entities []*T, ignore bool) (int64, error) {
 	if len(entities) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	// 获取字段名
@@ -390,15 +1151,25 @@ func (r *Repository[T]) batchInsertByNamedExec(entities []*T) error {
 
 	// 构造SQL
 	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s)",
+		"%s %s (%s) VALUES (%s)",
+		insertKeyword(ignore),
 		r.table,
 		strings.Join(fields, ","),
 		strings.Join(placeholders, ","),
 	)
 
-	// 执行带命名参数的SQL
-	_, err := r.db.NamedExec(query, entities)
-	return err
+	// 执行带命名参数的SQL，存在工作单元时在事务中执行
+	var result stdsql.Result
+	var err error
+	if r.uow != nil && r.uow.GetTx() != nil {
+		result, err = r.uow.NamedExecContext(ctx, query, r.getRecords(entities))
+	} else {
+		result, err = r.db.NamedExecContext(ctx, query, r.getRecords(entities))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 // getFields 获取实体的字段名（需要根据实际的标签或反射实现）
@@ -412,19 +1183,16 @@ func (r *Repository[T]) getFields(entity *T) []string {
 	}
 
 	fields := make([]string, 0)
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		// 获取 db tag
-		if tag := field.Tag.Get("db"); tag != "" {
-			fields = append(fields, tag)
-		}
+	// 优先使用 db tag，没有 tag 时使用 nameMapper 映射字段名，嵌入结构体的字段会展开
+	for _, c := range entityColumns(t, r.nameMapper) {
+		fields = append(fields, c.name)
 	}
 	return fields
 }
 
 // getValues 获取实体的字段值（需要根据实际的标签或反射实现）
 func (r *Repository[T]) getValues(entity *T) []interface{} {
-	v := reflect.ValueOf(*entity)
+	v := reflect.ValueOf(entity).Elem()
 	t := v.Type()
 
 	if t.Kind() == reflect.Ptr {
@@ -433,15 +1201,27 @@ func (r *Repository[T]) getValues(entity *T) []interface{} {
 	}
 
 	values := make([]interface{}, 0)
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if tag := field.Tag.Get("db"); tag != "" {
-			values = append(values, v.Field(i).Interface())
-		}
+	for _, c := range entityColumns(t, r.nameMapper) {
+		values = append(values, columnValue(v, c.index))
 	}
 	return values
 }
 
+// getRecords 将实体转换为列名到写入值的 map，供 NamedExec 批量绑定
+func (r *Repository[T]) getRecords(entities []*T) []map[string]interface{} {
+	records := make([]map[string]interface{}, len(entities))
+	for i, entity := range entities {
+		fields := r.getFields(entity)
+		values := r.getValues(entity)
+		record := make(map[string]interface{}, len(fields))
+		for j, field := range fields {
+			record[field] = values[j]
+		}
+		records[i] = record
+	}
+	return records
+}
+
 // BatchUpdateOption 批量更新的配置选项
 type BatchUpdateOption struct {
 	BatchSize       int      // 每批次处理的数据量
@@ -456,9 +1236,14 @@ var DefaultBatchUpdateOption = &BatchUpdateOption{
 }
 
 // BatchUpdate 批量更新数据
-// entities 要更新的实体数组
+// entities 要更新的实体数组，执行前按 KeyField 升序稳定排序（不修改传入的切片），
+// 使并发更新重叠键集合的调用方以相同顺序加行锁，减少死锁
 // opt 更新选项
-func (r *Repository[T]) BatchUpdate(entities []*T, opt *BatchUpdateOption) error {
+func (r *Repository[T]) BatchUpdate(entities []*T, opt *BatchUpdateOption) (err error) {
+	defer wrapQueryError("BatchUpdate", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return err
+	}
 	if len(entities) == 0 {
 		return nil
 	}
@@ -477,8 +1262,11 @@ func (r *Repository[T]) BatchUpdate(entities []*T, opt *BatchUpdateOption) error
 		return fmt.Errorf("key field must be specified for batch update")
 	}
 
-	// 计算安全的批次大小（考虑WHERE IN的限制和参数数量限制）
-	safeBatchSize := calculateSafeBatchSize(len(opt.UpdateFields)+1, 16384)
+	// 按键排序后再分批，所有调用方以相同顺序加锁，避免并发更新重叠的键时死锁
+	entities = r.sortByKey(entities, opt.KeyField)
+
+	// 计算安全的批次大小，每条记录占用每个字段的 WHEN ? THEN ? 两个参数和 WHERE IN 的一个参数
+	safeBatchSize := calculateSafeBatchSize(2*len(opt.UpdateFields)+1, maxPlaceholders(r.dbType))
 	if opt.BatchSize > safeBatchSize {
 		opt.BatchSize = safeBatchSize
 	}
@@ -499,7 +1287,29 @@ func (r *Repository[T]) BatchUpdate(entities []*T, opt *BatchUpdateOption) error
 	return nil
 }
 
-// batchUpdateExec 执行批量更新
+// sortByKey 按键字段升序稳定排序，返回新的切片，键相同或无法比较时保持原有顺序
+func (r *Repository[T]) sortByKey(entities []*T, keyField string) []*T {
+	type keyed struct {
+		key    interface{}
+		entity *T
+	}
+	items := make([]keyed, len(entities))
+	for i, entity := range entities {
+		items[i] = keyed{key: r.getFieldValue(entity, keyField), entity: entity}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		c, err := compareValues(items[i].key, items[j].key)
+		return err == nil && c < 0
+	})
+
+	sorted := make([]*T, len(items))
+	for i, item := range items {
+		sorted[i] = item.entity
+	}
+	return sorted
+}
+
+// batchUpdateExec 执行批量更新，CASE WHEN 与 IN 列表按 entities 的顺序生成
 func (r *Repository[T]) batchUpdateExec(entities []*T, opt *BatchUpdateOption) error {
 	if len(entities) == 0 {
 		return nil
@@ -509,18 +1319,15 @@ func (r *Repository[T]) batchUpdateExec(entities []*T, opt *BatchUpdateOption) e
 	//var cases []string
 	var args []interface{}
 
-	// 收集所有的主键值
-	keyValues := make([]interface{}, len(entities))
+	// 收集所有的主键值，键重复时使用最后一个实体
+	keyValues := make([]interface{}, 0, len(entities))
 	keyValueMap := make(map[interface{}]*T)
 
-	for i, entity := range entities {
-		v := reflect.ValueOf(*entity)
-		if v.Kind() == reflect.Ptr {
-			v = v.Elem()
-		}
-
+	for _, entity := range entities {
 		keyValue := r.getFieldValue(entity, opt.KeyField)
-		keyValues[i] = keyValue
+		if _, ok := keyValueMap[keyValue]; !ok {
+			keyValues = append(keyValues, keyValue)
+		}
 		keyValueMap[keyValue] = entity
 	}
 
@@ -535,8 +1342,8 @@ func (r *Repository[T]) batchUpdateExec(entities []*T, opt *BatchUpdateOption) e
 		}
 
 		caseStmt := fmt.Sprintf("%s = CASE %s ", field, opt.KeyField)
-		for keyValue, entity := range keyValueMap {
-			value := r.getFieldValue(entity, field)
+		for _, keyValue := range keyValues {
+			value := r.getFieldValue(keyValueMap[keyValue], field)
 			caseStmt += fmt.Sprintf("WHEN ? THEN ? ")
 			args = append(args, keyValue, value)
 		}
@@ -553,13 +1360,18 @@ func (r *Repository[T]) batchUpdateExec(entities []*T, opt *BatchUpdateOption) e
 	// 添加WHERE IN的参数
 	args = append(args, keyValues...)
 
-	// 如果有附加条件，添加到WHERE子句
+	// 如果有附加条件或租户条件，添加到WHERE子句
+	extraWhere := r.tenantWhere()
 	if opt.AdditionalWhere != nil {
-		whereSQL, whereArgs, err := r.dialect.From("").Where(opt.AdditionalWhere).ToSQL()
+		extraWhere = append(extraWhere, opt.AdditionalWhere)
+	}
+	if len(extraWhere) > 0 {
+		whereSQL, whereArgs, err := r.dialect.From(r.table).Where(extraWhere...).ToSQL()
 		if err != nil {
 			return err
 		}
-		sql += " AND " + whereSQL[7:] // 去掉开头的"WHERE "
+		// 只保留 WHERE 之后的条件部分
+		sql += " AND " + whereSQL[strings.Index(whereSQL, " WHERE ")+len(" WHERE "):]
 		args = append(args, whereArgs...)
 	}
 
@@ -570,16 +1382,13 @@ func (r *Repository[T]) batchUpdateExec(entities []*T, opt *BatchUpdateOption) e
 
 // getFieldValue 获取实体指定字段的值
 func (r *Repository[T]) getFieldValue(entity *T, fieldName string) interface{} {
-	v := reflect.ValueOf(*entity)
+	v := reflect.ValueOf(entity).Elem()
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
-	t := v.Type()
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if tag := field.Tag.Get("db"); tag == fieldName {
-			return v.Field(i).Interface()
+	for _, c := range entityColumns(v.Type(), r.nameMapper) {
+		if c.name == fieldName {
+			return columnValue(v, c.index)
 		}
 	}
 	return nil
@@ -588,16 +1397,30 @@ func (r *Repository[T]) getFieldValue(entity *T, fieldName string) interface{} {
 // ----------------------------------------------------------工作单元----------------------------------------------------------
 // IUnitOfWork 工作单元接口
 type IUnitOfWork interface {
+	// DB 返回工作单元使用的数据库连接，NewRepositoryTx 以它创建仓储
+	DB() *DBLogger
 	GetTx() *Tx
 	Begin() error
 	Commit() error
 	Rollback() error
+	// Exec/ExecContext 在事务中执行语句，并与 DBLogger 一样记录日志
+	Exec(query string, args ...interface{}) (stdsql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (stdsql.Result, error)
+	// NamedExecContext 在事务中执行带命名参数的语句，同样遵循 DryRun 和 OnSQL
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (stdsql.Result, error)
+	// Checkpoint 在保存点中执行 fn，fn 出错时只回滚到保存点，事务可以继续
+	Checkpoint(name string, fn func() error) error
 }
 
 // UnitOfWork 实现
 type UnitOfWork struct {
 	db *DBLogger
 	tx *Tx
+
+	retryClassifiers []RetryClassifier // 可重试错误的判断函数，为空时使用 DefaultRetryClassifiers
+	retryBackoff     time.Duration     // 首次重试前的等待时间，之后每次翻倍
+
+	savepoints int // 已创建的保存点数量，用于生成唯一的保存点名
 }
 
 func NewUnitOfWork(db *DBLogger) *UnitOfWork {
@@ -606,8 +1429,16 @@ func NewUnitOfWork(db *DBLogger) *UnitOfWork {
 	}
 }
 
+// DB 返回工作单元使用的数据库连接
+func (u *UnitOfWork) DB() *DBLogger {
+	return u.db
+}
+
 // 工作单元方法实现
 func (u *UnitOfWork) Begin() error {
+	if err := u.db.ready(); err != nil {
+		return err
+	}
 	tx, err := u.db.Begin()
 	if err != nil {
 		return fmt.Errorf("开始事务失败: %w", err)
@@ -627,6 +1458,42 @@ func (u *UnitOfWork) GetTx() *Tx {
 	return u.tx
 }
 
+// Exec 在事务中执行语句，使用 DBLogger 的 logger 记录日志
+func (u *UnitOfWork) Exec(query string, args ...interface{}) (stdsql.Result, error) {
+	return u.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext 带 context 的 Exec
+func (u *UnitOfWork) ExecContext(ctx context.Context, query string, args ...interface{}) (stdsql.Result, error) {
+	if u.tx == nil {
+		return nil, fmt.Errorf("事务未开始")
+	}
+	u.db.traceSQL(query, args)
+	if u.db.skipWrite(query, args) {
+		return dryRunResult{}, nil
+	}
+	start := time.Now()
+	result, err := u.tx.ExecContext(ctx, query, args...)
+	u.db.logQuery(ctx, "TxExec", query, args, err, time.Since(start))
+	return result, err
+}
+
+// NamedExecContext 在事务中执行带命名参数的语句，与 DBLogger.NamedExecContext 一样记录日志并遵循 DryRun
+func (u *UnitOfWork) NamedExecContext(ctx context.Context, query string, arg interface{}) (stdsql.Result, error) {
+	if u.tx == nil {
+		return nil, fmt.Errorf("事务未开始")
+	}
+	args := []interface{}{arg}
+	u.db.traceSQL(query, args)
+	if u.db.skipWrite(query, args) {
+		return dryRunResult{}, nil
+	}
+	start := time.Now()
+	result, err := u.tx.NamedExecContext(ctx, query, arg)
+	u.db.logQuery(ctx, "TxNamedExec", query, args, err, time.Since(start))
+	return result, err
+}
+
 // RunInTransaction executes a function within a database transaction.
 // If the function returns an error or panics, the transaction is rolled back.
 // Otherwise, the transaction is committed.
@@ -653,102 +1520,207 @@ func (u *UnitOfWork) RunInTransaction(fn func(IUnitOfWork) error) error {
 	return u.Commit()
 }
 
-// UpdateFieldsById(current.Id, map[string]interface{}{
-func (r *Repository[T]) UpdateFieldsById(id int64, fields map[string]interface{}) error {
-	if r.uow != nil {
-		return r.UpdateFieldsByIdWithTx(id, fields)
+// Checkpoint 在名为 name 的保存点中执行 fn，需要先开始事务
+// fn 成功时释放保存点；返回错误或 panic 时回滚到保存点，只撤销 fn 中的修改，事务仍然有效，
+// 由调用方决定继续执行还是回滚整个事务。保存点名会追加序号，嵌套或重复的 Checkpoint 不会互相覆盖
+func (u *UnitOfWork) Checkpoint(name string, fn func() error) (err error) {
+	if u.tx == nil {
+		return fmt.Errorf("事务未开始")
+	}
+	u.savepoints++
+	savepoint := fmt.Sprintf("sp_%s_%d", savepointName(name), u.savepoints)
+	if _, err := u.Exec("SAVEPOINT " + savepoint); err != nil {
+		return err
 	}
-	updateExp := make(map[string]interface{})
 
-	for field, value := range fields {
-		// 使用 exp.LiteralExpression 接口类型来判断
-		if _, ok := value.(exp.LiteralExpression); ok {
-			updateExp[field] = value
-		} else {
-			updateExp[field] = value
+	defer func() {
+		if r := recover(); r != nil {
+			u.Exec("ROLLBACK TO SAVEPOINT " + savepoint)
+			panic(r)
 		}
-	}
+	}()
 
-	query := r.dialect.Update(r.table).Set(updateExp).Where(goqu.Ex{"id": id})
-	sql, args, err := query.ToSQL()
-	if err != nil {
+	if err := fn(); err != nil {
+		if _, rollbackErr := u.Exec("ROLLBACK TO SAVEPOINT " + savepoint); rollbackErr != nil {
+			return fmt.Errorf("原始错误: %v, 回滚到保存点失败: %w", err, rollbackErr)
+		}
 		return err
 	}
-	_, err = r.db.Exec(sql, args...)
+	_, err = u.Exec("RELEASE SAVEPOINT " + savepoint)
 	return err
 }
 
-func (r *Repository[T]) UpdateFieldsByIds(ids []int64, fields map[string]interface{}) error {
-	return r.UpdateFieldsByCondition(goqu.Ex{"id": ids}, fields)
+// savepointName 将 name 转换为可以直接拼接到 SQL 中的标识符，非字母数字的字符替换为下划线
+func savepointName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
 }
 
-// UpdateFieldsByIdWithTx
-func (r *Repository[T]) UpdateFieldsByIdWithTx(id int64, fields map[string]interface{}) error {
-	updateExp := make(map[string]interface{})
-	for field, value := range fields {
-		if _, ok := value.(exp.LiteralExpression); ok {
-			updateExp[field] = value
-		} else {
-			updateExp[field] = value
+// RetryClassifier 判断事务错误是否可以安全重试
+type RetryClassifier func(err error) bool
+
+// IsMySQLRetryableError 判断是否为 MySQL 死锁(1213)或锁等待超时(1205)错误
+func IsMySQLRetryableError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	return false
+}
+
+// DefaultRetryClassifiers 默认的可重试错误判断，可追加其他数据库的判断（如 Postgres 的 40001）
+var DefaultRetryClassifiers = []RetryClassifier{IsMySQLRetryableError}
+
+// defaultRetryBackoff 默认的首次重试等待时间
+const defaultRetryBackoff = 50 * time.Millisecond
+
+// WithRetryClassifiers 返回使用指定可重试错误判断的工作单元副本，原工作单元不受影响
+func (u *UnitOfWork) WithRetryClassifiers(classifiers ...RetryClassifier) *UnitOfWork {
+	clone := *u
+	clone.retryClassifiers = append([]RetryClassifier(nil), classifiers...)
+	return &clone
+}
+
+// isRetryable 判断错误是否可以重试
+func (u *UnitOfWork) isRetryable(err error) bool {
+	classifiers := u.retryClassifiers
+	if len(classifiers) == 0 {
+		classifiers = DefaultRetryClassifiers
+	}
+	for _, classify := range classifiers {
+		if classify(err) {
+			return true
 		}
 	}
-	query := r.dialect.Update(r.table).Set(updateExp).Where(goqu.Ex{"id": id})
-	sql, args, err := query.ToSQL()
+	return false
+}
+
+// RunInTransactionWithRetry 在事务中执行函数，遇到死锁、锁等待超时等可重试错误时
+// 回滚并按指数退避重试，最多执行 maxAttempts 次；不可重试的错误会立即返回
+func (u *UnitOfWork) RunInTransactionWithRetry(ctx context.Context, maxAttempts int, fn func(IUnitOfWork) error) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	backoff := u.retryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		err = u.RunInTransaction(fn)
+		if err == nil || !u.isRetryable(err) {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxAttempts, err)
+}
+
+// UpdateFieldsById(current.Id, map[string]interface{}{
+func (r *Repository[T]) UpdateFieldsById(id int64, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsById", r.table, &err)
+	_, err = r.UpdateFieldsByIdWithCount(id, fields)
+	return err
+}
+
+// UpdateFieldsByIdWithCount 按 id 更新字段并返回影响行数，存在工作单元时在事务中执行
+// 影响行数为 0 通常表示记录不存在或已被删除；MySQL 默认只统计值发生变化的行，
+// 需要按匹配行数统计时在 DSN 中设置 clientFoundRows=true
+func (r *Repository[T]) UpdateFieldsByIdWithCount(id int64, fields map[string]interface{}) (_ int64, err error) {
+	defer wrapQueryError("UpdateFieldsByIdWithCount", r.table, &err)
+	fields = r.touchFields(fields)
+	sql, args, err := r.updateTable().Set(fields).Where(goqu.Ex{"id": id}).ToSQL()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	if r.uow != nil {
-		_, err := r.uow.GetTx().Exec(sql, args...)
-		return err
-	} else {
-		_, err = r.db.Exec(sql, args...)
-		return err
+	result, err := r.execContext(context.Background(), sql, args...)
+	if err != nil {
+		return 0, err
 	}
+	return result.RowsAffected()
+}
+
+func (r *Repository[T]) UpdateFieldsByIds(ids []int64, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsByIds", r.table, &err)
+	return r.UpdateFieldsByCondition(goqu.Ex{"id": ids}, fields)
+}
+
+// UpdateFieldsByIdWithTx
+func (r *Repository[T]) UpdateFieldsByIdWithTx(id int64, fields map[string]interface{}) (err error) {
+	defer wrapQueryError("UpdateFieldsByIdWithTx", r.table, &err)
+	_, err = r.UpdateFieldsByIdWithCount(id, fields)
+	return err
 }
 
 // ScanTx(ctx context.Context, dest interface{}) error
-func (r *Repository[T]) ScanTx(ctx context.Context, dest interface{}) error {
-	query := r.dialect.From(r.table)
+func (r *Repository[T]) ScanTx(ctx context.Context, dest interface{}) (err error) {
+	defer wrapQueryError("ScanTx", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return err
+	}
+	query := r.selectFrom()
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return err
 	}
-	return r.db.QueryRowxContext(ctx, sql, args...).StructScan(dest)
+	return r.readDB().QueryRowxContext(ctx, sql, args...).StructScan(dest)
 }
 
-// ScanInt64Slice() ([]int64, error)
-func (r *Repository[T]) ScanInt64Slice() ([]int64, error) {
-	query := r.dialect.From(r.table)
-	sql, args, err := query.ToSQL()
-	if err != nil {
-		return nil, err
+// selectColumn 构造只选择单列的查询，column 可以是列名或 goqu 表达式，ScanInt64Slice 和 ScanFloat64 共用
+func (r *Repository[T]) selectColumn(column interface{}) (IQueryable[T], error) {
+	if column == nil || column == "" {
+		return nil, errors.New("a single column to scan is required")
 	}
-	rows, err := r.db.Queryx(sql, args...)
+	return r.Query().Select(column), nil
+}
+
+// ScanInt64Slice 查询单列并扫描为 []int64，例如 repo.ScanInt64Slice("id")
+func (r *Repository[T]) ScanInt64Slice(column interface{}) (_ []int64, err error) {
+	defer wrapQueryError("ScanInt64Slice", r.table, &err)
+	query, err := r.selectColumn(column)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var result []int64
-	for rows.Next() {
-		var val int64
-		if err := rows.Scan(&val); err != nil {
-			return nil, err
-		}
-		result = append(result, val)
-	}
-	return result, nil
+	return ScanSlice[T, int64](query)
 }
 
-// ScanFloat64() (float64, error)
-func (r *Repository[T]) ScanFloat64() (float64, error) {
-	query := r.dialect.From(r.table)
+// ScanFloat64 查询单个值并扫描为 float64，例如 repo.ScanFloat64(goqu.SUM("amount"))
+func (r *Repository[T]) ScanFloat64(column interface{}) (_ float64, err error) {
+	defer wrapQueryError("ScanFloat64", r.table, &err)
+	if err := r.checkDB(); err != nil {
+		return 0, err
+	}
+	query, err := r.selectColumn(column)
+	if err != nil {
+		return 0, err
+	}
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return 0, err
 	}
 	var result float64
-	err = r.db.QueryRow(sql, args...).Scan(&result)
+	err = r.readDB().QueryRow(sql, args...).Scan(&result)
 	if err != nil {
 		return 0, err
 	}
@@ -756,14 +1728,15 @@ func (r *Repository[T]) ScanFloat64() (float64, error) {
 }
 
 // 写一个方法根据条件查询单个对象
-func (r *Repository[T]) QuerySingle(condition goqu.Ex) (*T, error) {
-	query := r.dialect.From(r.table).Where(condition)
+func (r *Repository[T]) QuerySingle(condition goqu.Ex) (_ *T, err error) {
+	defer wrapQueryError("QuerySingle", r.table, &err)
+	query := r.selectEntity().Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var result T
-	err = r.db.QueryRow(sql, args...).Scan(&result)
+	err = r.readDB().Get(&result, sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -771,35 +1744,177 @@ func (r *Repository[T]) QuerySingle(condition goqu.Ex) (*T, error) {
 }
 
 // QuerySingleTx 带事务的 工作单元
-func (r *Repository[T]) QuerySingleTx(ctx context.Context, condition goqu.Ex) (*T, error) {
-	query := r.dialect.From(r.table).Where(condition)
+func (r *Repository[T]) QuerySingleTx(ctx context.Context, condition goqu.Ex) (_ *T, err error) {
+	defer wrapQueryError("QuerySingleTx", r.table, &err)
+	query := r.selectEntity().Where(condition)
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return nil, err
 	}
 	var result T
-	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(&result)
+	err = r.readDB().GetContext(ctx, &result, sql, args...)
 	if err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
+
+// FindByIDs 按 id 列表查询，ids 为空时不执行查询
+func (r *Repository[T]) FindByIDs(ids []int64) (_ []*T, err error) {
+	defer wrapQueryError("FindByIDs", r.table, &err)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.Query().Where(goqu.Ex{"id": ids}).ToList()
+}
+
+// FindByIDsOrdered 按 id 列表查询，并按 ids 的顺序返回结果，符合 dataloader 的约定：
+// 结果与 ids 一一对应，长度相同，不存在的 id 对应位置为 nil，重复的 id 返回同一个实体
+func (r *Repository[T]) FindByIDsOrdered(ids []int64) (_ []*T, err error) {
+	defer wrapQueryError("FindByIDsOrdered", r.table, &err)
+	items, err := r.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*T, len(items))
+	for _, item := range items {
+		id, ok := r.entityID(item)
+		if !ok {
+			var entity T
+			return nil, fmt.Errorf("%T has no integer id field", entity)
+		}
+		byID[id] = item
+	}
+
+	results := make([]*T, len(ids))
+	for i, id := range ids {
+		results[i] = byID[id]
+	}
+	return results, nil
+}
+
+// entityID 读取实体 id 列的整数值
+func (r *Repository[T]) entityID(entity *T) (int64, bool) {
+	v := reflect.ValueOf(entity).Elem()
+	for _, c := range entityColumns(v.Type(), r.nameMapper) {
+		if c.name != "id" {
+			continue
+		}
+		field := fieldByIndex(v, c.index)
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return field.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(field.Uint()), true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// ErrNotFound Refresh 时主键对应的记录不存在
+var ErrNotFound = errors.New("record not found")
+
+// Refresh 按主键重新读取记录并覆盖 entity 的字段，用于取回触发器、默认值等由数据库填充的列
+// 主键列由 PrimaryKeyer 决定，默认为 id；记录不存在时返回 ErrNotFound，entity 保持不变
+func (r *Repository[T]) Refresh(entity *T) (err error) {
+	defer wrapQueryError("Refresh", r.table, &err)
+	pk := primaryKeyColumn[T]()
+	key := r.getFieldValue(entity, pk)
+	if key == nil {
+		return fmt.Errorf("%T has no %s field", entity, pk)
+	}
+
+	fresh, err := r.Shard(entity).QuerySingle(goqu.Ex{pk: key})
+	if errors.Is(err, stdsql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(entity).Elem().Set(reflect.ValueOf(fresh).Elem())
+	return nil
+}
+
+// FindByIDsChunked 按 chunkSize 将 ids 去重后分批执行 FindByIDs 并合并结果，避免超长的 IN 列表
+// 结果不保证与 ids 的顺序一致
+func (r *Repository[T]) FindByIDsChunked(ids []int64, chunkSize int) (_ []*T, err error) {
+	defer wrapQueryError("FindByIDsChunked", r.table, &err)
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be greater than 0")
+	}
+
+	seen := make(map[int64]struct{}, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+
+	var results []*T
+	for start := 0; start < len(unique); start += chunkSize {
+		end := start + chunkSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		items, err := r.FindByIDs(unique[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, items...)
+	}
+	return results, nil
+}
+
+// ErrClaimWithoutTx ClaimRows 不在已开始的工作单元事务中调用
+var ErrClaimWithoutTx = errors.New("ClaimRows requires a unit of work with an active transaction")
+
+// ClaimRows 在事务中按 orderBy 锁定最多 limit 行满足条件的记录，已被其他事务锁定的行会被跳过，
+// 适合用数据表实现任务队列：SELECT ... WHERE status = 'pending' ORDER BY id LIMIT n FOR UPDATE SKIP LOCKED
+// 锁在事务提交或回滚时释放，因此必须通过 WithUnitOfWork/NewRepositoryTx 在事务内调用，需要 MySQL 8.0+
+func (r *Repository[T]) ClaimRows(condition goqu.Ex, limit int, orderBy string) (_ []*T, err error) {
+	defer wrapQueryError("ClaimRows", r.table, &err)
+	return r.ClaimRowsTx(context.Background(), condition, limit, orderBy)
+}
+
+func (r *Repository[T]) ClaimRowsTx(ctx context.Context, condition goqu.Ex, limit int, orderBy string) (_ []*T, err error) {
+	defer wrapQueryError("ClaimRowsTx", r.table, &err)
+	if r.uow == nil || r.uow.GetTx() == nil {
+		return nil, ErrClaimWithoutTx
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("claim limit must be positive, got %d", limit)
+	}
+	q := r.Query().Where(condition).OrderByRaw(orderBy).Take(limit).(*Queryable[T])
+	q.query = q.query.ForUpdate(exp.SkipLocked)
+	return q.ToListTx(ctx)
+}
+
 func (r *Repository[T]) ToSQL() (sql string, params []interface{}, err error) {
-	query := r.dialect.From(r.table)
+	query := r.selectFrom()
 	query1, args, err := query.ToSQL()
 	return query1, args, err
 }
 
-func (r *Repository[T]) CreateAndReturnIDWithTx(entity *T) (int64, error) {
+func (r *Repository[T]) CreateAndReturnIDWithTx(entity *T) (_ int64, err error) {
+	defer wrapQueryError("CreateAndReturnIDWithTx", r.table, &err)
+	if err := r.beforeInsert(entity); err != nil {
+		return 0, err
+	}
+
 	// 构造插入语句
-	query := r.dialect.Insert(r.table).Rows(entity)
+	query := r.dialect.Insert(r.table).Rows(r.entityRecord(entity))
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return 0, fmt.Errorf("生成插入SQL失败: %w", err)
 	}
 
 	// 通过事务执行插入
-	result, err := r.uow.GetTx().Exec(sql, args...)
+	result, err := r.uow.Exec(sql, args...)
 	if err != nil {
 		return 0, fmt.Errorf("插入记录失败: %w", err)
 	}
@@ -813,14 +1928,24 @@ func (r *Repository[T]) CreateAndReturnIDWithTx(entity *T) (int64, error) {
 	return id, nil
 }
 
-func (r *Repository[T]) CreateAndReturnID(entity *T) (int64, error) {
+func (r *Repository[T]) CreateAndReturnID(entity *T) (_ int64, err error) {
+	defer wrapQueryError("CreateAndReturnID", r.table, &err)
+	if shard := r.Shard(entity); shard != r {
+		return shard.CreateAndReturnID(entity)
+	}
+	if err := r.checkDB(); err != nil {
+		return 0, err
+	}
 	// 如果有工作单元，调用 CreateAndReturnIDWithTx
 	if r.uow != nil {
 		return r.CreateAndReturnIDWithTx(entity)
 	}
+	if err := r.beforeInsert(entity); err != nil {
+		return 0, err
+	}
 
 	// 构造插入语句
-	query := r.dialect.Insert(r.table).Rows(entity)
+	query := r.dialect.Insert(r.table).Rows(r.entityRecord(entity))
 	sql, args, err := query.ToSQL()
 	if err != nil {
 		return 0, fmt.Errorf("生成插入SQL失败: %w", err)
@@ -840,3 +1965,92 @@ func (r *Repository[T]) CreateAndReturnID(entity *T) (int64, error) {
 
 	return id, nil
 }
+
+// ErrUpsertDialect 非 MySQL 仓储调用 UpsertWithResult
+var ErrUpsertDialect = errors.New("UpsertWithResult is only supported for the MySQL dialect")
+
+// ErrUpsertWithTenant 设置了租户隔离时拒绝 UpsertWithResult，ON DUPLICATE KEY UPDATE 没有租户条件，
+// 命中其他租户的唯一键时会覆盖其数据
+var ErrUpsertWithTenant = errors.New("UpsertWithResult is not allowed on a tenant-scoped repository")
+
+// UpsertResult UpsertWithResult 的执行结果
+type UpsertResult struct {
+	Inserted     bool  // 是否插入了新行，false 表示命中唯一键并走了更新分支
+	LastInsertID int64 // 插入时的自增 id，更新时 MySQL 返回 0
+}
+
+// UpsertWithResult 执行 INSERT ... ON DUPLICATE KEY UPDATE，并返回插入还是更新，只支持 MySQL 方言，其他方言返回 ErrUpsertDialect；
+// 设置了租户隔离的仓储返回 ErrUpsertWithTenant
+// updateCols 为冲突时更新的列，为空时更新除 conflictCols 之外的全部列
+// MySQL 按表上的主键/唯一索引判断冲突，conflictCols 不会出现在 SQL 中，只用于推导 updateCols
+//
+// 判断依据是 MySQL 的影响行数：插入返回 1，更新返回 2，命中但值未变化返回 0（连接开启 CLIENT_FOUND_ROWS 时为 1，
+// 此时无法区分插入和未变化的更新）
+func (r *Repository[T]) UpsertWithResult(entity *T, conflictCols, updateCols []string) (_ UpsertResult, err error) {
+	defer wrapQueryError("UpsertWithResult", r.table, &err)
+	return r.UpsertWithResultContext(context.Background(), entity, conflictCols, updateCols)
+}
+
+// UpsertWithResultContext 带 context 的 UpsertWithResult，同样只支持 MySQL
+func (r *Repository[T]) UpsertWithResultContext(ctx context.Context, entity *T, conflictCols, updateCols []string) (_ UpsertResult, err error) {
+	defer wrapQueryError("UpsertWithResultContext", r.table, &err)
+	if r.dbType != MySQL {
+		return UpsertResult{}, ErrUpsertDialect
+	}
+	if r.tenantColumn != "" {
+		return UpsertResult{}, ErrUpsertWithTenant
+	}
+	if shard := r.Shard(entity); shard != r {
+		return shard.UpsertWithResultContext(ctx, entity, conflictCols, updateCols)
+	}
+	if err := r.beforeInsert(entity); err != nil {
+		return UpsertResult{}, err
+	}
+	record := r.insertRecord(entity, &InsertOptions{})
+
+	if len(updateCols) == 0 {
+		conflict := make(map[string]bool, len(conflictCols))
+		for _, col := range conflictCols {
+			conflict[col] = true
+		}
+		for col := range record {
+			if !conflict[col] {
+				updateCols = append(updateCols, col)
+			}
+		}
+		sort.Strings(updateCols)
+	}
+	if len(updateCols) == 0 {
+		return UpsertResult{}, errors.New("upsert requires at least one column to update")
+	}
+	updates := make([]string, len(updateCols))
+	for i, col := range updateCols {
+		if _, ok := record[col]; !ok {
+			return UpsertResult{}, fmt.Errorf("update column %s is not a field of %s", col, r.table)
+		}
+		updates[i] = fmt.Sprintf("`%s`=VALUES(`%s`)", col, col)
+	}
+
+	// 不使用 goqu 的 OnConflict：mysql 方言会同时生成 INSERT IGNORE，把其他错误降级为警告
+	sql, args, err := r.dialect.Insert(r.table).Rows(record).ToSQL()
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	sql += " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ",")
+	result, err := r.execContext(ctx, sql, args...)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	if affected != 1 {
+		return UpsertResult{}, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	return UpsertResult{Inserted: true, LastInsertID: id}, nil
+}
@@ -1,9 +1,21 @@
 package core
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/doug-martin/goqu/v9"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // TestEntity for testing purposes
@@ -74,6 +86,26 @@ func TestCalculateSafeBatchSize(t *testing.T) {
 	}
 }
 
+func TestMaxPlaceholders(t *testing.T) {
+	tests := []struct {
+		dbType   DialectType
+		expected int
+	}{
+		{MySQL, 65535},
+		{StarRocks, 65535},
+		{DialectType("unknown"), 16384},
+	}
+
+	for _, tt := range tests {
+		if result := maxPlaceholders(tt.dbType); result != tt.expected {
+			t.Errorf("maxPlaceholders(%q) = %d, expected %d", tt.dbType, result, tt.expected)
+		}
+	}
+	if result := calculateSafeBatchSize(10, maxPlaceholders(MySQL)); result != 5242 {
+		t.Errorf("Expected MySQL batch size 5242 for 10 fields, got %d", result)
+	}
+}
+
 func TestDialectType(t *testing.T) {
 	if MySQL != "mysql" {
 		t.Errorf("Expected MySQL dialect to be 'mysql', got '%s'", MySQL)
@@ -125,3 +157,1248 @@ func ExampleRepository_Query() {
 
 	// Output: (example only, won't actually run)
 }
+
+func TestWithTenant(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	tenantRepo := repo.WithTenant("tenant_id", 42)
+
+	if repo.tenantColumn != "" {
+		t.Error("Expected WithTenant not to modify the original repository")
+	}
+
+	sql, _, err := tenantRepo.Query().Where(goqu.Ex{"status": 1}).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if !strings.Contains(sql, "`tenant_id` = 42") {
+		t.Errorf("Expected SELECT to include tenant predicate, got %q", sql)
+	}
+
+	if err := tenantRepo.UpdateFieldsByCondition(goqu.Ex{"id": 1}, map[string]interface{}{"status": 2}); err != nil {
+		t.Fatalf("UpdateFieldsByCondition failed: %v", err)
+	}
+	if err := tenantRepo.BatchDelete(goqu.Ex{"status": 0}); err != nil {
+		t.Fatalf("BatchDelete failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(execs))
+	}
+	for _, stmt := range execs {
+		if !strings.Contains(stmt, "`tenant_id` = 42") {
+			t.Errorf("Expected statement to include tenant predicate, got %q", stmt)
+		}
+	}
+	if !strings.HasPrefix(execs[0], "UPDATE") {
+		t.Errorf("Expected UPDATE statement, got %q", execs[0])
+	}
+}
+
+func TestWithTenantFromDataset(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL).WithTenant("tenant_id", 42)
+
+	ds := goqu.Dialect("mysql").From("test_table").Where(goqu.Ex{"status": 1})
+	sql, _, err := repo.Query().FromDataset(ds).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	expected := "SELECT * FROM `test_table` WHERE ((`status` = 1) AND (`tenant_id` = 42))"
+	if sql != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, sql)
+	}
+
+	sql, _, _ = repo.QueryAs("t").FromDataset(goqu.Dialect("mysql").From(goqu.T("test_table").As("t"))).ToSQL()
+	if !strings.Contains(sql, "`t`.`tenant_id` = 42") {
+		t.Errorf("Expected the aliased tenant predicate, got %q", sql)
+	}
+}
+
+func TestBatchDeleteChunked(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueRowsAffected(100, 100, 37, 0)
+
+	total, err := repo.BatchDeleteChunked(goqu.Ex{"status": 0}, 100)
+	if err != nil {
+		t.Fatalf("BatchDeleteChunked failed: %v", err)
+	}
+	if total != 237 {
+		t.Errorf("Expected 237 deleted rows, got %d", total)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 4 {
+		t.Fatalf("Expected 4 DELETE statements, got %d", len(execs))
+	}
+	expected := "DELETE FROM `test_table` WHERE (`status` = 0) LIMIT 100"
+	if execs[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, execs[0])
+	}
+
+	if _, err := repo.BatchDeleteChunked(goqu.Ex{"status": 0}, 0); err == nil {
+		t.Error("Expected error for non-positive chunk size")
+	}
+}
+
+func TestBatchDeleteChunkedCancelled(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueRowsAffected(10, 10, 10)
+
+	// 第一批执行时取消，等待间隔期间应停止而不再执行下一批
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake.setOnExec(func(string) { cancel() })
+
+	total, err := repo.BatchDeleteChunkedTx(ctx, goqu.Ex{"status": 0}, &ChunkedDeleteOption{
+		ChunkSize: 10,
+		Interval:  time.Hour,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if total != 10 {
+		t.Errorf("Expected the first chunk to be reported, got %d", total)
+	}
+	if execs := fake.Execs(); len(execs) != 1 || !strings.HasPrefix(execs[0], "DELETE") {
+		t.Errorf("Expected exactly one DELETE, got %v", execs)
+	}
+}
+
+func TestNewRepositoryWithPrefix(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	db.prefix = "app1_"
+	repo := NewRepositoryWithPrefix[TestEntity](db, "users", MySQL)
+
+	sql, _, err := repo.Query().ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if sql != "SELECT * FROM `app1_users`" {
+		t.Errorf("Expected prefixed table in goqu SQL, got %q", sql)
+	}
+
+	if err := repo.BatchInsert([]*TestEntity{{ID: 1, Name: "a"}}, nil); err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if err := repo.BatchUpdate([]*TestEntity{{ID: 1, Name: "b"}}, &BatchUpdateOption{
+		BatchSize:    10,
+		UpdateFields: []string{"name"},
+		KeyField:     "id",
+	}); err != nil {
+		t.Fatalf("BatchUpdate failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(execs))
+	}
+	if !strings.HasPrefix(execs[0], "INSERT INTO app1_users ") {
+		t.Errorf("Expected prefixed table in batch insert, got %q", execs[0])
+	}
+	if !strings.HasPrefix(execs[1], "UPDATE app1_users ") {
+		t.Errorf("Expected prefixed table in batch update, got %q", execs[1])
+	}
+}
+
+func TestRunInTransactionWithRetry(t *testing.T) {
+	db, _ := newFakeDBLogger(t)
+	uow := NewUnitOfWork(db)
+	uow.retryBackoff = time.Millisecond
+
+	attempts := 0
+	err := uow.RunInTransactionWithRetry(context.Background(), 5, func(IUnitOfWork) error {
+		attempts++
+		switch attempts {
+		case 1:
+			return &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+		case 2:
+			return &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success on third attempt, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	nonRetryable := errors.New("duplicate entry")
+	err = uow.RunInTransactionWithRetry(context.Background(), 5, func(IUnitOfWork) error {
+		attempts++
+		return nonRetryable
+	})
+	if !errors.Is(err, nonRetryable) || attempts != 1 {
+		t.Errorf("Expected non-retryable error after 1 attempt, got %v after %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	serialization := errors.New("40001: could not serialize access")
+	custom := uow.WithRetryClassifiers(func(err error) bool { return errors.Is(err, serialization) })
+	err = custom.RunInTransactionWithRetry(context.Background(), 2, func(IUnitOfWork) error {
+		attempts++
+		return serialization
+	})
+	if !errors.Is(err, serialization) || attempts != 2 {
+		t.Errorf("Expected custom classifier to retry until exhausted, got %v after %d attempts", err, attempts)
+	}
+
+	// 共享的工作单元仍使用默认判断，不会重试自定义错误
+	attempts = 0
+	err = uow.RunInTransactionWithRetry(context.Background(), 2, func(IUnitOfWork) error {
+		attempts++
+		return serialization
+	})
+	if !errors.Is(err, serialization) || attempts != 1 {
+		t.Errorf("Expected the original unit of work to keep its classifiers, got %v after %d attempts", err, attempts)
+	}
+}
+
+func TestRepositoryContextWrites(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	var repo IRepository[TestEntity] = NewRepository[TestEntity](db, "test_table", MySQL)
+	ctx := context.Background()
+
+	if err := repo.CreateContext(ctx, &TestEntity{ID: 1, Name: "a"}); err != nil {
+		t.Fatalf("CreateContext failed: %v", err)
+	}
+	if err := repo.UpdateContext(ctx, &TestEntity{ID: 1, Name: "b"}); err != nil {
+		t.Fatalf("UpdateContext failed: %v", err)
+	}
+	if err := repo.UpdateFieldsByConditionContext(ctx, goqu.Ex{"id": 1}, map[string]interface{}{"status": 2}); err != nil {
+		t.Fatalf("UpdateFieldsByConditionContext failed: %v", err)
+	}
+	if err := repo.BatchDeleteContext(ctx, goqu.Ex{"status": 0}); err != nil {
+		t.Fatalf("BatchDeleteContext failed: %v", err)
+	}
+	if len(fake.Execs()) != 4 {
+		t.Fatalf("Expected 4 statements, got %d", len(fake.Execs()))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	writes := []func() error{
+		func() error { return repo.CreateContext(cancelled, &TestEntity{ID: 2}) },
+		func() error { return repo.UpdateContext(cancelled, &TestEntity{ID: 2}) },
+		func() error {
+			return repo.UpdateFieldsByConditionContext(cancelled, goqu.Ex{"id": 2}, map[string]interface{}{"status": 2})
+		},
+		func() error { return repo.BatchDeleteContext(cancelled, goqu.Ex{"id": 2}) },
+	}
+	for i, write := range writes {
+		if err := write(); !errors.Is(err, context.Canceled) {
+			t.Errorf("write %d: expected context.Canceled, got %v", i, err)
+		}
+	}
+	if len(fake.Execs()) != 4 {
+		t.Errorf("Expected cancelled writes not to reach the database, got %d statements", len(fake.Execs()))
+	}
+}
+
+type testTimestampEntity struct {
+	ID        int64  `db:"id"`
+	Name      string `db:"name"`
+	CreatedAt int64  `db:"created_at"`
+}
+
+func TestCreateWithOptions(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[testTimestampEntity](db, "test_table", MySQL)
+
+	if err := repo.CreateWithOptions(&testTimestampEntity{Name: "a"}, &InsertOptions{OmitZeroValues: true}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+	if err := repo.CreateOmitting(&testTimestampEntity{ID: 7, Name: "b", CreatedAt: 100}, "created_at"); err != nil {
+		t.Fatalf("CreateOmitting failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(execs))
+	}
+	expected := "INSERT INTO `test_table` (`name`) VALUES ('a')"
+	if execs[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, execs[0])
+	}
+	expected = "INSERT INTO `test_table` (`id`, `name`) VALUES (7, 'b')"
+	if execs[1] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, execs[1])
+	}
+}
+
+func TestRepositoryIncrement(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "posts", MySQL)
+
+	if err := repo.Increment(goqu.Ex{"id": 7}, "views", 1); err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+	if err := repo.Decrement(goqu.Ex{"id": 7}, "stock", 3); err != nil {
+		t.Fatalf("Decrement failed: %v", err)
+	}
+	if err := repo.IncrementFields(goqu.Ex{"id": 7}, map[string]int64{"views": 2, "likes": 5}); err != nil {
+		t.Fatalf("IncrementFields failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(execs))
+	}
+	expected := []string{
+		"UPDATE `posts` SET `views`=`views` + ? WHERE (`id` = ?)",
+		"UPDATE `posts` SET `stock`=`stock` + ? WHERE (`id` = ?)",
+		"UPDATE `posts` SET `likes`=`likes` + ?,`views`=`views` + ? WHERE (`id` = ?)",
+	}
+	expectedArgs := [][]interface{}{
+		{int64(1), int64(7)},
+		{int64(-3), int64(7)},
+		{int64(5), int64(2), int64(7)},
+	}
+	for i := range expected {
+		if execs[i] != expected[i] {
+			t.Errorf("Expected SQL %q, got %q", expected[i], execs[i])
+		}
+		if !reflect.DeepEqual(fake.args[i], expectedArgs[i]) {
+			t.Errorf("Expected args %v, got %v", expectedArgs[i], fake.args[i])
+		}
+	}
+
+	if err := repo.IncrementFields(goqu.Ex{"id": 7}, nil); err == nil {
+		t.Error("Expected error for empty deltas")
+	}
+}
+
+func TestUnitOfWorkCheckpoint(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	uow := NewUnitOfWork(db)
+	stepFailed := errors.New("step failed")
+
+	err := uow.RunInTransaction(func(tx IUnitOfWork) error {
+		if _, err := tx.Exec("UPDATE a SET x = 1"); err != nil {
+			return err
+		}
+		err := tx.Checkpoint("step one", func() error {
+			if _, err := tx.Exec("UPDATE b SET x = 1"); err != nil {
+				return err
+			}
+			return stepFailed
+		})
+		if !errors.Is(err, stepFailed) {
+			t.Errorf("Expected the step error, got %v", err)
+		}
+		return tx.Checkpoint("outer", func() error {
+			return tx.Checkpoint("outer", func() error {
+				_, err := tx.Exec("UPDATE c SET x = 1")
+				return err
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Expected the transaction to commit, got %v", err)
+	}
+
+	expected := []string{
+		"UPDATE a SET x = 1",
+		"SAVEPOINT sp_step_one_1",
+		"UPDATE b SET x = 1",
+		"ROLLBACK TO SAVEPOINT sp_step_one_1",
+		"SAVEPOINT sp_outer_2",
+		"SAVEPOINT sp_outer_3",
+		"UPDATE c SET x = 1",
+		"RELEASE SAVEPOINT sp_outer_3",
+		"RELEASE SAVEPOINT sp_outer_2",
+	}
+	if execs := fake.Execs(); !reflect.DeepEqual(execs, expected) {
+		t.Errorf("Expected statements %v, got %v", expected, execs)
+	}
+
+	if err := NewUnitOfWork(db).Checkpoint("x", func() error { return nil }); err == nil {
+		t.Error("Expected error without a transaction")
+	}
+}
+
+func TestUnitOfWorkExecIsLogged(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	observed, logs := observer.New(zap.DebugLevel)
+	db.logger = zap.New(observed)
+	fake.queueRowsAffected(2)
+
+	uow := NewUnitOfWork(db)
+	err := uow.RunInTransaction(func(uow IUnitOfWork) error {
+		result, err := uow.Exec("UPDATE `test_table` SET `status` = ?", 1)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if affected != 2 {
+			t.Errorf("Expected 2 affected rows, got %d", affected)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+
+	entries := logs.FilterField(zap.String("operation", "TxExec")).All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 logged transactional exec, got %d", len(entries))
+	}
+	if query := entries[0].ContextMap()["query"]; query != "UPDATE `test_table` SET `status` = ?" {
+		t.Errorf("Expected logged query, got %v", query)
+	}
+
+	if _, err := NewUnitOfWork(db).Exec("SELECT 1"); err == nil {
+		t.Error("Expected error when no transaction has begun")
+	}
+}
+
+func TestUpdateFieldsByConditionSafe(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	if err := repo.UpdateFieldsByConditionSafe(goqu.Ex{"id": 1}, map[string]interface{}{"name": "a", "status": 2}); err != nil {
+		t.Fatalf("UpdateFieldsByConditionSafe failed: %v", err)
+	}
+	if len(fake.Execs()) != 1 {
+		t.Fatalf("Expected 1 statement, got %d", len(fake.Execs()))
+	}
+
+	err := repo.UpdateFieldsByConditionSafe(goqu.Ex{"id": 1}, map[string]interface{}{"name": "a", "is_admin": 1, "balance": 0})
+	if err == nil {
+		t.Fatal("Expected error for unknown columns")
+	}
+	if !strings.Contains(err.Error(), "balance, is_admin") {
+		t.Errorf("Expected error to list unknown columns, got %v", err)
+	}
+	if len(fake.Execs()) != 1 {
+		t.Error("Expected no statement to be executed for invalid fields")
+	}
+}
+
+func TestBatchInsertIgnore(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	// 三条中有一条主键冲突被跳过，第二次分两批执行
+	entities := []*TestEntity{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	fake.queueRowsAffected(2, 1, 1)
+	inserted, err := repo.BatchInsertWithCount(entities, &BatchInsertOption{BatchSize: 10, InsertIgnore: true})
+	if err != nil {
+		t.Fatalf("BatchInsertWithCount failed: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("Expected 2 inserted rows, got %d", inserted)
+	}
+	inserted, err = repo.BatchInsertWithCount(entities, &BatchInsertOption{BatchSize: 2, UseNamedExec: true, InsertIgnore: true})
+	if err != nil {
+		t.Fatalf("BatchInsertWithCount with NamedExec failed: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("Expected the affected rows of both batches to be summed, got %d", inserted)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(execs))
+	}
+	for _, sql := range execs {
+		if !strings.HasPrefix(sql, "INSERT IGNORE INTO test_table ") {
+			t.Errorf("Expected INSERT IGNORE statement, got %q", sql)
+		}
+	}
+}
+
+type validatedEntity struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+var errNameRequired = errors.New("name is required")
+
+func (e *validatedEntity) Validate() error {
+	if e.Name == "" {
+		return errNameRequired
+	}
+	return nil
+}
+
+func TestCreateValidatesEntity(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[validatedEntity](db, "users", MySQL)
+
+	if err := repo.Create(&validatedEntity{ID: 1}); !errors.Is(err, errNameRequired) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+	if len(fake.Execs()) != 0 {
+		t.Fatalf("Expected no SQL for an invalid entity, got %v", fake.Execs())
+	}
+
+	if err := repo.Create(&validatedEntity{ID: 1, Name: "alice"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(fake.Execs()) != 1 {
+		t.Errorf("Expected 1 statement for a valid entity, got %d", len(fake.Execs()))
+	}
+}
+
+func TestBatchCreateValidatesAllEntities(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[validatedEntity](db, "users", MySQL)
+
+	entities := []*validatedEntity{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3}}
+	err := repo.BatchInsert(entities, &BatchInsertOption{BatchSize: 1})
+	if err == nil || !strings.Contains(err.Error(), "index 2") {
+		t.Errorf("Expected validation error for index 2, got %v", err)
+	}
+	if err := repo.BatchCreate(entities); err == nil {
+		t.Error("Expected BatchCreate validation error")
+	}
+	if len(fake.Execs()) != 0 {
+		t.Fatalf("Expected no partial insert, got %v", fake.Execs())
+	}
+
+	entities[2].Name = "c"
+	if err := repo.BatchCreate(entities); err != nil {
+		t.Fatalf("BatchCreate failed: %v", err)
+	}
+	if len(fake.Execs()) != 1 {
+		t.Errorf("Expected 1 statement, got %d", len(fake.Execs()))
+	}
+}
+
+func TestPageResultNavigation(t *testing.T) {
+	cases := []struct {
+		name       string
+		result     PageResult[TestEntity]
+		totalPages int
+		hasNext    bool
+		hasPrev    bool
+	}{
+		{"first page", PageResult[TestEntity]{Total: 25, Page: 1, PageSize: 10}, 3, true, false},
+		{"middle page", PageResult[TestEntity]{Total: 25, Page: 2, PageSize: 10}, 3, true, true},
+		{"last page", PageResult[TestEntity]{Total: 25, Page: 3, PageSize: 10}, 3, false, true},
+		{"exact multiple", PageResult[TestEntity]{Total: 20, Page: 2, PageSize: 10}, 2, false, true},
+		{"single page", PageResult[TestEntity]{Total: 5, Page: 1, PageSize: 10}, 1, false, false},
+		{"empty", PageResult[TestEntity]{Total: 0, Page: 1, PageSize: 10}, 0, false, false},
+		{"zero page size", PageResult[TestEntity]{Total: 5, Page: 1, PageSize: 0}, 0, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.result.TotalPages(); got != c.totalPages {
+				t.Errorf("TotalPages() = %d, want %d", got, c.totalPages)
+			}
+			if got := c.result.HasNext(); got != c.hasNext {
+				t.Errorf("HasNext() = %v, want %v", got, c.hasNext)
+			}
+			if got := c.result.HasPrev(); got != c.hasPrev {
+				t.Errorf("HasPrev() = %v, want %v", got, c.hasPrev)
+			}
+		})
+	}
+}
+
+func TestRepositoryWithoutDatabase(t *testing.T) {
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	entity := &TestEntity{ID: 1, Name: "a"}
+
+	checks := map[string]func() error{
+		"Create":      func() error { return repo.Create(entity) },
+		"Update":      func() error { return repo.Update(entity) },
+		"BatchCreate": func() error { return repo.BatchCreate([]*TestEntity{entity}) },
+		"BatchInsert": func() error { return repo.BatchInsert([]*TestEntity{entity}, nil) },
+		"BatchUpdate": func() error {
+			return repo.BatchUpdate([]*TestEntity{entity}, &BatchUpdateOption{UpdateFields: []string{"name"}, KeyField: "id"})
+		},
+		"BatchDelete":   func() error { return repo.BatchDelete(goqu.Ex{"id": 1}) },
+		"CreateContext": func() error { return repo.CreateContext(context.Background(), entity) },
+		"ToList":        func() error { _, err := repo.Query().ToList(); return err },
+		"Count":         func() error { _, err := repo.Query().Count(); return err },
+		"FirstOrDefault": func() error {
+			_, err := repo.Query().Where(goqu.Ex{"id": 1}).FirstOrDefault()
+			return err
+		},
+		"ScanTx":           func() error { return repo.ScanTx(context.Background(), &TestEntity{}) },
+		"ScanFloat64":      func() error { _, err := repo.ScanFloat64(goqu.SUM("status")); return err },
+		"ScanInt64Slice":   func() error { _, err := repo.ScanInt64Slice("id"); return err },
+		"UpsertWithResult": func() error { _, err := repo.UpsertWithResult(entity, []string{"id"}, nil); return err },
+		"UnitOfWork.Begin": func() error { return NewUnitOfWork(nil).Begin() },
+		"DBLogger.Close":   func() error { return (*DBLogger)(nil).Close() },
+	}
+	for name, check := range checks {
+		t.Run(name, func(t *testing.T) {
+			if err := check(); !errors.Is(err, ErrNoDatabase) {
+				t.Errorf("Expected ErrNoDatabase, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRepositorySQLMockRoundTrip(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := NewDBLoggerWithDialect(sqlx.NewDb(mockDB, "mysql"), MySQL, zap.NewNop(), "")
+	repo := NewRepository[TestEntity](db, "test_table", "")
+	if repo.dbType != MySQL {
+		t.Errorf("Expected the repository to inherit the DBLogger dialect, got %q", repo.dbType)
+	}
+
+	mock.ExpectExec("INSERT INTO `test_table` (`id`, `name`, `status`) VALUES (1, 'alice', 1)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT `id`, `name`, `status` FROM `test_table` WHERE (`id` = 1) LIMIT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}).AddRow(1, "alice", 1))
+
+	if err := repo.Create(&TestEntity{ID: 1, Name: "alice", Status: 1}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	entity, err := repo.Query().Where(goqu.Ex{"id": 1}).FirstOrDefault()
+	if err != nil {
+		t.Fatalf("FirstOrDefault failed: %v", err)
+	}
+	if entity.ID != 1 || entity.Name != "alice" || entity.Status != 1 {
+		t.Errorf("Unexpected entity: %+v", entity)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestRepositoryTruncate(t *testing.T) {
+	sqlDB, fake := newFakeSQLX(t, t.Name())
+	db := NewDBLogger(sqlDB, zap.NewNop(), "app_")
+	repo := NewRepositoryWithPrefix[TestEntity](db, "test_table", MySQL)
+
+	if err := repo.Truncate(); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if err := repo.TruncateTx(context.Background()); err != nil {
+		t.Fatalf("TruncateTx failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(execs))
+	}
+	expected := "TRUNCATE TABLE `app_test_table`"
+	for _, sql := range execs {
+		if sql != expected {
+			t.Errorf("Expected SQL %q, got %q", expected, sql)
+		}
+	}
+
+	err := repo.WithTenant("tenant_id", 1).Truncate()
+	if !errors.Is(err, ErrTruncateWithTenant) {
+		t.Errorf("Expected ErrTruncateWithTenant, got %v", err)
+	}
+	if len(fake.Execs()) != 2 {
+		t.Error("Expected tenant-scoped truncate not to execute")
+	}
+
+	err = NewUnitOfWork(db).RunInTransaction(func(tx IUnitOfWork) error {
+		return repo.WithUnitOfWork(tx).Truncate()
+	})
+	if !errors.Is(err, ErrTruncateInTx) {
+		t.Errorf("Expected ErrTruncateInTx, got %v", err)
+	}
+	if len(fake.Execs()) != 2 {
+		t.Error("Expected truncate inside a transaction not to execute")
+	}
+}
+
+func TestUpdateFieldsByIdWithCount(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueRowsAffected(1, 0)
+
+	fields := map[string]interface{}{"status": 2}
+	n, err := repo.UpdateFieldsByIdWithCount(1, fields)
+	if err != nil || n != 1 {
+		t.Errorf("Expected 1 row updated, got %d, %v", n, err)
+	}
+	n, err = repo.UpdateFieldsByIdWithCount(404, fields)
+	if err != nil || n != 0 {
+		t.Errorf("Expected 0 rows updated for missing id, got %d, %v", n, err)
+	}
+
+	expected := "UPDATE `test_table` SET `status`=2 WHERE (`id` = 404)"
+	if execs := fake.Execs(); len(execs) != 2 || execs[1] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, execs)
+	}
+}
+
+func TestUpdateFieldsByIdWithCountInUnitOfWork(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	uow := NewUnitOfWork(db)
+	if err := uow.Begin(); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	repo := NewRepository[TestEntity](db, "test_table", MySQL).WithUnitOfWork(uow)
+	fake.queueRowsAffected(0, 1)
+
+	fields := map[string]interface{}{"status": 2}
+	if n, err := repo.UpdateFieldsByIdWithCount(404, fields); err != nil || n != 0 {
+		t.Errorf("Expected 0 rows updated for missing id, got %d, %v", n, err)
+	}
+	if n, err := repo.UpdateFieldsByIdWithCount(1, fields); err != nil || n != 1 {
+		t.Errorf("Expected 1 row updated, got %d, %v", n, err)
+	}
+	if err := uow.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if execs := fake.Execs(); len(execs) != 2 {
+		t.Errorf("Expected 2 updates in the transaction, got %v", execs)
+	}
+}
+
+func TestFindByIDsOrdered(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	row := func(id int64) []driver.Value { return []driver.Value{id, "n", int64(1)} }
+	fake.queueResult([]string{"id", "name", "status"}, row(1), row(2), row(3))
+
+	items, err := repo.FindByIDsOrdered([]int64{3, 9, 1, 2, 3})
+	if err != nil {
+		t.Fatalf("FindByIDsOrdered failed: %v", err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("Expected one result per id, got %d", len(items))
+	}
+	if items[1] != nil {
+		t.Errorf("Expected nil for the missing id, got %+v", items[1])
+	}
+	for i, want := range map[int]int64{0: 3, 2: 1, 3: 2, 4: 3} {
+		if items[i] == nil || items[i].ID != want {
+			t.Errorf("Expected id %d at position %d, got %+v", want, i, items[i])
+		}
+	}
+
+	if items, err := repo.FindByIDsOrdered(nil); err != nil || len(items) != 0 {
+		t.Errorf("Expected no results for no ids, got %v (%v)", items, err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"id", "name", "status"}, []driver.Value{int64(7), "from-db", int64(3)})
+
+	entity := &TestEntity{ID: 7, Name: "local", Status: 1}
+	if err := repo.Refresh(entity); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if entity.ID != 7 || entity.Name != "from-db" || entity.Status != 3 {
+		t.Errorf("Expected database values to win, got %+v", entity)
+	}
+	if queries := fake.Queries(); len(queries) != 1 || !strings.Contains(queries[0], "WHERE (`id` = 7)") {
+		t.Errorf("Expected lookup by primary key, got %v", queries)
+	}
+
+	fake.queueResult([]string{"id", "name", "status"})
+	missing := &TestEntity{ID: 8, Name: "local"}
+	if err := repo.Refresh(missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if missing.Name != "local" {
+		t.Errorf("Expected entity unchanged when not found, got %+v", missing)
+	}
+}
+
+func TestFindByIDsChunked(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	columns := []string{"id", "name", "status"}
+	row := func(id int64) []driver.Value { return []driver.Value{id, "n", int64(1)} }
+	fake.queueResult(columns, row(1), row(2))
+	fake.queueResult(columns, row(3), row(4))
+	fake.queueResult(columns, row(5))
+
+	items, err := repo.FindByIDsChunked([]int64{1, 2, 3, 2, 4, 5, 1}, 2)
+	if err != nil {
+		t.Fatalf("FindByIDsChunked failed: %v", err)
+	}
+	seen := make(map[int64]int)
+	for _, item := range items {
+		seen[item.ID]++
+	}
+	if len(items) != 5 || len(seen) != 5 {
+		t.Errorf("Expected 5 distinct entities, got %d items: %v", len(items), seen)
+	}
+
+	queries := fake.Queries()
+	if len(queries) != 3 {
+		t.Fatalf("Expected 3 chunked queries, got %d", len(queries))
+	}
+	expected := "SELECT `id`, `name`, `status` FROM `test_table` WHERE (`id` IN (1, 2))"
+	if queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries[0])
+	}
+	if !strings.HasSuffix(queries[2], "WHERE (`id` IN (5))") {
+		t.Errorf("Expected last chunk to hold the remaining id, got %q", queries[2])
+	}
+
+	if items, err := repo.FindByIDsChunked(nil, 2); err != nil || len(items) != 0 {
+		t.Errorf("Expected no results for empty ids, got %v, %v", items, err)
+	}
+	if len(fake.Queries()) != 3 {
+		t.Error("Expected empty ids not to query")
+	}
+	if _, err := repo.FindByIDsChunked([]int64{1}, 0); err == nil {
+		t.Error("Expected error for non-positive chunk size")
+	}
+}
+
+func TestQuerySingle(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	columns := []string{"id", "name", "status"}
+	fake.queueResult(columns, []driver.Value{int64(7), "alice", int64(3)})
+	fake.queueResult(columns, []driver.Value{int64(8), "bob", int64(4)})
+
+	entity, err := repo.QuerySingle(goqu.Ex{"id": 7})
+	if err != nil {
+		t.Fatalf("QuerySingle failed: %v", err)
+	}
+	if *entity != (TestEntity{ID: 7, Name: "alice", Status: 3}) {
+		t.Errorf("Expected all fields populated, got %+v", *entity)
+	}
+
+	entity, err = repo.QuerySingleTx(context.Background(), goqu.Ex{"id": 8})
+	if err != nil {
+		t.Fatalf("QuerySingleTx failed: %v", err)
+	}
+	if *entity != (TestEntity{ID: 8, Name: "bob", Status: 4}) {
+		t.Errorf("Expected all fields populated, got %+v", *entity)
+	}
+
+	expected := "SELECT `id`, `name`, `status` FROM `test_table` WHERE (`id` = 7)"
+	if queries := fake.Queries(); queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries[0])
+	}
+
+	if _, err := repo.QuerySingle(goqu.Ex{"id": 9}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestRepositoryScanColumn(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.queueResult([]string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	fake.queueResult([]string{"SUM(`status`)"}, []driver.Value{float64(7.5)})
+
+	ids, err := repo.ScanInt64Slice("id")
+	if err != nil {
+		t.Fatalf("ScanInt64Slice failed: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("Expected [1 2], got %v", ids)
+	}
+
+	sum, err := repo.ScanFloat64(goqu.SUM("status"))
+	if err != nil {
+		t.Fatalf("ScanFloat64 failed: %v", err)
+	}
+	if sum != 7.5 {
+		t.Errorf("Expected 7.5, got %v", sum)
+	}
+
+	expected := []string{
+		"SELECT `id` FROM `test_table`",
+		"SELECT SUM(`status`) FROM `test_table`",
+	}
+	if queries := fake.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected SQL %v, got %v", expected, queries)
+	}
+
+	if _, err := repo.ScanInt64Slice(""); err == nil {
+		t.Error("Expected error without a column")
+	}
+	if _, err := repo.ScanFloat64(nil); err == nil {
+		t.Error("Expected error without a column")
+	}
+}
+
+func TestNewRepositoryTx(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	// 只有一个连接：事务外的查询会一直等待事务释放连接
+	db.SetMaxOpenConns(1)
+	fake.queueResult([]string{"id", "user_name"}, []driver.Value{int64(1), "alice"})
+
+	var found []*testUser
+	err := NewUnitOfWork(db).RunInTransaction(func(tx IUnitOfWork) error {
+		entities := NewRepositoryTx[TestEntity](tx, "test_table", MySQL)
+		// 任何 IUnitOfWork 实现都通过 DB() 提供连接
+		users := NewRepositoryTx[testUser](wrappedUnitOfWork{tx}, "users", MySQL)
+		if err := entities.Create(&TestEntity{Name: "a", Status: 1}); err != nil {
+			return err
+		}
+		if err := users.Create(&testUser{ID: 1, UserName: "alice"}); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		var err error
+		found, err = users.Query().Where(goqu.Ex{"id": 1}).ToListTx(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+	if len(found) != 1 || found[0].UserName != "alice" {
+		t.Errorf("Expected to read the user inside the transaction, got %v", found)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 2 ||
+		!strings.HasPrefix(execs[0], "INSERT INTO `test_table`") ||
+		!strings.HasPrefix(execs[1], "INSERT INTO `users`") {
+		t.Errorf("Expected both inserts in the transaction, got %v", execs)
+	}
+}
+
+// wrappedUnitOfWork 包装其他工作单元的自定义实现
+type wrappedUnitOfWork struct{ IUnitOfWork }
+
+type testUserOrderRow struct {
+	ID     int64   `db:"id"`
+	Name   string  `db:"name"`
+	Total  float64 `db:"orders.total"`
+	Status int     `db:"orders.status"`
+}
+
+func TestJoinQuery(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	users := NewRepository[TestEntity](db, "users", MySQL).WithTenant("tenant_id", 7)
+	fake.queueResult([]string{"id", "name", "orders.total", "orders.status"},
+		[]driver.Value{int64(1), "alice", 9.5, int64(2)},
+	)
+
+	rows, err := JoinQuery[testUserOrderRow](users, "orders", map[string]string{"orders.user_id": "users.id"}).
+		Where(goqu.Ex{"orders.status": 2}).
+		ToList()
+	if err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if len(rows) != 1 || *rows[0] != (testUserOrderRow{ID: 1, Name: "alice", Total: 9.5, Status: 2}) {
+		t.Errorf("Expected both tables' columns scanned, got %+v", rows)
+	}
+
+	expected := "SELECT `users`.`id`, `users`.`name`, `orders`.`total` AS `orders.total`, " +
+		"`orders`.`status` AS `orders.status` FROM `users` " +
+		"INNER JOIN `orders` ON (`orders`.`user_id` = `users`.`id`) " +
+		"WHERE ((`users`.`tenant_id` = 7) AND (`orders`.`status` = 2))"
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, queries)
+	}
+}
+
+func TestBatchInsertNamedExecIsLogged(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	observed, logs := observer.New(zap.DebugLevel)
+	db.logger = zap.New(observed)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+
+	entities := []*TestEntity{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	opt := &BatchInsertOption{BatchSize: 10, UseNamedExec: true}
+	if err := repo.BatchInsertContext(context.Background(), entities, opt); err != nil {
+		t.Fatalf("BatchInsertContext failed: %v", err)
+	}
+
+	entries := logs.FilterField(zap.String("operation", "NamedExec")).All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 logged named exec, got %d", len(entries))
+	}
+	expected := "INSERT INTO test_table (id,name,status) VALUES (:id,:name,:status)"
+	if query := entries[0].ContextMap()["query"]; query != expected {
+		t.Errorf("Expected logged query %q, got %v", expected, query)
+	}
+	if execs := fake.Execs(); len(execs) != 1 || !strings.HasSuffix(execs[0], "VALUES (?,?,?),(?,?,?)") {
+		t.Errorf("Expected one multi-row insert, got %v", execs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := repo.BatchInsertContext(ctx, entities, opt); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestUpsertWithResult(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	fake.lastInsertID = 7
+	fake.queueRowsAffected(1, 2)
+
+	result, err := repo.UpsertWithResult(&TestEntity{ID: 7, Name: "a", Status: 1}, []string{"id"}, nil)
+	if err != nil {
+		t.Fatalf("UpsertWithResult failed: %v", err)
+	}
+	if !result.Inserted || result.LastInsertID != 7 {
+		t.Errorf("Expected insert with id 7, got %+v", result)
+	}
+
+	result, err = repo.UpsertWithResult(&TestEntity{ID: 7, Name: "b", Status: 2}, []string{"id"}, []string{"name"})
+	if err != nil {
+		t.Fatalf("UpsertWithResult failed: %v", err)
+	}
+	if result.Inserted || result.LastInsertID != 0 {
+		t.Errorf("Expected update, got %+v", result)
+	}
+
+	execs := fake.Execs()
+	expected := []string{
+		"INSERT INTO `test_table` (`id`, `name`, `status`) VALUES (7, 'a', 1) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`status`=VALUES(`status`)",
+		"INSERT INTO `test_table` (`id`, `name`, `status`) VALUES (7, 'b', 2) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+	}
+	if !reflect.DeepEqual(execs, expected) {
+		t.Errorf("Expected SQL %q, got %q", expected, execs)
+	}
+
+	if _, err := repo.UpsertWithResult(&TestEntity{ID: 7}, []string{"id"}, []string{"missing"}); err == nil {
+		t.Error("Expected error for unknown update column")
+	}
+
+	starRocks := NewRepository[TestEntity](db, "test_table", StarRocks)
+	if _, err := starRocks.UpsertWithResult(&TestEntity{ID: 7}, []string{"id"}, nil); !errors.Is(err, ErrUpsertDialect) {
+		t.Errorf("Expected ErrUpsertDialect, got %v", err)
+	}
+	if len(fake.Execs()) != 2 {
+		t.Error("Expected no statement for a non-MySQL repository")
+	}
+
+	tenantRepo := repo.WithTenant("tenant_id", 1)
+	if _, err := tenantRepo.UpsertWithResult(&TestEntity{ID: 7}, []string{"id"}, nil); !errors.Is(err, ErrUpsertWithTenant) {
+		t.Errorf("Expected ErrUpsertWithTenant, got %v", err)
+	}
+	if len(fake.Execs()) != 2 {
+		t.Error("Expected no statement for a tenant-scoped repository")
+	}
+}
+
+func TestClaimRows(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	fake.queueResult([]string{"id", "name", "status"},
+		[]driver.Value{int64(3), "job3", int64(0)},
+		[]driver.Value{int64(4), "job4", int64(0)})
+
+	var claimed []*TestEntity
+	err := NewUnitOfWork(db).RunInTransaction(func(tx IUnitOfWork) error {
+		var err error
+		claimed, err = NewRepositoryTx[TestEntity](tx, "jobs", MySQL).ClaimRows(goqu.Ex{"status": 0}, 2, "id")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ClaimRows failed: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != 3 || claimed[1].ID != 4 {
+		t.Errorf("Expected jobs 3 and 4, got %v", claimed)
+	}
+
+	queries := fake.Queries()
+	expected := "SELECT `id`, `name`, `status` FROM `jobs` WHERE (`status` = 0) ORDER BY `id` ASC LIMIT 2 FOR UPDATE SKIP LOCKED"
+	if len(queries) != 1 || queries[0] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, queries)
+	}
+
+	repo := NewRepository[TestEntity](db, "jobs", MySQL)
+	if _, err := repo.ClaimRows(goqu.Ex{"status": 0}, 2, "id"); !errors.Is(err, ErrClaimWithoutTx) {
+		t.Errorf("Expected ErrClaimWithoutTx outside a unit of work, got %v", err)
+	}
+	if _, err := repo.WithUnitOfWork(NewUnitOfWork(db)).ClaimRows(goqu.Ex{"status": 0}, 2, "id"); !errors.Is(err, ErrClaimWithoutTx) {
+		t.Errorf("Expected ErrClaimWithoutTx before Begin, got %v", err)
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "jobs", MySQL)
+	fake.queueResult([]string{"id", "name", "status"},
+		[]driver.Value{int64(3), "job3", int64(9)},
+		[]driver.Value{int64(4), "job4", int64(9)})
+
+	deleted, err := repo.DeleteReturning(goqu.Ex{"status": 9})
+	if err != nil {
+		t.Fatalf("DeleteReturning failed: %v", err)
+	}
+	if ids := entityIDs(deleted); !reflect.DeepEqual(ids, []int64{3, 4}) {
+		t.Errorf("Expected deleted rows 3 and 4, got %v", ids)
+	}
+
+	expectedQuery := "SELECT `id`, `name`, `status` FROM `jobs` WHERE (`status` = 9) FOR UPDATE "
+	if queries := fake.Queries(); len(queries) != 1 || queries[0] != expectedQuery {
+		t.Errorf("Expected SQL %q, got %q", expectedQuery, queries)
+	}
+	expectedExec := "DELETE `jobs` FROM `jobs` WHERE (`status` = 9)"
+	if execs := fake.Execs(); len(execs) != 1 || execs[0] != expectedExec {
+		t.Errorf("Expected SQL %q, got %q", expectedExec, execs)
+	}
+	if ends := fake.TxEnds(); !reflect.DeepEqual(ends, []string{"COMMIT"}) {
+		t.Errorf("Expected the delete to commit, got %v", ends)
+	}
+
+	// 没有匹配的行时不执行 DELETE
+	deleted, err = repo.DeleteReturning(goqu.Ex{"status": 10})
+	if err != nil || len(deleted) != 0 {
+		t.Errorf("Expected no rows, got %v, %v", deleted, err)
+	}
+	if execs := fake.Execs(); len(execs) != 1 {
+		t.Errorf("Expected no DELETE without matching rows, got %v", execs)
+	}
+
+	// 删除失败时回滚，不返回读取到的行
+	fake.queueResult([]string{"id", "name", "status"}, []driver.Value{int64(5), "job5", int64(9)})
+	fake.queueExecErr(errors.New("lock wait timeout"))
+	if deleted, err := repo.DeleteReturning(goqu.Ex{"status": 9}); err == nil || deleted != nil {
+		t.Errorf("Expected the delete error, got %v, %v", deleted, err)
+	}
+	if ends := fake.TxEnds(); !reflect.DeepEqual(ends, []string{"COMMIT", "COMMIT", "ROLLBACK"}) {
+		t.Errorf("Expected the failed delete to roll back, got %v", ends)
+	}
+}
+
+func TestBatchUpdatePlaceholderLimit(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	entities := make([]*TestEntity, 20000)
+	for i := range entities {
+		entities[i] = &TestEntity{ID: int64(i + 1), Name: "n"}
+	}
+
+	err := repo.BatchUpdate(entities, &BatchUpdateOption{
+		BatchSize:    len(entities),
+		UpdateFields: []string{"name"},
+		KeyField:     "id",
+	})
+	if err != nil {
+		t.Fatalf("BatchUpdate failed: %v", err)
+	}
+	execs := fake.Execs()
+	if len(execs) != 2 {
+		t.Fatalf("Expected the batch to be split in two, got %d statements", len(execs))
+	}
+	if n := strings.Count(execs[0], "?"); n > maxPlaceholders(MySQL) {
+		t.Errorf("Expected at most %d placeholders, got %d", maxPlaceholders(MySQL), n)
+	}
+}
+
+func TestBatchUpdateSortsByKey(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	entities := []*TestEntity{{ID: 30, Name: "c"}, {ID: 10, Name: "a"}, {ID: 20, Name: "b"}, {ID: 5, Name: "z"}}
+
+	err := repo.BatchUpdate(entities, &BatchUpdateOption{
+		BatchSize:    2,
+		UpdateFields: []string{"name"},
+		KeyField:     "id",
+	})
+	if err != nil {
+		t.Fatalf("BatchUpdate failed: %v", err)
+	}
+
+	fake.mu.Lock()
+	args := fake.args
+	fake.mu.Unlock()
+	expected := [][]interface{}{
+		{int64(5), "z", int64(10), "a", int64(5), int64(10)},
+		{int64(20), "b", int64(30), "c", int64(20), int64(30)},
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected keys in ascending order %v, got %v", expected, args)
+	}
+	if entities[0].ID != 30 {
+		t.Error("BatchUpdate must not reorder the caller's slice")
+	}
+}
+
+func TestBatchInsertContextCancel(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	entities := make([]*TestEntity, 5)
+	for i := range entities {
+		entities[i] = &TestEntity{ID: int64(i + 1), Name: "n"}
+	}
+
+	for _, atomic := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		execsBefore := len(fake.Execs())
+		var failedAt int
+		err := repo.BatchInsertContext(ctx, entities, &BatchInsertOption{
+			BatchSize: 2,
+			Atomic:    atomic,
+			OnBatch: func(done, total int, err error) {
+				if err != nil {
+					failedAt = done
+					return
+				}
+				cancel() // 第一批完成后取消
+			},
+		})
+		cancel()
+		if !errors.Is(err, context.Canceled) || failedAt != 2 {
+			t.Errorf("atomic=%v: expected cancellation after the first batch, got done=%d err=%v", atomic, failedAt, err)
+		}
+		if execs := fake.Execs()[execsBefore:]; len(execs) != 1 {
+			t.Errorf("atomic=%v: expected only the first batch to run, got %v", atomic, execs)
+		}
+	}
+	if ends := fake.TxEnds(); !reflect.DeepEqual(ends, []string{"ROLLBACK"}) {
+		t.Errorf("Expected the atomic insert to roll back, got %v", ends)
+	}
+
+	if err := repo.BatchInsertContext(context.Background(), entities, &BatchInsertOption{BatchSize: 2, Atomic: true}); err != nil {
+		t.Fatalf("Atomic BatchInsertContext failed: %v", err)
+	}
+	if ends := fake.TxEnds(); !reflect.DeepEqual(ends, []string{"ROLLBACK", "COMMIT"}) {
+		t.Errorf("Expected the atomic insert to commit, got %v", ends)
+	}
+}
+
+func TestBatchInsertOnBatch(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[TestEntity](db, "test_table", MySQL)
+	entities := make([]*TestEntity, 5)
+	for i := range entities {
+		entities[i] = &TestEntity{ID: int64(i + 1), Name: "n"}
+	}
+
+	var progress []int
+	err := repo.BatchInsert(entities, &BatchInsertOption{
+		BatchSize: 2,
+		OnBatch: func(done, total int, err error) {
+			if total != 5 || err != nil {
+				t.Errorf("Unexpected callback total=%d err=%v", total, err)
+			}
+			progress = append(progress, done)
+		},
+	})
+	if err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if !reflect.DeepEqual(progress, []int{2, 4, 5}) || len(fake.Execs()) != len(progress) {
+		t.Errorf("Expected one callback per batch [2 4 5], got %v for %d batches", progress, len(fake.Execs()))
+	}
+
+	execErr := errors.New("duplicate entry")
+	fake.queueExecErr(nil)
+	fake.queueExecErr(execErr)
+	var failedAt int
+	var batchErr error
+	err = repo.BatchInsert(entities, &BatchInsertOption{
+		BatchSize: 2,
+		OnBatch: func(done, total int, err error) {
+			failedAt, batchErr = done, err
+		},
+	})
+	if !errors.Is(err, execErr) || !errors.Is(batchErr, execErr) || failedAt != 2 {
+		t.Errorf("Expected the second batch to fail after 2 rows, got done=%d err=%v", failedAt, batchErr)
+	}
+}
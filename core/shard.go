@@ -0,0 +1,50 @@
+package core
+
+import "github.com/doug-martin/goqu/v9"
+
+// ShardResolver 分库路由，ByEntity 用于写入实体，ByKey 用于按主键读取，返回 nil 时操作返回 ErrNoDatabase
+type ShardResolver[T any] struct {
+	ByEntity func(entity *T) *DBLogger
+	ByKey    func(key interface{}) *DBLogger
+}
+
+// WithShards 返回按 resolver 分库的仓储副本
+// Create/CreateWithOptions/CreateContext/CreateAndReturnID/Update/UpdateContext/UpsertWithResult 按实体路由，
+// FindByID 按主键路由，其余方法（Query、按条件更新删除、批量操作等）使用仓储的默认 db，
+// 需要先用 Shard/ShardByKey 选定分库，例如 repo.ShardByKey(userID).Query()
+// 不支持跨分库查询和事务，需要时应在调用方对每个分库分别查询后合并；绑定工作单元时忽略路由，全部在事务中执行
+func (r *Repository[T]) WithShards(resolver ShardResolver[T]) *Repository[T] {
+	clone := *r
+	clone.shards = &resolver
+	return &clone
+}
+
+// Shard 返回实体所在分库的仓储，未设置分库时返回自身
+func (r *Repository[T]) Shard(entity *T) *Repository[T] {
+	if r.shards == nil || r.shards.ByEntity == nil || r.uow != nil {
+		return r
+	}
+	return r.onShard(r.shards.ByEntity(entity))
+}
+
+// ShardByKey 返回 key 所在分库的仓储，未设置分库时返回自身
+func (r *Repository[T]) ShardByKey(key interface{}) *Repository[T] {
+	if r.shards == nil || r.shards.ByKey == nil || r.uow != nil {
+		return r
+	}
+	return r.onShard(r.shards.ByKey(key))
+}
+
+// onShard 返回使用 db 且不再路由的仓储副本
+func (r *Repository[T]) onShard(db *DBLogger) *Repository[T] {
+	clone := *r
+	clone.db = db
+	clone.shards = nil
+	return &clone
+}
+
+// FindByID 按 id 列查询单条记录，设置分库时路由到 id 所在分库
+func (r *Repository[T]) FindByID(id interface{}) (_ *T, err error) {
+	defer wrapQueryError("FindByID", r.table, &err)
+	return r.ShardByKey(id).QuerySingle(goqu.Ex{"id": id})
+}
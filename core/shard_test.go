@@ -0,0 +1,56 @@
+package core
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRepositoryShards(t *testing.T) {
+	db0, fake0 := newFakeSQLX(t, t.Name()+"-0")
+	db1, fake1 := newFakeSQLX(t, t.Name()+"-1")
+	shards := []*DBLogger{NewDBLogger(db0, zap.NewNop(), ""), NewDBLogger(db1, zap.NewNop(), "")}
+	byID := func(key interface{}) *DBLogger { return shards[key.(int64)%2] }
+
+	repo := NewRepository[TestEntity](nil, "test_table", MySQL).WithShards(ShardResolver[TestEntity]{
+		ByEntity: func(e *TestEntity) *DBLogger { return byID(e.ID) },
+		ByKey:    byID,
+	})
+
+	for _, e := range []*TestEntity{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}} {
+		if err := repo.Create(e); err != nil {
+			t.Fatalf("Create %d failed: %v", e.ID, err)
+		}
+	}
+	if execs := fake0.Execs(); len(execs) != 1 || !strings.Contains(execs[0], "(2, 'b', 0)") {
+		t.Errorf("Expected id 2 on shard 0, got %v", execs)
+	}
+	if execs := fake1.Execs(); len(execs) != 2 ||
+		!strings.Contains(execs[0], "(1, 'a', 0)") || !strings.Contains(execs[1], "(3, 'c', 0)") {
+		t.Errorf("Expected ids 1 and 3 on shard 1, got %v", execs)
+	}
+
+	fake1.queueResult([]string{"id", "name", "status"}, []driver.Value{int64(3), "c", int64(0)})
+	found, err := repo.FindByID(int64(3))
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if found.Name != "c" || len(fake0.Queries()) != 0 || len(fake1.Queries()) != 1 {
+		t.Errorf("Expected FindByID(3) to read shard 1, got %+v", found)
+	}
+
+	// 未选定分库的查询没有默认连接
+	if _, err := repo.Query().ToList(); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("Expected ErrNoDatabase for an unrouted query, got %v", err)
+	}
+	fake0.queueResult([]string{"id", "name", "status"})
+	if _, err := repo.ShardByKey(int64(4)).Query().ToList(); err != nil {
+		t.Fatalf("ShardByKey query failed: %v", err)
+	}
+	if len(fake0.Queries()) != 1 {
+		t.Errorf("Expected ShardByKey(4) to query shard 0, got %v", fake0.Queries())
+	}
+}
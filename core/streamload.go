@@ -0,0 +1,283 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrStreamLoadDialect 非 StarRocks 仓储调用 StreamLoad
+var ErrStreamLoadDialect = errors.New("stream load is only supported for the StarRocks dialect")
+
+// StreamLoadOption StarRocks Stream Load 的配置
+type StreamLoadOption struct {
+	URL      string // FE 的 HTTP 地址，如 http://fe:8030，FE 会重定向到 BE
+	Database string // 为空时取表名中的库名，如 db.table
+	User     string
+	Password string
+
+	// Format 为 json（默认）或 csv，csv 使用 StarRocks 默认的 \t 分隔，NULL 写为 \N
+	Format string
+	// Label 导入标签，StarRocks 对同一库内的标签去重，重试时复用同一个标签可避免重复导入；为空时自动生成
+	Label string
+	// Timeout 导入超时，同时作为 HTTP 请求的超时，0 表示使用 StarRocks 默认值
+	Timeout time.Duration
+	// Client 发送请求的 HTTP 客户端，为空时使用 http.DefaultClient 的配置
+	Client *http.Client
+	// BackendHosts 允许携带凭据跟随重定向的 BE 主机名，FE 所在主机始终允许；
+	// 重定向到其他主机或更换协议时返回错误，避免把凭据发给任意地址
+	BackendHosts []string
+}
+
+// streamLoadResponse Stream Load 返回的结果
+type streamLoadResponse struct {
+	TxnID             int64  `json:"TxnId"`
+	Label             string `json:"Label"`
+	Status            string `json:"Status"`
+	ExistingJobStatus string `json:"ExistingJobStatus"`
+	Message           string `json:"Message"`
+	NumberLoadedRows  int64  `json:"NumberLoadedRows"`
+	ErrorURL          string `json:"ErrorURL"`
+}
+
+// StreamLoad 通过 StarRocks 的 Stream Load 接口导入实体，吞吐远高于多行 INSERT，只支持 StarRocks 方言
+// 所有实体在一个导入事务中写入，要么全部可见要么全部失败；标签已存在且对应导入已完成时视为成功
+func (r *Repository[T]) StreamLoad(entities []*T, opt *StreamLoadOption) (err error) {
+	defer wrapQueryError("StreamLoad", r.table, &err)
+	return r.StreamLoadContext(context.Background(), entities, opt)
+}
+
+// StreamLoadContext 带 context 的 StreamLoad
+func (r *Repository[T]) StreamLoadContext(ctx context.Context, entities []*T, opt *StreamLoadOption) (err error) {
+	defer wrapQueryError("StreamLoadContext", r.table, &err)
+	if r.dbType != StarRocks {
+		return ErrStreamLoadDialect
+	}
+	if opt == nil || opt.URL == "" {
+		return errors.New("stream load requires a load URL")
+	}
+	if len(entities) == 0 {
+		return nil
+	}
+	if err := r.beforeBatchInsert(entities); err != nil {
+		return err
+	}
+
+	database, table := opt.Database, r.table
+	if i := strings.Index(table, "."); i >= 0 {
+		if database == "" {
+			database = table[:i]
+		}
+		table = table[i+1:]
+	}
+	if database == "" {
+		return errors.New("stream load requires a database")
+	}
+
+	format := strings.ToLower(opt.Format)
+	if format == "" {
+		format = "json"
+	}
+	columns, body, err := r.streamLoadBody(entities, format)
+	if err != nil {
+		return err
+	}
+
+	label := opt.Label
+	if label == "" {
+		label = fmt.Sprintf("goqu_linq_%s_%d", table, time.Now().UnixNano())
+	}
+
+	url := fmt.Sprintf("%s/api/%s/%s/_stream_load", strings.TrimSuffix(opt.URL, "/"), database, table)
+	if r.db != nil && r.db.skipWrite(fmt.Sprintf("STREAM LOAD %s LABEL %s", url, label), nil) {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(opt.User, opt.Password)
+	// 先发请求头，FE 重定向或拒绝时不必发送数据
+	req.Header.Set("Expect", "100-continue")
+	req.Header.Set("label", label)
+	req.Header.Set("format", format)
+	req.Header.Set("columns", strings.Join(columns, ","))
+	if format == "json" {
+		req.Header.Set("strip_outer_array", "true")
+	}
+	if opt.Timeout > 0 {
+		req.Header.Set("timeout", strconv.Itoa(int(opt.Timeout.Seconds())))
+	}
+
+	resp, err := streamLoadClient(opt).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stream load failed with HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(payload)))
+	}
+	var result streamLoadResponse
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return fmt.Errorf("invalid stream load response: %w", err)
+	}
+
+	if r.db != nil && r.db.logger != nil {
+		r.db.logger.Info("Stream load finished",
+			zap.String("table", r.table), zap.String("label", label), zap.String("status", result.Status),
+			zap.Int64("txn_id", result.TxnID), zap.Int64("loaded_rows", result.NumberLoadedRows))
+	}
+
+	switch result.Status {
+	case "Success", "Publish Timeout":
+		// Publish Timeout 表示事务已提交，数据稍后可见
+		return nil
+	case "Label Already Exists":
+		if result.ExistingJobStatus == "FINISHED" {
+			return nil
+		}
+	}
+	if result.ErrorURL != "" {
+		return fmt.Errorf("stream load %s: %s: %s (see %s)", label, result.Status, result.Message, result.ErrorURL)
+	}
+	return fmt.Errorf("stream load %s: %s: %s", label, result.Status, result.Message)
+}
+
+// streamLoadClient 返回带凭据跟随重定向的客户端，FE 会把请求重定向到 BE，
+// 而 net/http 在跨主机重定向时会去掉 Authorization；只对 FE 主机和 BackendHosts 重新附加凭据
+func streamLoadClient(opt *StreamLoadOption) *http.Client {
+	client := http.Client{}
+	if opt.Client != nil {
+		client = *opt.Client
+	}
+	if opt.Timeout > 0 && client.Timeout == 0 {
+		client.Timeout = opt.Timeout
+	}
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stream load stopped after 10 redirects")
+		}
+		if checkRedirect != nil {
+			if err := checkRedirect(req, via); err != nil {
+				return err
+			}
+		}
+		if !streamLoadTrusted(opt, via[0].URL, req.URL) {
+			return fmt.Errorf("stream load redirected to untrusted host %s, add it to BackendHosts", req.URL.Host)
+		}
+		req.SetBasicAuth(opt.User, opt.Password)
+		return nil
+	}
+	return &client
+}
+
+// streamLoadTrusted 判断重定向目标能否携带凭据：协议与 FE 相同，且主机为 FE 主机或在 BackendHosts 中
+func streamLoadTrusted(opt *StreamLoadOption, fe, target *url.URL) bool {
+	if target.Scheme != fe.Scheme {
+		return false
+	}
+	host := target.Hostname()
+	if strings.EqualFold(host, fe.Hostname()) {
+		return true
+	}
+	for _, allowed := range opt.BackendHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamLoadBody 按 format 序列化实体，返回列顺序和请求体
+func (r *Repository[T]) streamLoadBody(entities []*T, format string) ([]string, []byte, error) {
+	rows := make([]map[string]interface{}, len(entities))
+	for i, entity := range entities {
+		record := r.insertRecord(entity, &InsertOptions{})
+		row := make(map[string]interface{}, len(record))
+		for column, value := range record {
+			v, err := streamLoadValue(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("column %s: %w", column, err)
+			}
+			row[column] = v
+		}
+		rows[i] = row
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for column := range rows[0] {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	switch format {
+	case "json":
+		body, err := json.Marshal(rows)
+		return columns, body, err
+	case "csv":
+		var buf bytes.Buffer
+		for _, row := range rows {
+			for i, column := range columns {
+				if i > 0 {
+					buf.WriteByte('\t')
+				}
+				value := row[column]
+				if value == nil {
+					buf.WriteString(`\N`)
+					continue
+				}
+				s := fmt.Sprint(value)
+				if strings.ContainsAny(s, "\t\n") {
+					return nil, nil, fmt.Errorf("column %s contains a tab or newline, use the json format", column)
+				}
+				buf.WriteString(s)
+			}
+			buf.WriteByte('\n')
+		}
+		return columns, buf.Bytes(), nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported stream load format %q", format)
+	}
+}
+
+// streamLoadValue 将字段值转换为 StarRocks 能解析的形式，时间使用 DATETIME 格式
+func streamLoadValue(value interface{}) (interface{}, error) {
+	if isNil(value) {
+		return nil, nil
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	if v := indirectValue(value); v.IsValid() {
+		value = v.Interface()
+	}
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05"), nil
+	case []byte:
+		return string(v), nil
+	}
+	return value, nil
+}
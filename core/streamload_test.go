@@ -0,0 +1,137 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestStreamLoad(t *testing.T) {
+	var (
+		header http.Header
+		method string
+		path   string
+		body   string
+		user   string
+		pass   string
+	)
+	status := `{"TxnId": 7, "Label": "batch-1", "Status": "Success", "NumberLoadedRows": 2}`
+	be := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, method, path = r.Header.Clone(), r.Method, r.URL.Path
+		user, pass, _ = r.BasicAuth()
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		io.WriteString(w, status)
+	}))
+	defer be.Close()
+	// FE 只负责把请求重定向到 BE
+	fe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, be.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer fe.Close()
+
+	repo := NewRepository[TestEntity](nil, "analytics.test_table", StarRocks)
+	entities := []*TestEntity{{ID: 1, Name: "a", Status: 1}, {ID: 2, Name: "b"}}
+	opt := &StreamLoadOption{URL: fe.URL, User: "root", Password: "secret", Label: "batch-1"}
+	if err := repo.StreamLoad(entities, opt); err != nil {
+		t.Fatalf("StreamLoad failed: %v", err)
+	}
+
+	if method != http.MethodPut || path != "/api/analytics/test_table/_stream_load" {
+		t.Errorf("Unexpected request %s %s", method, path)
+	}
+	if user != "root" || pass != "secret" {
+		t.Errorf("Expected credentials to follow the redirect, got %q/%q", user, pass)
+	}
+	expectedHeaders := map[string]string{
+		"Label":             "batch-1",
+		"Format":            "json",
+		"Columns":           "id,name,status",
+		"Strip_outer_array": "true",
+	}
+	for key, want := range expectedHeaders {
+		if got := header.Get(key); got != want {
+			t.Errorf("Expected header %s=%q, got %q", key, want, got)
+		}
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &rows); err != nil {
+		t.Fatalf("Expected a JSON array body, got %q: %v", body, err)
+	}
+	if len(rows) != 2 || rows[0]["name"] != "a" || rows[1]["id"] != float64(2) {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	opt.Format = "csv"
+	if err := repo.StreamLoad(entities, opt); err != nil {
+		t.Fatalf("StreamLoad csv failed: %v", err)
+	}
+	if body != "1\ta\t1\n2\tb\t0\n" || header.Get("Strip_outer_array") != "" {
+		t.Errorf("Unexpected csv body %q", body)
+	}
+
+	status = `{"Label": "batch-1", "Status": "Label Already Exists", "ExistingJobStatus": "FINISHED"}`
+	if err := repo.StreamLoad(entities, opt); err != nil {
+		t.Errorf("Expected a finished duplicate label to succeed, got %v", err)
+	}
+	status = `{"Label": "batch-1", "Status": "Fail", "Message": "too many filtered rows", "ErrorURL": "http://be/error"}`
+	if err := repo.StreamLoad(entities, opt); err == nil || !strings.Contains(err.Error(), "too many filtered rows") {
+		t.Errorf("Expected the load failure, got %v", err)
+	}
+
+	// 重定向到其他主机时不携带凭据，需在 BackendHosts 中声明
+	other := strings.Replace(be.URL, "127.0.0.1", "localhost", 1)
+	fe.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other+r.URL.Path, http.StatusTemporaryRedirect)
+	})
+	status = `{"TxnId": 8, "Label": "batch-1", "Status": "Success", "NumberLoadedRows": 2}`
+	user, pass = "", ""
+	if err := repo.StreamLoad(entities, opt); err == nil || !strings.Contains(err.Error(), "untrusted host") {
+		t.Errorf("Expected a redirect to another host to be rejected, got %v", err)
+	}
+	if user != "" || pass != "" {
+		t.Errorf("Expected no credentials to reach another host, got %q/%q", user, pass)
+	}
+	opt.BackendHosts = []string{"localhost"}
+	if err := repo.StreamLoad(entities, opt); err != nil || user != "root" {
+		t.Errorf("Expected credentials to follow a redirect to a listed backend, got %q (%v)", user, err)
+	}
+
+	mysqlRepo := NewRepository[TestEntity](nil, "test_table", MySQL)
+	if err := mysqlRepo.StreamLoad(entities, opt); !errors.Is(err, ErrStreamLoadDialect) {
+		t.Errorf("Expected ErrStreamLoadDialect, got %v", err)
+	}
+}
+
+func TestStreamLoadDryRun(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		io.WriteString(w, `{"Status": "Success"}`)
+	}))
+	defer server.Close()
+
+	db, _ := newFakeDBLogger(t)
+	observed, logs := observer.New(zap.InfoLevel)
+	db.logger = zap.New(observed)
+	db.DryRun = true
+	repo := NewRepository[TestEntity](db, "analytics.test_table", StarRocks)
+	opt := &StreamLoadOption{URL: server.URL, Label: "batch-1"}
+	if err := repo.StreamLoad([]*TestEntity{{ID: 1, Name: "a"}}, opt); err != nil {
+		t.Fatalf("StreamLoad failed: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request in dry-run mode, got %d", requests)
+	}
+	expected := "STREAM LOAD " + server.URL + "/api/analytics/test_table/_stream_load LABEL batch-1"
+	if entries := logs.FilterMessage("Dry run, statement not executed").All(); len(entries) != 1 || entries[0].ContextMap()["query"] != expected {
+		t.Errorf("Expected the load to be logged as %q, got %v", expected, logs.All())
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	createdAtColumn = "created_at"
+	updatedAtColumn = "updated_at"
+)
+
+// Timestamped 实体可选实现的时间戳接口，开启自动时间戳后优先于 created_at/updated_at 字段
+type Timestamped interface {
+	SetCreatedAt(now time.Time)
+	SetUpdatedAt(now time.Time)
+}
+
+// timeNow 当前时间，测试中可替换
+var timeNow = time.Now
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// touchCreated 插入前设置 created_at 和 updated_at，未开启自动时间戳时不做任何处理
+func (r *Repository[T]) touchCreated(entity *T) {
+	if !r.timestamps || entity == nil {
+		return
+	}
+	now := timeNow()
+	if ts, ok := any(entity).(Timestamped); ok {
+		ts.SetCreatedAt(now)
+		ts.SetUpdatedAt(now)
+		return
+	}
+	r.setTimestampField(entity, createdAtColumn, now)
+	r.setTimestampField(entity, updatedAtColumn, now)
+}
+
+// touchUpdated 更新前设置 updated_at
+func (r *Repository[T]) touchUpdated(entity *T) {
+	if !r.timestamps || entity == nil {
+		return
+	}
+	now := timeNow()
+	if ts, ok := any(entity).(Timestamped); ok {
+		ts.SetUpdatedAt(now)
+		return
+	}
+	r.setTimestampField(entity, updatedAtColumn, now)
+}
+
+// touchFields 按字段更新时追加 updated_at，调用方已指定时保持不变，返回新的 map
+func (r *Repository[T]) touchFields(fields map[string]interface{}) map[string]interface{} {
+	if !r.timestamps {
+		return fields
+	}
+	if _, ok := fields[updatedAtColumn]; ok {
+		return fields
+	}
+	field, ok := r.timestampField(updatedAtColumn)
+	if !ok {
+		return fields
+	}
+	value, ok := timestampValue(field.field.Type, timeNow())
+	if !ok {
+		return fields
+	}
+
+	touched := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		touched[k] = v
+	}
+	touched[updatedAtColumn] = value.Interface()
+	return touched
+}
+
+// timestampField 查找实体中映射到指定列的字段，包括嵌入结构体中的字段
+func (r *Repository[T]) timestampField(column string) (entityColumn, bool) {
+	for _, c := range entityColumns(reflect.TypeOf((*T)(nil)).Elem(), r.nameMapper) {
+		if c.name == column {
+			return c, true
+		}
+	}
+	return entityColumn{}, false
+}
+
+// setTimestampField 设置实体中映射到指定列的时间字段，不支持的类型会被忽略
+func (r *Repository[T]) setTimestampField(entity *T, column string, now time.Time) {
+	field, ok := r.timestampField(column)
+	if !ok {
+		return
+	}
+	value, ok := timestampValue(field.field.Type, now)
+	if !ok {
+		return
+	}
+	if target := settableField(reflect.ValueOf(entity).Elem(), field.index); target.IsValid() {
+		target.Set(value)
+	}
+}
+
+// timestampValue 按字段类型转换时间，支持 time.Time、*time.Time 和 int64 Unix 秒
+func timestampValue(typ reflect.Type, now time.Time) (reflect.Value, bool) {
+	switch {
+	case typ == timeType:
+		return reflect.ValueOf(now), true
+	case typ.Kind() == reflect.Ptr && typ.Elem() == timeType:
+		return reflect.ValueOf(&now), true
+	case typ.Kind() == reflect.Int64:
+		return reflect.ValueOf(now.Unix()).Convert(typ), true
+	}
+	return reflect.Value{}, false
+}
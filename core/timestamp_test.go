@@ -0,0 +1,98 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type unixTimestampEntity struct {
+	ID        int64  `db:"id"`
+	Name      string `db:"name"`
+	CreatedAt int64  `db:"created_at"`
+	UpdatedAt int64  `db:"updated_at"`
+}
+
+type timeTimestampEntity struct {
+	ID        int64     `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func fixTimeNow(t *testing.T, now time.Time) {
+	t.Helper()
+	original := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = original })
+}
+
+func TestTimestampsUnixColumns(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fixTimeNow(t, now)
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[unixTimestampEntity](db, "users", MySQL).WithTimestamps()
+
+	entity := &unixTimestampEntity{ID: 1, Name: "a"}
+	if err := repo.Create(entity); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if entity.CreatedAt != now.Unix() || entity.UpdatedAt != now.Unix() {
+		t.Errorf("Expected both timestamps to be %d, got %d/%d", now.Unix(), entity.CreatedAt, entity.UpdatedAt)
+	}
+
+	later := now.Add(time.Hour)
+	fixTimeNow(t, later)
+	if err := repo.Update(entity); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if entity.CreatedAt != now.Unix() || entity.UpdatedAt != later.Unix() {
+		t.Errorf("Expected only updated_at to change, got %d/%d", entity.CreatedAt, entity.UpdatedAt)
+	}
+
+	if err := repo.UpdateFieldsByCondition(goqu.Ex{"id": 1}, map[string]interface{}{"name": "b"}); err != nil {
+		t.Fatalf("UpdateFieldsByCondition failed: %v", err)
+	}
+	execs := fake.Execs()
+	expected := "UPDATE `users` SET `name`='b',`updated_at`=1714568400 WHERE (`id` = 1)"
+	if len(execs) != 3 || execs[2] != expected {
+		t.Errorf("Expected SQL %q, got %v", expected, execs)
+	}
+}
+
+func TestTimestampsTimeColumns(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fixTimeNow(t, now)
+	db, _ := newFakeDBLogger(t)
+	repo := NewRepository[timeTimestampEntity](db, "events", MySQL).WithTimestamps()
+
+	entities := []*timeTimestampEntity{{ID: 1}, {ID: 2}}
+	if err := repo.BatchCreate(entities); err != nil {
+		t.Fatalf("BatchCreate failed: %v", err)
+	}
+	for _, entity := range entities {
+		if !entity.CreatedAt.Equal(now) || !entity.UpdatedAt.Equal(now) {
+			t.Errorf("Expected timestamps %v, got %v/%v", now, entity.CreatedAt, entity.UpdatedAt)
+		}
+	}
+}
+
+func TestTimestampsOptIn(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[unixTimestampEntity](db, "users", MySQL)
+
+	entity := &unixTimestampEntity{ID: 1}
+	if err := repo.Create(entity); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if entity.CreatedAt != 0 || entity.UpdatedAt != 0 {
+		t.Errorf("Expected timestamps untouched without WithTimestamps, got %d/%d", entity.CreatedAt, entity.UpdatedAt)
+	}
+	if err := repo.UpdateFieldsByCondition(goqu.Ex{"id": 1}, map[string]interface{}{"name": "b"}); err != nil {
+		t.Fatalf("UpdateFieldsByCondition failed: %v", err)
+	}
+	expected := "UPDATE `users` SET `name`='b' WHERE (`id` = 1)"
+	if execs := fake.Execs(); execs[1] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, execs[1])
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testAttrs 以 JSON 文本存储的自定义字段类型
+type testAttrs struct {
+	Color string   `json:"color"`
+	Tags  []string `json:"tags"`
+}
+
+func (a testAttrs) Value() (driver.Value, error) {
+	data, err := json.Marshal(a)
+	return string(data), err
+}
+
+func (a *testAttrs) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case nil:
+		*a = testAttrs{}
+		return nil
+	}
+	return errors.New("unsupported attrs source")
+}
+
+// testLevel 只有指针接收者实现 driver.Valuer 的枚举
+type testLevel int
+
+func (l *testLevel) Value() (driver.Value, error) {
+	return []string{"low", "high"}[*l], nil
+}
+
+func (l *testLevel) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	}
+	switch s {
+	case "low":
+		*l = 0
+	case "high":
+		*l = 1
+	default:
+		return errors.New("unknown level " + s)
+	}
+	return nil
+}
+
+type valuerEntity struct {
+	ID    int64     `db:"id"`
+	Attrs testAttrs `db:"attrs"`
+	Level testLevel `db:"level"`
+}
+
+func TestValuerScannerRoundTrip(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[valuerEntity](db, "items", MySQL)
+	attrs := testAttrs{Color: "red", Tags: []string{"a", "b"}}
+	entities := []*valuerEntity{
+		{ID: 1, Attrs: attrs, Level: 1},
+		{ID: 2, Attrs: testAttrs{Color: "blue"}, Level: 0},
+	}
+
+	if err := repo.CreateWithOptions(entities[0], &InsertOptions{}); err != nil {
+		t.Fatalf("CreateWithOptions failed: %v", err)
+	}
+	if err := repo.BatchInsert(entities, &BatchInsertOption{BatchSize: 10}); err != nil {
+		t.Fatalf("BatchInsert failed: %v", err)
+	}
+	if err := repo.BatchInsert(entities, &BatchInsertOption{BatchSize: 10, UseNamedExec: true}); err != nil {
+		t.Fatalf("BatchInsert with named exec failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 3 {
+		t.Fatalf("Expected 3 inserts, got %v", execs)
+	}
+	// goqu 内联参数时会转义 JSON 中的引号，这里只检查枚举写入的是 Value() 的结果
+	if !strings.Contains(execs[0], "red") || !strings.HasSuffix(execs[0], ", 1, 'high')") {
+		t.Errorf("Expected CreateWithOptions to insert driver values, got %q", execs[0])
+	}
+	expectedArgs := []interface{}{int64(1), `{"color":"red","tags":["a","b"]}`, "high", int64(2), `{"color":"blue","tags":null}`, "low"}
+	fake.mu.Lock()
+	batchArgs, namedArgs := fake.args[1], fake.args[2]
+	fake.mu.Unlock()
+	if !reflect.DeepEqual(batchArgs, expectedArgs) {
+		t.Errorf("Expected batch insert args %v, got %v", expectedArgs, batchArgs)
+	}
+	if !reflect.DeepEqual(namedArgs, expectedArgs) {
+		t.Errorf("Expected named batch insert args %v, got %v", expectedArgs, namedArgs)
+	}
+
+	fake.queueResult([]string{"id", "attrs", "level"},
+		[]driver.Value{int64(1), []byte(`{"color":"red","tags":["a","b"]}`), []byte("high")})
+	fake.queueResult([]string{"id", "attrs", "level"},
+		[]driver.Value{int64(1), []byte(`{"color":"red","tags":["a","b"]}`), []byte("high")})
+
+	items, err := repo.Query().ToList()
+	if err != nil {
+		t.Fatalf("ToList failed: %v", err)
+	}
+	if len(items) != 1 || !reflect.DeepEqual(*items[0], *entities[0]) {
+		t.Errorf("Expected %+v, got %+v", *entities[0], items)
+	}
+
+	maps, err := repo.Query().ToMapSlice()
+	if err != nil {
+		t.Fatalf("ToMapSlice failed: %v", err)
+	}
+	if len(maps) != 1 || !reflect.DeepEqual(maps[0]["attrs"], attrs) || maps[0]["level"] != testLevel(1) {
+		t.Errorf("Expected scanned custom types in map, got %v", maps)
+	}
+}
+
+func TestValuerCreateAndUpdate(t *testing.T) {
+	db, fake := newFakeDBLogger(t)
+	repo := NewRepository[valuerEntity](db, "items", MySQL)
+	entity := &valuerEntity{ID: 1, Attrs: testAttrs{Color: "red"}, Level: 1}
+
+	if err := repo.Create(entity); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.BatchCreate([]*valuerEntity{entity}); err != nil {
+		t.Fatalf("BatchCreate failed: %v", err)
+	}
+	if err := repo.Update(entity); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	err := NewUnitOfWork(db).RunInTransaction(func(tx IUnitOfWork) error {
+		txRepo := repo.WithUnitOfWork(tx)
+		if err := txRepo.Create(entity); err != nil {
+			return err
+		}
+		return txRepo.Update(entity)
+	})
+	if err != nil {
+		t.Fatalf("transactional Create/Update failed: %v", err)
+	}
+
+	execs := fake.Execs()
+	if len(execs) != 5 {
+		t.Fatalf("Expected 5 statements, got %v", execs)
+	}
+	for _, stmt := range execs {
+		if !strings.Contains(stmt, "'high'") || !strings.Contains(stmt, "red") {
+			t.Errorf("Expected the pointer-receiver Valuer to be written as 'high', got %q", stmt)
+		}
+	}
+	if expected := "UPDATE `items` SET `attrs`='{\\\"color\\\":\\\"red\\\",\\\"tags\\\":null}',`id`=1,`level`='high'"; execs[2] != expected {
+		t.Errorf("Expected SQL %q, got %q", expected, execs[2])
+	}
+}
@@ -33,7 +33,7 @@ type UserRepository struct {
 // NewUserRepository creates a new user repository
 func NewUserRepository(db *core.DBLogger) *UserRepository {
 	return &UserRepository{
-		Repository: core.NewRepository[User](db, "users", core.MySQL),
+		Repository: core.NewRepository[User](db, "", core.MySQL),
 	}
 }
 
@@ -3,6 +3,7 @@ module github.com/Natalieihs/goqu-linq
 go 1.23
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/doug-martin/goqu/v9 v9.19.0
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/jmoiron/sqlx v1.4.0